@@ -0,0 +1,167 @@
+package main
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/service/services"
+	ms "auth-server/internal/app/store/mongo_store"
+	"auth-server/pkg/breached"
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/subosito/gotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  keys list               list signing keys
+  keys rotate [-revoke]   create new signing key, -revoke stops old keys verifying tokens immediately
+  breached build -in <dataset> -out <filter> [-fp 0.001] [-min-count 1]
+                          build breached passwords filter from "SHA1:COUNT" file or directory of range files
+`
+
+func init() {
+	if err := gotenv.Load(".env"); err != nil {
+		log.Println(".env file not found.")
+	}
+}
+
+func main() {
+	if len(os.Args) < 3 {
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+	config := cfg.GetConfig()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	switch os.Args[1] + " " + os.Args[2] {
+	case "keys list":
+		err := listKeys(ctx, config)
+		if err != nil {
+			log.Fatalf("Err in list keys. Err: %s", err.Error())
+		}
+	case "keys rotate":
+		flags := flag.NewFlagSet("keys rotate", flag.ExitOnError)
+		revoke := flags.Bool("revoke", false, "old keys stop verifying tokens immediately")
+		flags.Parse(os.Args[3:])
+		err := rotateKeys(ctx, config, *revoke)
+		if err != nil {
+			log.Fatalf("Err in rotate keys. Err: %s", err.Error())
+		}
+	case "breached build":
+		flags := flag.NewFlagSet("breached build", flag.ExitOnError)
+		in := flags.String("in", "", "dataset file or directory of range files")
+		out := flags.String("out", "", "filter file")
+		fpRate := flags.Float64("fp", breached.DefaultFalsePositiveRate, "false positive rate")
+		minCount := flags.Int("min-count", 1, "skip hashes seen less times")
+		flags.Parse(os.Args[3:])
+		if len(*in) == 0 || len(*out) == 0 {
+			flags.Usage()
+			os.Exit(2)
+		}
+		err := buildBreachedFilter(*in, *out, *fpRate, *minCount)
+		if err != nil {
+			log.Fatalf("Err in build breached passwords filter. Err: %s", err.Error())
+		}
+	default:
+		fmt.Print(usage)
+		os.Exit(2)
+	}
+}
+
+//connect returns mongo store, the database connection is needed only for mongo key store
+func connect(ctx context.Context, config *cfg.Config) (*ms.Store, func(), error) {
+	if config.KeyStore != cfg.KeyStoreMongo {
+		return nil, func() {}, nil
+	}
+	client, err := mongo.NewClient(options.Client().ApplyURI(config.MongoURI))
+	if err != nil {
+		return nil, nil, err
+	}
+	if err = client.Connect(ctx); err != nil {
+		return nil, nil, err
+	}
+	disconnect := func() { client.Disconnect(ctx) }
+	if err = client.Ping(ctx, nil); err != nil {
+		disconnect()
+		return nil, nil, err
+	}
+	return ms.NewStore(client.Database(config.MongoDatabase)), disconnect, nil
+}
+
+func listKeys(ctx context.Context, config *cfg.Config) error {
+	store, disconnect, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	keyService, err := services.NewKeyService(store, config)
+	if err != nil {
+		return err
+	}
+	keys, err := keyService.Keys(ctx)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		status := "active"
+		if !key.Active {
+			status = "retired till " + key.ExpIn.Format(time.RFC3339)
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n", key.ID, key.Algorithm, key.CreatedAt.Format(time.RFC3339), status)
+	}
+	return nil
+}
+
+func rotateKeys(ctx context.Context, config *cfg.Config, revoke bool) error {
+	store, disconnect, err := connect(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer disconnect()
+	keyService, err := services.NewKeyService(store, config)
+	if err != nil {
+		return err
+	}
+	key, err := keyService.Rotate(ctx, revoke)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("New signing key: %s (%s).\n", key.ID, key.Algorithm)
+	if revoke {
+		fmt.Println("Old keys are revoked, running servers stop accepting them within a minute.")
+	}
+	return nil
+}
+
+func buildBreachedFilter(in, out string, fpRate float64, minCount int) error {
+	filter, err := breached.Build(in, fpRate, minCount)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(out)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	size, err := filter.WriteTo(writer)
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Filter of %d hashes written to %s (%d bytes).\n", filter.Len(), out, size)
+	return nil
+}
@@ -9,6 +9,7 @@ import (
 	"auth-server/internal/app/utils/validators"
 	"auth-server/pkg/emailsender"
 	"context"
+	"github.com/gorilla/mux"
 	"github.com/subosito/gotenv"
 	"log"
 	"time"
@@ -39,7 +40,8 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
-	ctx, _ := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 	err = client.Connect(ctx)
 	if err != nil {
 		log.Fatalf("Err in init database. Err message: %s", err.Error())
@@ -57,16 +59,11 @@ func main() {
 	store.User()
 	store.Client()
 
-	uvalidator, err := validators.NewUserValidator()
+	uvalidator, err := validators.NewUserValidator(config)
 	if err != nil {
 		log.Fatalf("Err in init user validator. Err message: %s", err.Error())
 	}
 
-	svm, err := services.NewManager(store, uvalidator)
-	if err != nil {
-		log.Fatalf("Err in init user manager. Err message: %s", err.Error())
-	}
-
 	emailSender := emailsender.New(
 		config.CompanyEmail,
 		config.CompanyEmailPassword,
@@ -76,9 +73,33 @@ func main() {
 		config.CompanyEmail,
 	)
 
-	userHandler := handler.NewUserHandler(svm, emailSender)
+	svm, err := services.NewManager(store, uvalidator, emailSender, config)
+	if err != nil {
+		log.Fatalf("Err in init user manager. Err message: %s", err.Error())
+	}
+
+	//Rotate signing keys on schedule
+	go svm.Key.Run(context.Background())
+
+	userHandler := handler.NewUserHandler(svm, emailSender, config.PasswordResetLink)
+	authHandler := handler.NewAuthHandler(svm)
+	oauthHandler := handler.NewOAuthHandler(svm, config.RegistrationLink)
+	registrationHandler := handler.NewRegistrationHandler(svm, config.AppLink)
+	adminHandler := handler.NewAdminHandler(svm, config.AdminClientID)
+	oidcHandler := handler.NewOIDCHandler(svm, config.JWTIssuer, config.AppLink, config.SigningAlg)
+
+	trustedProxies, err := handler.ParseNetworks(config.TrustedProxies)
+	if err != nil {
+		log.Fatalf("Err in parse trusted proxies. Err message: %s", err.Error())
+	}
+	middlewares := []mux.MiddlewareFunc{
+		handler.RequestMeta(config.TrustProxy, trustedProxies, config.GeoHeader),
+		handler.ClientContext(svm),
+		handler.RateLimit(svm),
+	}
 
-	server, err := server.NewServer(svm, store, userHandler)
+	server, err := server.NewServer(svm, store, middlewares, userHandler, authHandler, registrationHandler, oauthHandler,
+		oidcHandler, adminHandler)
 	if err != nil {
 		log.Fatalf("Error creating server, err: %s", err.Error())
 	}
@@ -1,5 +1,11 @@
 package filePath
 
 const (
-	EmailConfTemplate = "./files/message_templates/EmailConfTemp.html"
+	EmailConfTemplate     = "./files/message_templates/EmailConfTemp.html"
+	PasswordResetTemplate = "./files/message_templates/PasswordResetTemp.html"
+	AccountLockedTemplate = "./files/message_templates/AccountLockedTemp.html"
+	AuthStaticDir         = "./files/auth_templates/static/"
+	AuthPageTemplate      = "./files/auth_templates/auth_page.html"
+	DevicePageTemplate    = "./files/auth_templates/device_page.html"
+	ConsentPageTemplate   = "./files/auth_templates/consent_page.html"
 )
@@ -0,0 +1,55 @@
+//Answers MFA challenge by passkey, options are rendered by server into data-options of the button
+(function () {
+    function decode(value) {
+        var base64 = value.replace(/-/g, '+').replace(/_/g, '/');
+        return Uint8Array.from(atob(base64), function (c) { return c.charCodeAt(0); });
+    }
+
+    function encode(buffer) {
+        var binary = String.fromCharCode.apply(null, new Uint8Array(buffer));
+        return btoa(binary).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+    }
+
+    var button = document.getElementById('passkey_button');
+    if (!button || !window.PublicKeyCredential) {
+        if (button) {
+            button.style.display = 'none';
+        }
+        return;
+    }
+    button.addEventListener('click', function () {
+        var options = JSON.parse(button.dataset.options);
+        navigator.credentials.get({
+            publicKey: {
+                challenge: decode(options.challenge),
+                rpId: options.rpId,
+                timeout: options.timeout,
+                userVerification: options.userVerification,
+                allowCredentials: (options.allowCredentials || []).map(function (c) {
+                    return { type: c.type, id: decode(c.id), transports: c.transports };
+                })
+            }
+        }).then(function (credential) {
+            var response = credential.response;
+            document.getElementById('webauthn').value = JSON.stringify({
+                id: credential.id,
+                type: credential.type,
+                response: {
+                    clientDataJSON: encode(response.clientDataJSON),
+                    authenticatorData: encode(response.authenticatorData),
+                    signature: encode(response.signature),
+                    userHandle: response.userHandle ? encode(response.userHandle) : ''
+                }
+            });
+            var form = button.form;
+            var submit = document.getElementById('login_button');
+            if (form.requestSubmit && submit) {
+                form.requestSubmit(submit);
+            } else {
+                form.submit();
+            }
+        }).catch(function () {
+            button.textContent = 'Passkey failed, try again';
+        });
+    });
+})();
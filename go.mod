@@ -3,10 +3,11 @@ module auth-server
 go 1.15
 
 require (
+	github.com/golang-jwt/jwt/v4 v4.5.0
 	github.com/gorilla/mux v1.8.0
-	github.com/joho/godotenv v1.3.0
 	github.com/pkg/errors v0.9.1
 	github.com/spf13/viper v1.7.1
+	github.com/subosito/gotenv v1.2.0
 	go.mongodb.org/mongo-driver v1.4.4
 	golang.org/x/crypto v0.0.0-20190605123033-f99c8df09eb5
 )
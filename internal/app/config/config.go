@@ -2,23 +2,98 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"time"
+)
+
+//Storages of signing keys
+const (
+	KeyStoreMongo = "mongo"
+	KeyStoreDisk  = "disk"
+)
+
+//Storages of rate limiter state
+const (
+	RateLimitStoreMemory = "memory"
+	RateLimitStoreMongo  = "mongo"
+)
+
+//DefaultRateLimits limit every route by IP, public endpoints which may be abused have stricter limits
+const DefaultRateLimits = "*=600/1m@ip;" +
+	"/users/register=10/1h,3@ip;" +
+	"/users/email/confirm/{token}=30/1h,5@ip;" +
+	"/users/get/id/{id}=60/1m@ip+client+user;" +
+	"/users/get/username/{username}=60/1m@ip+client+user;" +
+	"/users/password/forgot=5/1h,2@ip;" +
+	"/users/password/reset=10/1h,3@ip;" +
+	"/auth/login=30/1m,10@ip+client"
+
+//Password hashing algorithms
+const (
+	PasswordHasherArgon2id = "argon2id"
+	PasswordHasherBcrypt   = "bcrypt"
 )
 
 type Config struct {
-	MongoURI             string
-	MongoDatabase        string
-	MongoUsername        string
-	MongoPassword        string
-	MongoUsersColName    string
-	MongoClientColName   string
-	AppLink              string
-	JWTKey               string
-	EmailConfKey         string
-	EmailHost            string
-	EmailHostPort        string
-	CompanyEmail         string
-	CompanyEmailPassword string
-	CompanyName          string
+	MongoURI           string
+	MongoDatabase      string
+	MongoUsername      string
+	MongoPassword      string
+	MongoUsersColName  string
+	MongoClientColName string
+	AppLink            string
+	TrustProxy         bool
+	//TrustedProxies are comma separated networks of proxies in front of the proxy which connects to server
+	TrustedProxies         string
+	GeoHeader              string
+	JWTIssuer              string
+	SigningAlg             string
+	KeyStore               string
+	KeyStorePath           string
+	KeyRotationPeriod      time.Duration
+	KeyOverlap             time.Duration
+	EncryptionKey          string
+	AccessTokenTTL         time.Duration
+	AuthCodeTTL            time.Duration
+	DeviceCodeTTL          time.Duration
+	RegistrationLink       string
+	InitialAccessToken     string
+	AdminClientID          string
+	MFAIssuer              string
+	WebAuthnRPID           string
+	WebAuthnOrigins        string
+	PasswordResetLink      string
+	PasswordResetTTL       time.Duration
+	PasswordResetCooldown  time.Duration
+	PasswordHasher         string
+	Argon2Memory           int
+	Argon2Iterations       int
+	Argon2Parallelism      int
+	BcryptCost             int
+	LoginBackoffBase       time.Duration
+	LoginLockoutDuration   time.Duration
+	LoginLockoutThreshold  int
+	LoginIPThreshold       int
+	RateLimitStore         string
+	RateLimits             string
+	PasswordMinLength      int
+	PasswordMaxLength      int
+	PasswordRequireUpper   bool
+	PasswordRequireLower   bool
+	PasswordRequireDigits  bool
+	PasswordRequireSymbols bool
+	//PasswordAllowedChars is a regexp of one allowed character, e.g. "[\x20-\x7e]", any character is allowed if it is empty
+	PasswordAllowedChars    string
+	PasswordMinStrength     int
+	PasswordBanPersonalInfo bool
+	PasswordHistorySize     int
+	PasswordBreachedFile    string
+	EmailConfKey            string
+	EmailHost               string
+	EmailHostPort           string
+	CompanyEmail            string
+	CompanyEmailPassword    string
+	CompanyName             string
 }
 
 var Cfg = GetConfig()
@@ -26,20 +101,63 @@ var Cfg = GetConfig()
 func GetConfig() *Config {
 
 	return &Config{
-		MongoURI:             getEnv("MONGO_URI", ""),
-		MongoDatabase:        getEnv("MONGO_DATABASE", "auth"),
-		MongoUsername:        getEnv("MONGO_USERNAME", ""),
-		MongoPassword:        getEnv("MONGO_PASSWORD", ""),
-		MongoUsersColName:    getEnv("MONGO_USERS_COLLECTION", "users"),
-		MongoClientColName:   getEnv("MONGO_CLIENTS_COLLECTION", "clients"),
-		AppLink:              getEnv("APPLICATION_LINK", ""),
-		JWTKey:               getEnv("JWT_KEY", ""),
-		EmailConfKey:         getEnv("EMAIL_CONF_KEY", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
-		EmailHost:            getEnv("EMAIL_HOST", ""),
-		EmailHostPort:        getEnv("EMAIL_HOST_PORT", ""),
-		CompanyEmail:         getEnv("COMPANY_EMAIL", "example@examle.org"),
-		CompanyEmailPassword: getEnv("COMPANY_EMAIL_PASSWORD", "password"),
-		CompanyName:          getEnv("COMPANY_NAME", ""),
+		MongoURI:                getEnv("MONGO_URI", ""),
+		MongoDatabase:           getEnv("MONGO_DATABASE", "auth"),
+		MongoUsername:           getEnv("MONGO_USERNAME", ""),
+		MongoPassword:           getEnv("MONGO_PASSWORD", ""),
+		MongoUsersColName:       getEnv("MONGO_USERS_COLLECTION", "users"),
+		MongoClientColName:      getEnv("MONGO_CLIENTS_COLLECTION", "clients"),
+		AppLink:                 getEnv("APPLICATION_LINK", ""),
+		TrustProxy:              getEnv("TRUST_PROXY", "false") == "true",
+		TrustedProxies:          getEnv("TRUSTED_PROXIES", ""),
+		GeoHeader:               getEnv("GEO_HEADER", "CF-IPCountry"),
+		JWTIssuer:               getEnv("JWT_ISSUER", "gibbon-auth"),
+		SigningAlg:              getEnv("SIGNING_ALG", "RS256"),
+		KeyStore:                getEnv("KEY_STORE", KeyStoreMongo),
+		KeyStorePath:            getEnv("KEY_STORE_PATH", "./keys"),
+		KeyRotationPeriod:       getEnvDuration("KEY_ROTATION_PERIOD", 720*time.Hour),
+		KeyOverlap:              getEnvDuration("KEY_OVERLAP", 24*time.Hour),
+		EncryptionKey:           getEnv("ENCRYPTION_KEY", ""),
+		AccessTokenTTL:          getEnvDuration("ACCESS_TOKEN_TTL", 15*time.Minute),
+		AuthCodeTTL:             getEnvDuration("AUTH_CODE_TTL", time.Minute),
+		DeviceCodeTTL:           getEnvDuration("DEVICE_CODE_TTL", 10*time.Minute),
+		RegistrationLink:        getEnv("REGISTRATION_LINK", ""),
+		InitialAccessToken:      getEnv("INITIAL_ACCESS_TOKEN", ""),
+		AdminClientID:           getEnv("ADMIN_CLIENT_ID", ""),
+		MFAIssuer:               getEnv("MFA_ISSUER", "GibbonAuth"),
+		WebAuthnRPID:            getEnv("WEBAUTHN_RP_ID", ""),
+		WebAuthnOrigins:         getEnv("WEBAUTHN_ORIGINS", ""),
+		PasswordResetLink:       getEnv("PASSWORD_RESET_LINK", ""),
+		PasswordResetTTL:        getEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetCooldown:   getEnvDuration("PASSWORD_RESET_COOLDOWN", 5*time.Minute),
+		PasswordHasher:          getEnv("PASSWORD_HASHER", PasswordHasherArgon2id),
+		Argon2Memory:            getEnvInt("ARGON2_MEMORY", 64*1024),
+		Argon2Iterations:        getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:       getEnvInt("ARGON2_PARALLELISM", 2),
+		BcryptCost:              getEnvInt("BCRYPT_COST", 12),
+		LoginBackoffBase:        getEnvDuration("LOGIN_BACKOFF_BASE", time.Second),
+		LoginLockoutDuration:    getEnvDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute),
+		LoginLockoutThreshold:   getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 10),
+		LoginIPThreshold:        getEnvInt("LOGIN_IP_THRESHOLD", 100),
+		RateLimitStore:          getEnv("RATE_LIMIT_STORE", RateLimitStoreMemory),
+		RateLimits:              getEnv("RATE_LIMITS", DefaultRateLimits),
+		PasswordMinLength:       getEnvInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordMaxLength:       getEnvInt("PASSWORD_MAX_LENGTH", 128),
+		PasswordRequireUpper:    getEnv("PASSWORD_REQUIRE_UPPER", "false") == "true",
+		PasswordRequireLower:    getEnv("PASSWORD_REQUIRE_LOWER", "false") == "true",
+		PasswordRequireDigits:   getEnv("PASSWORD_REQUIRE_DIGITS", "true") == "true",
+		PasswordRequireSymbols:  getEnv("PASSWORD_REQUIRE_SYMBOLS", "false") == "true",
+		PasswordAllowedChars:    getEnv("PASSWORD_ALLOWED_CHARS", ""),
+		PasswordMinStrength:     getEnvInt("PASSWORD_MIN_STRENGTH", 2),
+		PasswordBanPersonalInfo: getEnv("PASSWORD_BAN_PERSONAL_INFO", "true") == "true",
+		PasswordHistorySize:     getEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		PasswordBreachedFile:    getEnv("PASSWORD_BREACHED_FILE", ""),
+		EmailConfKey:            getEnv("EMAIL_CONF_KEY", "aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"),
+		EmailHost:               getEnv("EMAIL_HOST", ""),
+		EmailHostPort:           getEnv("EMAIL_HOST_PORT", ""),
+		CompanyEmail:            getEnv("COMPANY_EMAIL", "example@examle.org"),
+		CompanyEmailPassword:    getEnv("COMPANY_EMAIL_PASSWORD", "password"),
+		CompanyName:             getEnv("COMPANY_NAME", ""),
 	}
 }
 
@@ -50,6 +168,24 @@ func getEnv(key string, defaultVal string) string {
 	return defaultVal
 }
 
+func getEnvInt(key string, defaultVal int) int {
+	if value, exists := os.LookupEnv(key); exists {
+		if number, err := strconv.Atoi(value); err == nil {
+			return number
+		}
+	}
+	return defaultVal
+}
+
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return defaultVal
+}
+
 //Init func initialize a viper config from file
 //func Init() error {
 //	if DEV {
@@ -1,6 +1,15 @@
 package config
 
-var (
-	ContextDeviceKey   = "DeviceContext"
-	ContextClientIDKey = "ClientIdContext"
+//ContextKey is a type of request context keys, it prevents collisions with other packages
+type ContextKey string
+
+const (
+	ContextDeviceKey   ContextKey = "DeviceContext"
+	ContextClientIDKey ContextKey = "ClientIdContext"
+	ContextIPKey       ContextKey = "IPContext"
+	ContextLocationKey ContextKey = "LocationContext"
+	//ContextAccessClaimsKey contains claims of verified access token
+	ContextAccessClaimsKey ContextKey = "AccessClaimsContext"
+	//ContextTokenClaimsKey contains claims of valid bearer token parsed by rate limiter, session isn't checked
+	ContextTokenClaimsKey ContextKey = "TokenClaimsContext"
 )
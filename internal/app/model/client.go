@@ -2,16 +2,143 @@ package model
 
 import "time"
 
+//Client authentication methods at token endpoint, RFC 7591 section 2
+const (
+	ClientAuthNone          = "none"
+	ClientAuthSecretBasic   = "client_secret_basic"
+	ClientAuthSecretPost    = "client_secret_post"
+	ClientAuthPrivateKeyJWT = "private_key_jwt"
+)
+
+//ClientAssertionTypeJWT is a type of client assertion, RFC 7523 section 2.2
+const ClientAssertionTypeJWT = "urn:ietf:params:oauth:client-assertion-type:jwt-bearer"
+
 //Client struct represent client data
 type Client struct {
-	ID               string           `json:"client_id"`
-	ClientName       string           `json:"client_name"`
-	ClientsRefTokens []ClientRefToken `json:"-"`
+	ID                      string           `json:"client_id"`
+	ClientName              string           `json:"client_name"`
+	LogoURI                 string           `json:"logo_uri,omitempty"`
+	RedirectURIs            []string         `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string           `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string         `json:"grant_types,omitempty"`
+	Scope                   string           `json:"scope,omitempty"`
+	Roles                   []string         `json:"roles,omitempty"`
+	RoleDefinitions         []RoleDefinition `json:"role_definitions,omitempty"`
+	JWKS                    *JSONWebKeySet   `json:"jwks,omitempty"`
+	AccessTokenLifetime     int64            `json:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetime    int64            `json:"refresh_token_lifetime,omitempty"`
+	ServiceAccount          bool             `json:"service_account,omitempty"`
+	FirstParty              bool             `json:"first_party,omitempty"`
+	ClientSecret            string           `json:"client_secret,omitempty"`
+	SecretHash              string           `json:"-"`
+	RegistrationTokenHash   string           `json:"-"`
+	CreatedAt               *time.Time       `json:"created_at,omitempty"`
+	ClientsRefTokens        []ClientRefToken `json:"-"`
+}
+
+//RoleDefinition struct represent the role defined by client and permissions inside it
+type RoleDefinition struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+//ClientCredentials struct represent credentials of client at token endpoint
+type ClientCredentials struct {
+	ClientID      string
+	Secret        string
+	AssertionType string
+	Assertion     string
+}
+
+//IsConfidential checks that client must authenticate at token endpoint
+func (c *Client) IsConfidential() bool {
+	return len(c.TokenEndpointAuthMethod) > 0 && c.TokenEndpointAuthMethod != ClientAuthNone
+}
+
+//HasGrantType checks that client is allowed to use the grant type.
+//Clients without grant types use authorization code flow.
+func (c *Client) HasGrantType(grantType string) bool {
+	if len(c.GrantTypes) == 0 {
+		return grantType == GrantTypeAuthorizationCode || grantType == GrantTypeRefreshToken
+	}
+	for _, v := range c.GrantTypes {
+		if v == grantType {
+			return true
+		}
+	}
+	return false
+}
+
+//HasRole checks that role is defined by client
+func (c *Client) HasRole(role string) bool {
+	for _, v := range c.RoleDefinitions {
+		if v.Name == role {
+			return true
+		}
+	}
+	return false
+}
+
+//Permissions returns the permissions of roles defined by client, each permission is returned once
+func (c *Client) Permissions(roles []string) []string {
+	permissions := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, role := range roles {
+		for _, v := range c.RoleDefinitions {
+			if v.Name != role {
+				continue
+			}
+			for _, p := range v.Permissions {
+				if !seen[p] {
+					seen[p] = true
+					permissions = append(permissions, p)
+				}
+			}
+		}
+	}
+	return permissions
+}
+
+//AccessTokenTTL returns lifetime of access tokens issued to client, def is used if client has no own lifetime
+func (c *Client) AccessTokenTTL(def time.Duration) time.Duration {
+	if c.AccessTokenLifetime > 0 {
+		return time.Duration(c.AccessTokenLifetime) * time.Second
+	}
+	return def
+}
+
+//RefreshTokenTTL returns lifetime of refresh tokens issued to client, def is used if client has no own lifetime
+func (c *Client) RefreshTokenTTL(def time.Duration) time.Duration {
+	if c.RefreshTokenLifetime > 0 {
+		return time.Duration(c.RefreshTokenLifetime) * time.Second
+	}
+	return def
+}
+
+//Sanitize removes secrets and tokens of client
+func (c *Client) Sanitize() {
+	c.ClientSecret = ""
+	c.SecretHash = ""
+	c.RegistrationTokenHash = ""
+	c.ClientsRefTokens = nil
+}
+
+//HasRedirectURI checks that redirectURI is registered for client, URIs compared exactly
+func (c *Client) HasRedirectURI(redirectURI string) bool {
+	for _, v := range c.RedirectURIs {
+		if v == redirectURI {
+			return true
+		}
+	}
+	return false
 }
 
 //ClientRefToken struct
 type ClientRefToken struct {
 	SessionID string    `json:"-"`
+	UserID    string    `json:"-"`
+	Scope     string    `json:"-"`
 	RefToken  string    `json:"refToken"`
 	ExpIn     time.Time `json:"exp_in"`
 	CreatedAt time.Time `json:"-"`
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+//Scope struct represent registered scope, description is shown on consent page
+type Scope struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+//Consent struct represent scopes which user granted to client
+type Consent struct {
+	UserID     string     `json:"-"`
+	ClientID   string     `json:"client_id"`
+	ClientName string     `json:"client_name,omitempty"`
+	LogoURI    string     `json:"logo_uri,omitempty"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  *time.Time `json:"created_at,omitempty"`
+	UpdatedAt  *time.Time `json:"updated_at,omitempty"`
+}
@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+//GrantTypeDeviceCode is a grant type of device authorization flow, RFC 8628 section 3.4
+const GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+
+//Statuses of device authorization
+const (
+	DeviceAuthPending  = "pending"
+	DeviceAuthApproved = "approved"
+	DeviceAuthDenied   = "denied"
+)
+
+//Device flow error codes, RFC 8628 section 3.5
+const (
+	OAuthErrAuthorizationPending = "authorization_pending"
+	OAuthErrSlowDown             = "slow_down"
+	OAuthErrExpiredToken         = "expired_token"
+)
+
+//DeviceAuthorization struct represent pending authorization of device
+type DeviceAuthorization struct {
+	DeviceCode   string
+	UserCode     string
+	ClientID     string
+	Scope        string
+	Status       string
+	UserID       string
+	AuthTime     time.Time
+	Device       string
+	IP           string
+	Location     string
+	Interval     time.Duration
+	LastPolledAt time.Time
+	ExpIn        time.Time
+	CreatedAt    time.Time
+}
+
+//DeviceAuthorizationResponce is a responce of device authorization endpoint, RFC 8628 section 3.2
+type DeviceAuthorizationResponce struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int64  `json:"expires_in"`
+	Interval                int64  `json:"interval,omitempty"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+const (
+	EventRefreshTokenReuse  = "refresh_token_reuse"
+	EventMFAEnabled         = "mfa_enabled"
+	EventMFADisabled        = "mfa_disabled"
+	EventRecoveryCodeUsed   = "recovery_code_used"
+	EventWebAuthnRegistered = "webauthn_registered"
+	EventWebAuthnFailed     = "webauthn_failed"
+	EventPasswordReset      = "password_reset"
+	EventAccountLocked      = "account_locked"
+	EventAccountUnlocked    = "account_unlocked"
+)
+
+//SecurityEvent struct represent an audit record of suspicious activity
+type SecurityEvent struct {
+	Type      string            `json:"type"`
+	UserID    string            `json:"user_id,omitempty"`
+	ClientID  string            `json:"client_id,omitempty"`
+	SessionID string            `json:"session_id,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+//MaxGroupDepth is a limit of nested groups, deeper nesting is rejected
+const MaxGroupDepth = 8
+
+//Group struct represent group of users, members inherit roles of group and of its parent groups
+type Group struct {
+	ID          string     `json:"group_id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description,omitempty"`
+	ParentID    string     `json:"parent_id,omitempty"`
+	Members     []string   `json:"members,omitempty"`
+	Roles       []UserRole `json:"roles,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+}
+
+//ClientRoles returns the roles which group grants in the client
+func (g *Group) ClientRoles(clientID string) []string {
+	for _, v := range g.Roles {
+		if v.ClientID == clientID {
+			return v.Roles
+		}
+	}
+	return nil
+}
+
+//RoleSource struct explains where user gets the role from.
+//Path contains names of groups from group of user up to the group which grants the role.
+type RoleSource struct {
+	Direct    bool     `json:"direct"`
+	GroupID   string   `json:"group_id,omitempty"`
+	GroupName string   `json:"group_name,omitempty"`
+	Path      []string `json:"path,omitempty"`
+}
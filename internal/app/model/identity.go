@@ -8,10 +8,68 @@ type Token struct {
 }
 
 type Identity struct {
-	UserID   string `json:"user_id,omitempty"`
-	UserName string `json:"username,omitempty"`
-	Email    string `json:"email,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+	UserName  string `json:"username,omitempty"`
+	Email     string `json:"email,omitempty"`
+	SessionID string `json:"session_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
 	//
 	AuthToken Token `json:"auth_token,omitempty"`
 	RefToken  Token `json:"ref_token,omitempty"`
 }
+
+//AccessClaims struct represent the claims of access token
+type AccessClaims struct {
+	TokenID     string
+	UserID      string
+	ClientID    string
+	SessionID   string
+	Scope       string
+	Roles       []string
+	Permissions []string
+	IssuedAt    time.Time
+	ExpIn       time.Time
+}
+
+//IsClientToken checks that token was issued to client by client_credentials grant, such tokens have no user session
+func (c *AccessClaims) IsClientToken() bool {
+	return len(c.SessionID) == 0
+}
+
+//Subject returns the user ID, or client ID for client tokens
+func (c *AccessClaims) Subject() string {
+	if c.IsClientToken() {
+		return c.ClientID
+	}
+	return c.UserID
+}
+
+//IDClaims struct represent the claims of OpenID Connect ID token
+type IDClaims struct {
+	UserID        string
+	ClientID      string
+	Nonce         string
+	AuthTime      time.Time
+	Email         string
+	EmailVerified bool
+	IssuedAt      time.Time
+	ExpIn         time.Time
+}
+
+//JSONWebKey struct represent a public key, RFC 7517
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Kid string `json:"kid,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+//JSONWebKeySet struct represent a set of public keys
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+//Signing algorithms of asymmetric keys
+const (
+	SigningAlgRS256 = "RS256"
+	SigningAlgES256 = "ES256"
+	SigningAlgEdDSA = "EdDSA"
+)
+
+//SigningKey struct represent the key pair which signs tokens.
+//Only one key is active, retired keys verify tokens till ExpIn.
+type SigningKey struct {
+	ID         string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	PrivateKey string     `json:"-"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpIn      *time.Time `json:"exp_in,omitempty"`
+}
+
+//IsValid checks that the key can be used to verify tokens
+func (k *SigningKey) IsValid(now time.Time) bool {
+	return k.Active || (k.ExpIn != nil && k.ExpIn.After(now))
+}
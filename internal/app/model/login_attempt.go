@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+//LoginAttempts struct represent failed logins of an account or an IP address
+type LoginAttempts struct {
+	Key          string
+	Failures     int
+	Locked       bool
+	BlockedUntil time.Time
+	LastFailure  time.Time
+	ExpIn        time.Time
+}
+
+//Blocked reports whether logins are rejected at the moment
+func (l *LoginAttempts) Blocked(now time.Time) bool {
+	return now.Before(l.BlockedUntil)
+}
+
+//LoginThrottle struct represent a policy of failed logins: the first free failures aren't delayed,
+//next ones are delayed exponentially and threshold failures lock logins for the lockout duration
+type LoginThrottle struct {
+	Free        int
+	Threshold   int
+	BackoffBase time.Duration
+	Lockout     time.Duration
+}
+
+//Backoff returns how long logins are rejected after the failures, locked is true if threshold is reached
+func (t *LoginThrottle) Backoff(failures int) (time.Duration, bool) {
+	if t.Threshold > 0 && failures >= t.Threshold {
+		return t.Lockout, true
+	}
+	if failures <= t.Free {
+		return 0, false
+	}
+	delay := t.BackoffBase
+	for i := t.Free + 1; i < failures && delay < t.Lockout; i++ {
+		delay *= 2
+	}
+	if delay > t.Lockout {
+		delay = t.Lockout
+	}
+	return delay, false
+}
+
+//Fail counts failed login at the time, counter expires after lockout duration since the last failure
+func (l *LoginAttempts) Fail(throttle *LoginThrottle, now time.Time) {
+	l.Failures++
+	l.LastFailure = now
+	l.ExpIn = now.Add(throttle.Lockout)
+	delay, locked := throttle.Backoff(l.Failures)
+	l.Locked = locked
+	if delay > 0 {
+		l.BlockedUntil = now.Add(delay)
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+const (
+	//RecoveryCodesCount is a number of one-time recovery codes issued on TOTP enrollment
+	RecoveryCodesCount = 10
+	//MFAMaxAttempts is a number of wrong codes after which MFA challenge is dropped
+	MFAMaxAttempts = 5
+)
+
+//Second factor methods
+const (
+	MFAMethodTOTP     = "totp"
+	MFAMethodWebAuthn = "webauthn"
+)
+
+//MFA struct represent second factor of user, it is never sent to clients
+type MFA struct {
+	TOTPSecret  string
+	TOTPEnabled bool
+	//TOTPLastStep is a time step of the last accepted code, so codes can't be replayed
+	TOTPLastStep int64
+	//RecoveryCodes are hashes of unused recovery codes
+	RecoveryCodes []string
+}
+
+//TOTPEnrollment struct represent pending TOTP, it is enabled after confirmation by the first code
+type TOTPEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+//RecoveryCodes struct represent one-time recovery codes, they are shown to user once
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
+
+//MFAChallenge struct represent login which waits for second factor code, session is created after it
+type MFAChallenge struct {
+	MFARequired bool      `json:"mfa_required"`
+	Token       string    `json:"mfa_token"`
+	Methods     []string  `json:"methods,omitempty"`
+	UserID      string    `json:"-"`
+	ClientID    string    `json:"-"`
+	Attempts    int       `json:"-"`
+	ExpIn       time.Time `json:"exp_in"`
+	CreatedAt   time.Time `json:"-"`
+}
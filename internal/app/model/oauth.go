@@ -0,0 +1,168 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+
+	CodeChallengeMethodS256 = "S256"
+
+	TokenTypeHintAccessToken  = "access_token"
+	TokenTypeHintRefreshToken = "refresh_token"
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+//OAuth 2.0 error codes, RFC 6749 section 5.2
+const (
+	OAuthErrInvalidRequest       = "invalid_request"
+	OAuthErrInvalidClient        = "invalid_client"
+	OAuthErrInvalidGrant         = "invalid_grant"
+	OAuthErrUnauthorizedClient   = "unauthorized_client"
+	OAuthErrUnsupportedGrantType = "unsupported_grant_type"
+	OAuthErrUnsupportedRespType  = "unsupported_response_type"
+	OAuthErrAccessDenied         = "access_denied"
+	OAuthErrInvalidScope         = "invalid_scope"
+	OAuthErrServerError          = "server_error"
+)
+
+//AuthorizeRequest struct represent parameters of authorization request
+type AuthorizeRequest struct {
+	ResponseType        string `json:"response_type"`
+	ClientID            string `json:"client_id"`
+	RedirectURI         string `json:"redirect_uri"`
+	Scope               string `json:"scope,omitempty"`
+	State               string `json:"state,omitempty"`
+	Nonce               string `json:"nonce,omitempty"`
+	CodeChallenge       string `json:"code_challenge"`
+	CodeChallengeMethod string `json:"code_challenge_method"`
+}
+
+//AuthCode struct represent issued authorization code
+type AuthCode struct {
+	Code                string
+	ClientID            string
+	UserID              string
+	RedirectURI         string
+	Scope               string
+	Nonce               string
+	AuthTime            time.Time
+	CodeChallenge       string
+	CodeChallengeMethod string
+	//Pending code can't be exchanged till user gives consent
+	Pending   bool
+	ExpIn     time.Time
+	CreatedAt time.Time
+}
+
+//TokenRequest struct represent parameters of token request
+type TokenRequest struct {
+	GrantType    string
+	Credentials  ClientCredentials
+	Code         string
+	DeviceCode   string
+	RedirectURI  string
+	CodeVerifier string
+	RefToken     string
+	Scope        string
+}
+
+//OAuthTokenResponce is a successful token responce, RFC 6749 section 5.1
+type OAuthTokenResponce struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+//CreateOAuthTokenResponce a constructor of token responce from identity
+func CreateOAuthTokenResponce(identity *Identity) *OAuthTokenResponce {
+	return &OAuthTokenResponce{
+		AccessToken:  identity.AuthToken.Token,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(time.Until(identity.AuthToken.ExpIn).Seconds()),
+		RefreshToken: identity.RefToken.Token,
+		Scope:        identity.Scope,
+	}
+}
+
+//ContainsScopes checks that space-delimited scope list contains all requested scopes
+func ContainsScopes(scopes, requested string) bool {
+	for _, v := range strings.Fields(requested) {
+		if !HasScope(scopes, v) {
+			return false
+		}
+	}
+	return true
+}
+
+//HasScope checks that space-delimited scope list contains the scope
+func HasScope(scopes, scope string) bool {
+	for _, v := range strings.Fields(scopes) {
+		if v == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//OpenIDConfiguration is a provider metadata, OpenID Connect Discovery 1.0 section 3
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	IntrospectionEndpoint             string   `json:"introspection_endpoint"`
+	RevocationEndpoint                string   `json:"revocation_endpoint"`
+	DeviceAuthorizationEndpoint       string   `json:"device_authorization_endpoint"`
+	RegistrationEndpoint              string   `json:"registration_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+//IntrospectionResponce is a responce of introspection endpoint, RFC 7662 section 2.2.
+//Only "active" field is sent for inactive token.
+type IntrospectionResponce struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+//OAuthError is an error responce of OAuth 2.0 endpoints
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+//NewOAuthError a constructor of OAuthError
+func NewOAuthError(code, description string) *OAuthError {
+	return &OAuthError{
+		Code:        code,
+		Description: description,
+	}
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+//PasswordReset struct represent a single-use token which allows user to set new password
+type PasswordReset struct {
+	Token     string
+	UserID    string
+	ExpIn     time.Time
+	CreatedAt time.Time
+}
+
+//PasswordResetRequest struct represent a body of "forgot password" request
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+//NewPasswordRequest struct represent a body of "reset password" request
+type NewPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+//Keys of rate limit buckets
+const (
+	RateLimitByIP     = "ip"
+	RateLimitByClient = "client"
+	RateLimitByUser   = "user"
+)
+
+//RateLimitDefaultRoute is a route of policy which is applied to routes without own policy
+const RateLimitDefaultRoute = "*"
+
+//RateLimitPolicy struct represent a limit of requests to route, each key gets own bucket
+type RateLimitPolicy struct {
+	Route  string
+	Limit  int
+	Period time.Duration
+	Burst  int
+	Keys   []string
+}
+
+//Rate returns number of tokens which bucket gains per second
+func (p *RateLimitPolicy) Rate() float64 {
+	return float64(p.Limit) / p.Period.Seconds()
+}
+
+//RateLimitResult struct represent state of the most exhausted bucket after request,
+//limit is a burst of bucket and quota is a number of requests per period
+type RateLimitResult struct {
+	Allowed    bool
+	Limit      int
+	Quota      int
+	Period     time.Duration
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+//TokenBucket struct represent a bucket which gains rate tokens per second up to burst
+type TokenBucket struct {
+	Tokens    float64
+	UpdatedAt time.Time
+}
+
+//NewTokenBucket returns full bucket
+func NewTokenBucket(burst int, now time.Time) *TokenBucket {
+	return &TokenBucket{
+		Tokens:    float64(burst),
+		UpdatedAt: now,
+	}
+}
+
+//Take refills bucket and takes one token, it returns false if bucket has no token
+func (b *TokenBucket) Take(rate float64, burst int, now time.Time) bool {
+	//Clocks of instances may differ, bucket isn't drained by negative elapsed time
+	elapsed := math.Max(0, now.Sub(b.UpdatedAt).Seconds())
+	b.Tokens = math.Min(float64(burst), b.Tokens+elapsed*rate)
+	b.UpdatedAt = now
+	if b.Tokens < 1 {
+		return false
+	}
+	b.Tokens--
+	return true
+}
+
+//FullIn returns time after which bucket is full again
+func (b *TokenBucket) FullIn(rate float64, burst int) time.Duration {
+	return time.Duration((float64(burst) - b.Tokens) / rate * float64(time.Second))
+}
+
+//NextIn returns time after which bucket has a token
+func (b *TokenBucket) NextIn(rate float64) time.Duration {
+	if b.Tokens >= 1 {
+		return 0
+	}
+	return time.Duration((1 - b.Tokens) / rate * float64(time.Second))
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	now := time.Now()
+	//2 tokens per second up to 3
+	rate, burst := 2.0, 3
+	b := NewTokenBucket(burst, now)
+	for i := 0; i < burst; i++ {
+		if !b.Take(rate, burst, now) {
+			t.Fatalf("request %d of burst rejected", i+1)
+		}
+	}
+	if b.Take(rate, burst, now) {
+		t.Fatal("request above burst allowed")
+	}
+	if next := b.NextIn(rate); next != 500*time.Millisecond {
+		t.Fatalf("next token in %v, want 500ms", next)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if !b.Take(rate, burst, now) {
+		t.Fatal("refilled token rejected")
+	}
+	if b.Take(rate, burst, now) {
+		t.Fatal("request above refill allowed")
+	}
+	if full := b.FullIn(rate, burst); full != 1500*time.Millisecond {
+		t.Fatalf("full in %v, want 1.5s", full)
+	}
+	//bucket isn't filled above burst
+	now = now.Add(time.Hour)
+	b.Take(rate, burst, now)
+	if b.Tokens != float64(burst-1) {
+		t.Fatalf("%v tokens after long pause, want %d", b.Tokens, burst-1)
+	}
+}
+
+func TestTokenBucketClockSkew(t *testing.T) {
+	now := time.Now()
+	b := NewTokenBucket(1, now)
+	if !b.Take(1, 1, now) {
+		t.Fatal("first request rejected")
+	}
+	//clock of other instance is behind, bucket isn't drained below zero
+	if b.Take(1, 1, now.Add(-time.Minute)) {
+		t.Fatal("request with earlier clock allowed")
+	}
+	if b.Tokens < 0 {
+		t.Fatalf("%v tokens after earlier clock", b.Tokens)
+	}
+}
@@ -0,0 +1,66 @@
+package model
+
+//Dynamic client registration error codes, RFC 7591 section 3.2.2
+const (
+	OAuthErrInvalidRedirectURI    = "invalid_redirect_uri"
+	OAuthErrInvalidClientMetadata = "invalid_client_metadata"
+)
+
+//ClientMetadata is a client metadata which client registers by itself, RFC 7591 section 2
+type ClientMetadata struct {
+	ClientName              string         `json:"client_name,omitempty"`
+	LogoURI                 string         `json:"logo_uri,omitempty"`
+	RedirectURIs            []string       `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string         `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string       `json:"grant_types,omitempty"`
+	Scope                   string         `json:"scope,omitempty"`
+	JWKS                    *JSONWebKeySet `json:"jwks,omitempty"`
+}
+
+//ClientInformation is a responce of client registration endpoints, RFC 7591 section 3.2.1 and RFC 7592 section 3
+type ClientInformation struct {
+	ClientMetadata
+	ClientID                string `json:"client_id"`
+	ClientSecret            string `json:"client_secret,omitempty"`
+	ClientIDIssuedAt        int64  `json:"client_id_issued_at,omitempty"`
+	ClientSecretExpiresAt   *int64 `json:"client_secret_expires_at,omitempty"`
+	RegistrationAccessToken string `json:"registration_access_token,omitempty"`
+	RegistrationClientURI   string `json:"registration_client_uri,omitempty"`
+}
+
+//Apply replaces registration data of client by metadata
+func (m *ClientMetadata) Apply(client *Client) {
+	client.ClientName = m.ClientName
+	client.LogoURI = m.LogoURI
+	client.RedirectURIs = m.RedirectURIs
+	client.TokenEndpointAuthMethod = m.TokenEndpointAuthMethod
+	client.GrantTypes = m.GrantTypes
+	client.Scope = m.Scope
+	client.JWKS = m.JWKS
+}
+
+//NewClientInformation a constructor of ClientInformation, secret and registration token are set by caller
+func NewClientInformation(client *Client) *ClientInformation {
+	info := &ClientInformation{
+		ClientMetadata: ClientMetadata{
+			ClientName:              client.ClientName,
+			LogoURI:                 client.LogoURI,
+			RedirectURIs:            client.RedirectURIs,
+			TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+			GrantTypes:              client.GrantTypes,
+			Scope:                   client.Scope,
+			JWKS:                    client.JWKS,
+		},
+		ClientID:     client.ID,
+		ClientSecret: client.ClientSecret,
+	}
+	if client.CreatedAt != nil {
+		info.ClientIDIssuedAt = client.CreatedAt.Unix()
+	}
+	if len(client.ClientSecret) > 0 {
+		//secrets never expire
+		var expiresAt int64
+		info.ClientSecretExpiresAt = &expiresAt
+	}
+	return info
+}
@@ -34,11 +34,30 @@ func (u *User) SanitizeForRegistration() {
 //UserSession struct
 type UserSession struct {
 	SessionID      string    `json:"session_id,omitempty"`
+	ClientID       string    `json:"client_id,omitempty"`
 	ClientName     string    `json:"client_name,omitempty"`
 	Device         string    `json:"device,omitempty"`
+	IP             string    `json:"ip,omitempty"`
+	Location       string    `json:"location,omitempty"`
 	LastActiveTime time.Time `json:"last_active_time,omitempty"`
+	Current        bool      `json:"current"`
 }
+
+//RoleAdmin is a role of admin client, users with it manage the server by admin API
+const RoleAdmin = "admin"
+
+//UserRole struct represent roles granted to user in the client and their permissions
 type UserRole struct {
-	ClientName string   `bson:"client_name,omitempty"`
-	Roles      []string `bson:"roles,omitempty"`
+	ClientID    string   `json:"client_id"`
+	ClientName  string   `json:"client_name,omitempty"`
+	Roles       []string `json:"roles"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+//RoleAssignment struct represent roles granted to user in the client
+type RoleAssignment struct {
+	UserID   string   `json:"user_id"`
+	UserName string   `json:"username,omitempty"`
+	ClientID string   `json:"client_id"`
+	Roles    []string `json:"roles"`
 }
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"auth-server/pkg/webauthn"
+	"time"
+)
+
+//Purposes of WebAuthn challenges
+const (
+	WebAuthnRegistration = "registration"
+	WebAuthnLogin        = "login"
+	WebAuthnMFA          = "mfa"
+)
+
+//WebAuthnChallengeTTL is a time which user has to answer WebAuthn challenge
+const WebAuthnChallengeTTL = 5 * time.Minute
+
+//WebAuthnCredential struct represent passkey of user
+type WebAuthnCredential struct {
+	ID                string     `json:"id"`
+	Name              string     `json:"name,omitempty"`
+	PublicKey         []byte     `json:"-"`
+	SignCount         uint32     `json:"sign_count"`
+	AttestationFormat string     `json:"attestation_format"`
+	AAGUID            string     `json:"aaguid,omitempty"`
+	Transports        []string   `json:"transports,omitempty"`
+	BackupEligible    bool       `json:"backup_eligible"`
+	CreatedAt         *time.Time `json:"created_at,omitempty"`
+	LastUsedAt        *time.Time `json:"last_used_at,omitempty"`
+}
+
+//WebAuthnChallenge struct represent a registration or login ceremony which waits for authenticator response
+type WebAuthnChallenge struct {
+	Challenge string
+	Purpose   string
+	UserID    string
+	ClientID  string
+	ExpIn     time.Time
+	CreatedAt time.Time
+}
+
+//MFAAnswer struct represent answer to MFA challenge, TOTP or recovery code or passkey assertion
+type MFAAnswer struct {
+	Code     string                      `json:"code,omitempty"`
+	WebAuthn *webauthn.AssertionResponse `json:"webauthn,omitempty"`
+}
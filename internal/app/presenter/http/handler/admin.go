@@ -0,0 +1,553 @@
+package handler
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service/services"
+	errors "auth-server/pkg/errors/types"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+type AdminHandler struct {
+	Handler
+	serviceManager *services.Manager
+	adminClientID  string
+}
+
+//serviceAccountRequest is a body of service account creation request
+type serviceAccountRequest struct {
+	ClientName              string               `json:"client_name"`
+	Scope                   string               `json:"scope,omitempty"`
+	Roles                   []string             `json:"roles,omitempty"`
+	TokenEndpointAuthMethod string               `json:"token_endpoint_auth_method,omitempty"`
+	JWKS                    *model.JSONWebKeySet `json:"jwks,omitempty"`
+}
+
+//clientRequest is a body of client creation and update requests
+type clientRequest struct {
+	ClientName              string                 `json:"client_name"`
+	LogoURI                 string                 `json:"logo_uri,omitempty"`
+	FirstParty              bool                   `json:"first_party,omitempty"`
+	RedirectURIs            []string               `json:"redirect_uris,omitempty"`
+	TokenEndpointAuthMethod string                 `json:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string               `json:"grant_types,omitempty"`
+	Scope                   string                 `json:"scope,omitempty"`
+	Roles                   []string               `json:"roles,omitempty"`
+	RoleDefinitions         []model.RoleDefinition `json:"role_definitions,omitempty"`
+	JWKS                    *model.JSONWebKeySet   `json:"jwks,omitempty"`
+	AccessTokenLifetime     int64                  `json:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetime    int64                  `json:"refresh_token_lifetime,omitempty"`
+}
+
+//toClient converts request to client with clientID
+func (c *clientRequest) toClient(clientID string) *model.Client {
+	return &model.Client{
+		ID:                      clientID,
+		ClientName:              c.ClientName,
+		LogoURI:                 c.LogoURI,
+		FirstParty:              c.FirstParty,
+		RedirectURIs:            c.RedirectURIs,
+		TokenEndpointAuthMethod: c.TokenEndpointAuthMethod,
+		GrantTypes:              c.GrantTypes,
+		Scope:                   c.Scope,
+		Roles:                   c.Roles,
+		RoleDefinitions:         c.RoleDefinitions,
+		JWKS:                    c.JWKS,
+		AccessTokenLifetime:     c.AccessTokenLifetime,
+		RefreshTokenLifetime:    c.RefreshTokenLifetime,
+	}
+}
+
+//groupRequest is a body of group creation and update requests
+type groupRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	ParentID    string `json:"parent_id,omitempty"`
+}
+
+//NewAdminHandler creates handler of admin API, only admins of adminClientID have access to it
+func NewAdminHandler(manager *services.Manager, adminClientID string) *AdminHandler {
+	if len(adminClientID) == 0 {
+		log.Println("Admin client ID is not set, admin API is disabled.")
+	}
+	return &AdminHandler{
+		Handler:        Handler{},
+		serviceManager: manager,
+		adminClientID:  adminClientID,
+	}
+}
+
+//ConfigureRoutes ...
+func (a *AdminHandler) ConfigureRoutes(router *mux.Router) {
+	admin := router.PathPrefix("/admin").Subrouter()
+	admin.Use(a.authorize(a.serviceManager), a.requireAdmin(a.serviceManager, a.adminClientID))
+	admin.HandleFunc("/clients", a.getClients()).Methods(http.MethodGet)
+	admin.HandleFunc("/clients", a.createClient()).Methods(http.MethodPost)
+	admin.HandleFunc("/clients/{id}", a.getClient()).Methods(http.MethodGet)
+	admin.HandleFunc("/clients/{id}", a.updateClient()).Methods(http.MethodPut)
+	admin.HandleFunc("/clients/{id}", a.deleteClient()).Methods(http.MethodDelete)
+	admin.HandleFunc("/clients/{id}/secret", a.resetClientSecret()).Methods(http.MethodPost)
+	admin.HandleFunc("/clients/{id}/assignments", a.getRoleAssignments()).Methods(http.MethodGet)
+	admin.HandleFunc("/clients/{id}/assignments/{user_id}/{role}", a.explainRole()).Methods(http.MethodGet)
+	admin.HandleFunc("/clients/{id}/assignments/{user_id}/{role}", a.grantRole()).Methods(http.MethodPut)
+	admin.HandleFunc("/clients/{id}/assignments/{user_id}/{role}", a.revokeRole()).Methods(http.MethodDelete)
+	admin.HandleFunc("/groups", a.getGroups()).Methods(http.MethodGet)
+	admin.HandleFunc("/groups", a.createGroup()).Methods(http.MethodPost)
+	admin.HandleFunc("/groups/{id}", a.getGroup()).Methods(http.MethodGet)
+	admin.HandleFunc("/groups/{id}", a.updateGroup()).Methods(http.MethodPut)
+	admin.HandleFunc("/groups/{id}", a.deleteGroup()).Methods(http.MethodDelete)
+	admin.HandleFunc("/groups/{id}/members/{user_id}", a.addGroupMember()).Methods(http.MethodPut)
+	admin.HandleFunc("/groups/{id}/members/{user_id}", a.removeGroupMember()).Methods(http.MethodDelete)
+	admin.HandleFunc("/groups/{id}/roles/{client_id}/{role}", a.grantGroupRole()).Methods(http.MethodPut)
+	admin.HandleFunc("/groups/{id}/roles/{client_id}/{role}", a.revokeGroupRole()).Methods(http.MethodDelete)
+	admin.HandleFunc("/scopes", a.getScopes()).Methods(http.MethodGet)
+	admin.HandleFunc("/scopes", a.createScope()).Methods(http.MethodPost)
+	admin.HandleFunc("/scopes/{name}", a.updateScope()).Methods(http.MethodPut)
+	admin.HandleFunc("/scopes/{name}", a.deleteScope()).Methods(http.MethodDelete)
+	admin.HandleFunc("/users/{id}/lockout", a.unlockUser()).Methods(http.MethodDelete)
+	admin.HandleFunc("/service-accounts", a.getServiceAccounts()).Methods(http.MethodGet)
+	admin.HandleFunc("/service-accounts", a.createServiceAccount()).Methods(http.MethodPost)
+	admin.HandleFunc("/service-accounts/{id}", a.deleteServiceAccount()).Methods(http.MethodDelete)
+	admin.HandleFunc("/service-accounts/{id}/secret", a.resetServiceAccountSecret()).Methods(http.MethodPost)
+}
+
+func (a *AdminHandler) getClients() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getClients, handler: admin.")
+		clients, err := a.serviceManager.Client.FindClients(r.Context())
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(clients))
+		for _, v := range clients {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) getClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getClient, handler: admin.")
+		client, err := a.serviceManager.Client.FindClientByID(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(client)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) createClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: createClient, handler: admin.")
+		req := &clientRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid client data."))
+			return
+		}
+		client, err := a.serviceManager.Client.CreateClient(r.Context(), req.toClient(""))
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(client)
+		a.respondJson(w, r, http.StatusCreated, responce)
+	}
+}
+
+func (a *AdminHandler) updateClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: updateClient, handler: admin.")
+		req := &clientRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid client data."))
+			return
+		}
+		client, err := a.serviceManager.Client.UpdateClient(r.Context(), req.toClient(mux.Vars(r)["id"]))
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(client)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) deleteClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteClient, handler: admin.")
+		err := a.serviceManager.Client.DeleteClient(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) getRoleAssignments() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getRoleAssignments, handler: admin.")
+		assignments, err := a.serviceManager.User.FindRoleAssignments(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(assignments))
+		for _, v := range assignments {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) explainRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: explainRole, handler: admin.")
+		vars := mux.Vars(r)
+		sources, err := a.serviceManager.User.ExplainRole(r.Context(), vars["user_id"], vars["id"], vars["role"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(sources))
+		for _, v := range sources {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) grantRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: grantRole, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.User.GrantRole(r.Context(), vars["user_id"], vars["id"], vars["role"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) revokeRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: revokeRole, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.User.RevokeRole(r.Context(), vars["user_id"], vars["id"], vars["role"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) getGroups() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getGroups, handler: admin.")
+		groups, err := a.serviceManager.Group.FindGroups(r.Context())
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(groups))
+		for _, v := range groups {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) getGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getGroup, handler: admin.")
+		group, err := a.serviceManager.Group.FindGroupByID(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(group)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) createGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: createGroup, handler: admin.")
+		req := &groupRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid group data."))
+			return
+		}
+		group, err := a.serviceManager.Group.CreateGroup(r.Context(), &model.Group{
+			Name:        req.Name,
+			Description: req.Description,
+			ParentID:    req.ParentID,
+		})
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(group)
+		a.respondJson(w, r, http.StatusCreated, responce)
+	}
+}
+
+func (a *AdminHandler) updateGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: updateGroup, handler: admin.")
+		req := &groupRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid group data."))
+			return
+		}
+		group, err := a.serviceManager.Group.UpdateGroup(r.Context(), &model.Group{
+			ID:          mux.Vars(r)["id"],
+			Name:        req.Name,
+			Description: req.Description,
+			ParentID:    req.ParentID,
+		})
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(group)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) deleteGroup() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteGroup, handler: admin.")
+		err := a.serviceManager.Group.DeleteGroup(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) unlockUser() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: unlockUser, handler: admin.")
+		err := a.serviceManager.User.UnlockUser(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) addGroupMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: addGroupMember, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.Group.AddMember(r.Context(), vars["id"], vars["user_id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) removeGroupMember() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: removeGroupMember, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.Group.RemoveMember(r.Context(), vars["id"], vars["user_id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) grantGroupRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: grantGroupRole, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.Group.GrantRole(r.Context(), vars["id"], vars["client_id"], vars["role"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) revokeGroupRole() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: revokeGroupRole, handler: admin.")
+		vars := mux.Vars(r)
+		err := a.serviceManager.Group.RevokeRole(r.Context(), vars["id"], vars["client_id"], vars["role"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) getScopes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getScopes, handler: admin.")
+		scopes, err := a.serviceManager.Scope.FindScopes(r.Context())
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(scopes))
+		for _, v := range scopes {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) createScope() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: createScope, handler: admin.")
+		scope := &model.Scope{}
+		if err := json.NewDecoder(r.Body).Decode(scope); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid scope data."))
+			return
+		}
+		if err := a.serviceManager.Scope.CreateScope(r.Context(), scope); err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(scope)
+		a.respondJson(w, r, http.StatusCreated, responce)
+	}
+}
+
+func (a *AdminHandler) updateScope() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: updateScope, handler: admin.")
+		scope := &model.Scope{}
+		if err := json.NewDecoder(r.Body).Decode(scope); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid scope data."))
+			return
+		}
+		scope.Name = mux.Vars(r)["name"]
+		if err := a.serviceManager.Scope.UpdateScope(r.Context(), scope); err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(scope)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) deleteScope() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteScope, handler: admin.")
+		err := a.serviceManager.Scope.DeleteScope(r.Context(), mux.Vars(r)["name"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) getServiceAccounts() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getServiceAccounts, handler: admin.")
+		accounts, err := a.serviceManager.Client.FindServiceAccounts(r.Context())
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(accounts))
+		for _, v := range accounts {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) createServiceAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: createServiceAccount, handler: admin.")
+		req := &serviceAccountRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid service account data."))
+			return
+		}
+		account, err := a.serviceManager.Client.CreateServiceAccount(r.Context(), &model.Client{
+			ClientName:              req.ClientName,
+			Scope:                   req.Scope,
+			Roles:                   req.Roles,
+			TokenEndpointAuthMethod: req.TokenEndpointAuthMethod,
+			JWKS:                    req.JWKS,
+		})
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(account)
+		a.respondJson(w, r, http.StatusCreated, responce)
+	}
+}
+
+func (a *AdminHandler) deleteServiceAccount() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteServiceAccount, handler: admin.")
+		err := a.serviceManager.Client.DeleteServiceAccount(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AdminHandler) resetClientSecret() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: resetClientSecret, handler: admin.")
+		secret, err := a.serviceManager.Client.ResetClientSecret(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(map[string]string{"client_secret": secret})
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AdminHandler) resetServiceAccountSecret() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: resetServiceAccountSecret, handler: admin.")
+		secret, err := a.serviceManager.Client.ResetServiceAccountSecret(r.Context(), mux.Vars(r)["id"])
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(map[string]string{"client_secret": secret})
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
@@ -1,9 +1,21 @@
 package handler
 
 import (
+	"auth-server/internal/app/model"
 	"auth-server/internal/app/service/services"
-	"github.com/gorilla/mux"
+	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/webauthn"
+	"encoding/json"
+	"log"
 	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	RefTokenCookieName = "ref_token"
+	RefTokenCookiePath = "/auth"
 )
 
 type AuthHandler struct {
@@ -11,6 +23,28 @@ type AuthHandler struct {
 	serviceManager *services.Manager
 }
 
+//loginRequest is a body of login request
+type loginRequest struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+//mfaRequest is a body of second step of login, answer is TOTP, recovery code or passkey assertion
+type mfaRequest struct {
+	MFAToken string `json:"mfa_token"`
+	model.MFAAnswer
+}
+
+//webauthnLoginRequest is a body of passwordless login options request, login is optional
+type webauthnLoginRequest struct {
+	Login string `json:"login,omitempty"`
+}
+
+//refreshRequest is a body of refresh request, refresh token may be sent by cookie
+type refreshRequest struct {
+	RefToken string `json:"ref_token,omitempty"`
+}
+
 func NewAuthHandler(serviceManager *services.Manager) *AuthHandler {
 	return &AuthHandler{
 		Handler:        Handler{},
@@ -20,10 +54,217 @@ func NewAuthHandler(serviceManager *services.Manager) *AuthHandler {
 
 func (a *AuthHandler) ConfigureRoutes(router *mux.Router) {
 
-	//auth := router.PathPrefix("/auth").Subrouter()
-
+	auth := router.PathPrefix("/auth").Subrouter()
+	auth.HandleFunc("/login", a.authenticate()).Methods(http.MethodPost)
+	auth.HandleFunc("/login/mfa", a.authenticateMFA()).Methods(http.MethodPost)
+	auth.HandleFunc("/login/mfa/webauthn", a.webauthnMFAOptions()).Methods(http.MethodPost)
+	auth.HandleFunc("/webauthn/options", a.webauthnLoginOptions()).Methods(http.MethodPost)
+	auth.HandleFunc("/webauthn/login", a.authenticateWebAuthn()).Methods(http.MethodPost)
+	auth.HandleFunc("/refresh", a.refresh()).Methods(http.MethodPost)
+	auth.Handle("/logout", a.authorize(a.serviceManager)(a.logout())).Methods(http.MethodPost)
 }
 
 func (a *AuthHandler) authenticate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: authenticate, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &loginRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil || len(req.Login) == 0 || len(req.Password) == 0 {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid login data."))
+			return
+		}
+		identity, challenge, err := a.serviceManager.User.Authenticate(r.Context(), req.Login, req.Password, clientID)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		if challenge != nil {
+			responce := model.CreateOneOkResponce(challenge)
+			a.respondJson(w, r, http.StatusOK, responce)
+			return
+		}
+		a.setRefTokenCookie(w, &identity.RefToken)
+		responce := model.CreateOneOkResponce(identity)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) authenticateMFA() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: authenticateMFA, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &mfaRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil || len(req.MFAToken) == 0 || (len(req.Code) == 0 && req.WebAuthn == nil) {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid MFA data."))
+			return
+		}
+		identity, err := a.serviceManager.User.AuthenticateMFA(r.Context(), req.MFAToken, clientID, &req.MFAAnswer)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		a.setRefTokenCookie(w, &identity.RefToken)
+		responce := model.CreateOneOkResponce(identity)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) webauthnMFAOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: webauthnMFAOptions, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &mfaRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil || len(req.MFAToken) == 0 {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid MFA data."))
+			return
+		}
+		options, err := a.serviceManager.User.BeginWebAuthnMFA(r.Context(), req.MFAToken, clientID)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(options)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) webauthnLoginOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: webauthnLoginOptions, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &webauthnLoginRequest{}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				a.error(w, r, errors.ErrInvalidArgument.New("Invalid login data."))
+				return
+			}
+		}
+		options, err := a.serviceManager.User.BeginWebAuthnLogin(r.Context(), clientID, req.Login)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(options)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) authenticateWebAuthn() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: authenticateWebAuthn, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &webauthn.AssertionResponse{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			a.error(w, r, errors.ErrInvalidArgument.New("Invalid credential."))
+			return
+		}
+		identity, err := a.serviceManager.User.FinishWebAuthnLogin(r.Context(), clientID, req)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		a.setRefTokenCookie(w, &identity.RefToken)
+		responce := model.CreateOneOkResponce(identity)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) refresh() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: refresh, handler: auth.")
+		clientID := contextClientID(r)
+		if len(clientID) == 0 {
+			a.error(w, r, errors.ErrUnauthorized.New("Client required."))
+			return
+		}
+		req := &refreshRequest{}
+		if r.ContentLength != 0 {
+			err := json.NewDecoder(r.Body).Decode(req)
+			if err != nil {
+				a.error(w, r, errors.ErrInvalidArgument.New("Invalid refresh data."))
+				return
+			}
+		}
+		if len(req.RefToken) == 0 {
+			if cookie, err := r.Cookie(RefTokenCookieName); err == nil {
+				req.RefToken = cookie.Value
+			}
+		}
+		identity, err := a.serviceManager.User.UpdateRefToken(r.Context(), clientID, req.RefToken)
+		if err != nil {
+			a.clearRefTokenCookie(w)
+			a.error(w, r, err)
+			return
+		}
+		a.setRefTokenCookie(w, &identity.RefToken)
+		responce := model.CreateOneOkResponce(identity)
+		a.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (a *AuthHandler) logout() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: logout, handler: auth.")
+		claims := accessClaims(r)
+		err := a.serviceManager.User.SignOut(r.Context(), claims.UserID, claims.SessionID)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		err = a.serviceManager.Token.RevokeAccessToken(r.Context(), claims)
+		if err != nil {
+			a.error(w, r, err)
+			return
+		}
+		a.clearRefTokenCookie(w)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (a *AuthHandler) setRefTokenCookie(w http.ResponseWriter, refToken *model.Token) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefTokenCookieName,
+		Value:    refToken.Token,
+		Path:     RefTokenCookiePath,
+		Expires:  refToken.ExpIn,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
 
+func (a *AuthHandler) clearRefTokenCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefTokenCookieName,
+		Value:    "",
+		Path:     RefTokenCookiePath,
+		Expires:  time.Unix(0, 0),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
 }
@@ -0,0 +1,44 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+)
+
+const (
+	csrfCookie = "csrf_token"
+	csrfField  = "csrf_token"
+)
+
+//csrfToken returns the token of login forms and sets it in cookie if it isn't set yet.
+//Form is accepted only if it sends back the same token as cookie (double submit cookie).
+func csrfToken(w http.ResponseWriter, r *http.Request) string {
+	if cookie, err := r.Cookie(csrfCookie); err == nil && len(cookie.Value) > 0 {
+		return cookie.Value
+	}
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return ""
+	}
+	token := hex.EncodeToString(bytes)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookie,
+		Value:    token,
+		Path:     "/oauth",
+		HttpOnly: true,
+		Secure:   r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https",
+		SameSite: http.SameSiteLaxMode,
+	})
+	return token
+}
+
+//checkCSRF compares token of the form with cookie
+func checkCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookie)
+	if err != nil || len(cookie.Value) == 0 {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostFormValue(csrfField))) == 1
+}
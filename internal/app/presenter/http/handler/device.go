@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+)
+
+const ClientIDHeader = "X-Client-ID"
+
+//userAgentMarker is a substring of User-Agent with a readable name
+type userAgentMarker struct {
+	marker string
+	name   string
+}
+
+//Order matters, e.g. Edge and Opera user agents contain "Chrome"
+var (
+	browserMarkers = []userAgentMarker{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"YaBrowser/", "Yandex Browser"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"okhttp/", "Android app"},
+		{"CFNetwork/", "iOS app"},
+		{"curl/", "curl"},
+	}
+	osMarkers = []userAgentMarker{
+		{"Windows", "Windows"},
+		{"Android", "Android"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+)
+
+func findMarker(userAgent string, markers []userAgentMarker) string {
+	for _, m := range markers {
+		if strings.Contains(userAgent, m.marker) {
+			return m.name
+		}
+	}
+	return ""
+}
+
+//deviceDescriptor returns a human readable device description, e.g. "Chrome on Windows (10.0.0.1)"
+func deviceDescriptor(userAgent, ip string) string {
+	browser := findMarker(userAgent, browserMarkers)
+	os := findMarker(userAgent, osMarkers)
+
+	device := "Unknown device"
+	switch {
+	case len(browser) > 0 && len(os) > 0:
+		device = fmt.Sprintf("%s on %s", browser, os)
+	case len(browser) > 0:
+		device = browser
+	case len(os) > 0:
+		device = os
+	}
+	if len(ip) > 0 {
+		device = fmt.Sprintf("%s (%s)", device, ip)
+	}
+	return device
+}
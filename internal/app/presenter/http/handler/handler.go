@@ -3,8 +3,11 @@ package handler
 import (
 	"auth-server/internal/app/model"
 	he "auth-server/pkg/errors/error"
+	"bytes"
 	"encoding/json"
 	"github.com/gorilla/mux"
+	"html/template"
+	"log"
 	"net/http"
 )
 
@@ -31,6 +34,21 @@ func (h Handler) error(w http.ResponseWriter, r *http.Request, err error) {
 	return
 }
 
-func (h Handler) respondHtml(w http.ResponseWriter, r *http.Request, data interface{}) {
-	panic("Implement me.")
+func (h Handler) respondHtml(w http.ResponseWriter, r *http.Request, code int, templatePath string, data interface{}) {
+	tmpl, err := template.ParseFiles(templatePath)
+	if err != nil {
+		log.Printf("Err in parse template %s. Err: %s", templatePath, err.Error())
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		log.Printf("Err in execute template %s. Err: %s", templatePath, err.Error())
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(code)
+	w.Write(buf.Bytes())
 }
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service/services"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+//authorize middleware verifies bearer access token of user and its session, then puts the claims to request context
+func (h Handler) authorize(serviceManager *services.Manager) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+				h.error(w, r, errors.ErrUnauthorized.New("Access token required."))
+				return
+			}
+			claims, err := bearerClaims(r, serviceManager)
+			if err != nil {
+				h.error(w, r, errors.ErrUnauthorized.New("Invalid access token."))
+				return
+			}
+			if claims.IsClientToken() {
+				h.error(w, r, errors.ErrUnauthorized.New("User access token required."))
+				return
+			}
+			err = serviceManager.User.CheckSession(r.Context(), claims.SessionID)
+			if err != nil {
+				if errors.GetType(err) == errors.ErrInvalidArgument {
+					err = errors.ErrUnauthorized.New("Session closed.")
+				}
+				h.error(w, r, err)
+				return
+			}
+			ctx := context.WithValue(r.Context(), config.ContextAccessClaimsKey, claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+//requireAdmin middleware rejects users who aren't admins of admin client, it must be used after authorize.
+//Roles of other clients are managed by their owners, so they never grant access to admin API.
+func (h Handler) requireAdmin(serviceManager *services.Manager, adminClientID string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := accessClaims(r)
+			if claims == nil {
+				h.error(w, r, errors.ErrUnauthorized.New("Access token required."))
+				return
+			}
+			if len(adminClientID) == 0 || claims.ClientID != adminClientID {
+				h.error(w, r, errors.ErrForbidden.New("Access denied."))
+				return
+			}
+			//role is checked in store, so revoked role stops working before token expiration
+			admin, err := serviceManager.User.HasRole(r.Context(), claims.UserID, adminClientID, model.RoleAdmin)
+			if err != nil {
+				h.error(w, r, err)
+				return
+			}
+			if !admin {
+				h.error(w, r, errors.ErrForbidden.New("Access denied."))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//bearerClaims parses bearer access token of request, token parsed by RateLimit middleware isn't parsed again
+func bearerClaims(r *http.Request, serviceManager *services.Manager) (*model.AccessClaims, error) {
+	if claims, ok := r.Context().Value(config.ContextTokenClaimsKey).(*model.AccessClaims); ok {
+		return claims, nil
+	}
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errors.ErrUnauthorized.New("Access token required.")
+	}
+	return serviceManager.Token.ParseAccessToken(r.Context(), strings.TrimPrefix(header, "Bearer "))
+}
+
+//accessClaims returns the claims put by authorize middleware
+func accessClaims(r *http.Request) *model.AccessClaims {
+	claims, _ := r.Context().Value(config.ContextAccessClaimsKey).(*model.AccessClaims)
+	return claims
+}
+
+//RequestMeta middleware puts client IP and geo label to request context.
+//Proxy headers are trusted only if trustProxy is set, trustedProxies are proxies in front of the proxy which connects to server.
+func RequestMeta(trustProxy bool, trustedProxies []*net.IPNet, geoHeader string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), config.ContextIPKey, clientIP(r, trustProxy, trustedProxies))
+			if trustProxy && len(geoHeader) > 0 {
+				ctx = context.WithValue(ctx, config.ContextLocationKey, r.Header.Get(geoHeader))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+//ParseNetworks parses comma separated CIDR networks or addresses
+func ParseNetworks(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid network %s.", entry)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+//clientIP returns address of peer, behind proxy it is the rightmost address of X-Forwarded-For which isn't
+//a trusted proxy. Proxies append addresses to the right, the addresses on the left may be forged by client.
+func clientIP(r *http.Request, trustProxy bool, trustedProxies []*net.IPNet) string {
+	if trustProxy {
+		if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+			hops := strings.Split(strings.Join(forwarded, ","), ",")
+			for i := len(hops) - 1; i > 0; i-- {
+				if !isTrustedProxy(strings.TrimSpace(hops[i]), trustedProxies) {
+					return strings.TrimSpace(hops[i])
+				}
+			}
+			return strings.TrimSpace(hops[0])
+		}
+		if realIP := r.Header.Get("X-Real-IP"); len(realIP) > 0 {
+			return realIP
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func isTrustedProxy(hop string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(hop)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//ClientContext middleware resolves the client from "X-Client-ID" header or basic auth,
+//rejects unknown clients and puts client ID and device descriptor to request context.
+//It must be used after RequestMeta.
+func ClientContext(serviceManager *services.Manager) mux.MiddlewareFunc {
+	h := Handler{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, _ := r.Context().Value(config.ContextIPKey).(string)
+			ctx := context.WithValue(r.Context(), config.ContextDeviceKey, deviceDescriptor(r.UserAgent(), ip))
+
+			clientID := r.Header.Get(ClientIDHeader)
+			if basicClientID, _, ok := r.BasicAuth(); ok && len(clientID) == 0 {
+				clientID = basicClientID
+			}
+			if len(clientID) > 0 {
+				client, err := serviceManager.Client.FindClientByID(ctx, clientID)
+				if err != nil {
+					if errors.GetType(err) == errors.ErrInvalidArgument {
+						err = errors.ErrUnauthorized.New("Unknown client.")
+					}
+					h.error(w, r, err)
+					return
+				}
+				ctx = context.WithValue(ctx, config.ContextClientIDKey, client.ID)
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+//contextClientID returns the client ID put by ClientContext middleware
+func contextClientID(r *http.Request) string {
+	clientID, _ := r.Context().Value(config.ContextClientIDKey).(string)
+	return clientID
+}
+
+//RateLimit middleware limits requests to matched route by token buckets of IP, client and user,
+//limits are reported in "RateLimit-*" headers. It must be used after ClientContext.
+//User is identified only by valid access token, limiter doesn't fail requests if its store fails.
+func RateLimit(serviceManager *services.Manager) mux.MiddlewareFunc {
+	h := Handler{}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			route := mux.CurrentRoute(r)
+			if route == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			template, err := route.GetPathTemplate()
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			//claims are kept in context, so authorize middleware doesn't parse token again
+			claims, _ := bearerClaims(r, serviceManager)
+			if claims != nil {
+				r = r.WithContext(context.WithValue(r.Context(), config.ContextTokenClaimsKey, claims))
+			}
+			result, err := serviceManager.RateLimit.Allow(r.Context(), template, rateLimitKeys(r, claims))
+			if err != nil || result == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+			w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d;burst=%d", result.Quota, ceilSeconds(result.Period), result.Limit))
+			if !result.Allowed {
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+				h.error(w, r, errors.ErrTooManyRequests.New("Rate limit exceeded."))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+//rateLimitKeys returns IP, client and user of request, client and user may be taken from claims of access token
+func rateLimitKeys(r *http.Request, claims *model.AccessClaims) map[string]string {
+	keys := map[string]string{}
+	keys[model.RateLimitByIP], _ = r.Context().Value(config.ContextIPKey).(string)
+	keys[model.RateLimitByClient], _ = r.Context().Value(config.ContextClientIDKey).(string)
+	if claims == nil {
+		return keys
+	}
+	if len(keys[model.RateLimitByClient]) == 0 {
+		keys[model.RateLimitByClient] = claims.ClientID
+	}
+	if !claims.IsClientToken() {
+		keys[model.RateLimitByUser] = claims.UserID
+	}
+	return keys
+}
+
+//ceilSeconds rounds duration up to whole seconds as rate limit headers require
+func ceilSeconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
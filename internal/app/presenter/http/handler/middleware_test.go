@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	trusted, err := ParseNetworks("10.0.0.0/8, 192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		name       string
+		trustProxy bool
+		forwarded  []string
+		want       string
+	}{
+		{"proxy isn't trusted", false, []string{"203.0.113.7"}, "198.51.100.1"},
+		{"no header", true, nil, "198.51.100.1"},
+		{"single hop", true, []string{"203.0.113.7"}, "203.0.113.7"},
+		{"forged hops on the left", true, []string{"1.1.1.1, 2.2.2.2, 203.0.113.7"}, "203.0.113.7"},
+		{"trusted proxies skipped", true, []string{"1.1.1.1, 203.0.113.7, 10.1.2.3, 192.0.2.1"}, "203.0.113.7"},
+		{"several headers", true, []string{"1.1.1.1", "203.0.113.7, 10.1.2.3"}, "203.0.113.7"},
+		{"invalid hop isn't trusted", true, []string{"203.0.113.7, unknown, 10.1.2.3"}, "unknown"},
+		{"all hops trusted", true, []string{"10.0.0.1, 10.0.0.2"}, "10.0.0.1"},
+	}
+	for _, c := range cases {
+		r := &http.Request{RemoteAddr: "198.51.100.1:4321", Header: http.Header{}}
+		for _, value := range c.forwarded {
+			r.Header.Add("X-Forwarded-For", value)
+		}
+		if got := clientIP(r, c.trustProxy, trusted); got != c.want {
+			t.Errorf("%s: got %s, want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestParseNetworks(t *testing.T) {
+	networks, err := ParseNetworks("10.0.0.0/8,,192.0.2.1, 2001:db8::/32")
+	if err != nil || len(networks) != 3 {
+		t.Fatalf("got %v, err %v", networks, err)
+	}
+	for _, value := range []string{"10.0.0.0/33", "proxy", "10.0.0"} {
+		if _, err = ParseNetworks(value); err == nil {
+			t.Errorf("network %q parsed", value)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package handler
+
+import (
+	"auth-server/config/filePath"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service/services"
+	"auth-server/internal/app/service/services/oauth_service"
+	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/webauthn"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gorilla/mux"
+)
+
+type OAuthHandler struct {
+	Handler
+	serviceManager   *services.Manager
+	registrationLink string
+}
+
+//devicePage is a data of "device_page.html" template
+type devicePage struct {
+	Title            string
+	Action           string
+	UserCode         string
+	Login            string
+	MFAToken         string
+	WebAuthnOptions  string
+	Error            string
+	Message          string
+	RegistrationLink string
+	CSRFToken        string
+}
+
+//consentPage is a data of "consent_page.html" template
+type consentPage struct {
+	Title     string
+	Action    string
+	LogoURI   string
+	Scopes    []model.Scope
+	Code      string
+	State     string
+	CSRFToken string
+}
+
+//authPage is a data of "auth_page.html" template
+type authPage struct {
+	Title            string
+	Action           string
+	Login            string
+	MFAToken         string
+	WebAuthnOptions  string
+	Error            string
+	RegistrationLink string
+	Params           map[string]string
+	CSRFToken        string
+}
+
+func NewOAuthHandler(manager *services.Manager, registrationLink string) *OAuthHandler {
+	return &OAuthHandler{
+		Handler:          Handler{},
+		serviceManager:   manager,
+		registrationLink: registrationLink,
+	}
+}
+
+//ConfigureRoutes ...
+func (o *OAuthHandler) ConfigureRoutes(router *mux.Router) {
+	oauth := router.PathPrefix("/oauth").Subrouter()
+	oauth.HandleFunc("/authorize", o.authorizePage()).Methods(http.MethodGet)
+	oauth.HandleFunc("/authorize", o.authorize()).Methods(http.MethodPost)
+	oauth.HandleFunc("/token", o.token()).Methods(http.MethodPost)
+	oauth.HandleFunc("/introspect", o.introspect()).Methods(http.MethodPost)
+	oauth.HandleFunc("/revoke", o.revoke()).Methods(http.MethodPost)
+	oauth.HandleFunc("/consent", o.consent()).Methods(http.MethodPost)
+	oauth.HandleFunc("/device_authorization", o.deviceAuthorization()).Methods(http.MethodPost)
+	oauth.HandleFunc("/device", o.devicePage()).Methods(http.MethodGet)
+	oauth.HandleFunc("/device", o.verifyDevice()).Methods(http.MethodPost)
+	oauth.PathPrefix("/static/").Handler(
+		http.StripPrefix("/oauth/static/", http.FileServer(http.Dir(filePath.AuthStaticDir))),
+	).Methods(http.MethodGet)
+}
+
+func parseAuthorizeRequest(r *http.Request) *model.AuthorizeRequest {
+	return &model.AuthorizeRequest{
+		ResponseType:        r.FormValue("response_type"),
+		ClientID:            r.FormValue("client_id"),
+		RedirectURI:         r.FormValue("redirect_uri"),
+		Scope:               r.FormValue("scope"),
+		State:               r.FormValue("state"),
+		Nonce:               r.FormValue("nonce"),
+		CodeChallenge:       r.FormValue("code_challenge"),
+		CodeChallengeMethod: r.FormValue("code_challenge_method"),
+	}
+}
+
+//authorizeParams returns the authorization request parameters which login form sends back
+func authorizeParams(req *model.AuthorizeRequest) map[string]string {
+	return map[string]string{
+		"response_type":         req.ResponseType,
+		"client_id":             req.ClientID,
+		"redirect_uri":          req.RedirectURI,
+		"scope":                 req.Scope,
+		"state":                 req.State,
+		"nonce":                 req.Nonce,
+		"code_challenge":        req.CodeChallenge,
+		"code_challenge_method": req.CodeChallengeMethod,
+	}
+}
+
+func (o *OAuthHandler) authorizePage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: authorizePage, handler: oauth.")
+		req := parseAuthorizeRequest(r)
+		client, ok := o.validateAuthorizeRequest(w, r, req)
+		if !ok {
+			return
+		}
+		page := authPage{
+			Title:            client.ClientName,
+			Action:           "authorize",
+			RegistrationLink: o.registrationLink,
+			Params:           authorizeParams(req),
+			CSRFToken:        csrfToken(w, r),
+		}
+		o.respondHtml(w, r, http.StatusOK, filePath.AuthPageTemplate, page)
+	}
+}
+
+func (o *OAuthHandler) authorize() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: authorize, handler: oauth.")
+		if !checkCSRF(r) {
+			http.Error(w, "Invalid CSRF token.", http.StatusForbidden)
+			return
+		}
+		req := parseAuthorizeRequest(r)
+		client, ok := o.validateAuthorizeRequest(w, r, req)
+		if !ok {
+			return
+		}
+		user, mfaToken, failure, err := o.signIn(r, client.ID)
+		if err != nil {
+			log.Printf("Err in authorize. Err: %s", err.Error())
+			o.redirectError(w, r, req, model.NewOAuthError(model.OAuthErrServerError, ""))
+			return
+		}
+		if user == nil {
+			page := authPage{
+				Title:            client.ClientName,
+				Action:           "authorize",
+				Login:            r.PostFormValue("login"),
+				MFAToken:         mfaToken,
+				WebAuthnOptions:  o.webauthnOptions(r, mfaToken, client.ID),
+				Error:            failure,
+				RegistrationLink: o.registrationLink,
+				Params:           authorizeParams(req),
+				CSRFToken:        csrfToken(w, r),
+			}
+			o.respondHtml(w, r, signInStatus(failure), filePath.AuthPageTemplate, page)
+			return
+		}
+		code, consentRequired, err := o.serviceManager.OAuth.CreateAuthCode(r.Context(), client, req, user.ID)
+		if err != nil {
+			log.Printf("Err in create authorization code. Err: %s", err.Error())
+			o.redirectError(w, r, req, model.NewOAuthError(model.OAuthErrServerError, ""))
+			return
+		}
+		if !consentRequired {
+			o.redirect(w, r, req.RedirectURI, map[string]string{
+				"code":  code,
+				"state": req.State,
+			})
+			return
+		}
+		scopes, err := o.serviceManager.Scope.DescribeScopes(r.Context(), req.Scope)
+		if err != nil {
+			log.Printf("Err in describe scopes. Err: %s", err.Error())
+			o.redirectError(w, r, req, model.NewOAuthError(model.OAuthErrServerError, ""))
+			return
+		}
+		page := consentPage{
+			Title:     client.ClientName,
+			Action:    "consent",
+			LogoURI:   client.LogoURI,
+			Scopes:    scopes,
+			Code:      code,
+			State:     req.State,
+			CSRFToken: csrfToken(w, r),
+		}
+		o.respondHtml(w, r, http.StatusOK, filePath.ConsentPageTemplate, page)
+	}
+}
+
+//consent handles answer of user on consent page of third-party client
+func (o *OAuthHandler) consent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: consent, handler: oauth.")
+		if !checkCSRF(r) {
+			http.Error(w, "Invalid CSRF token.", http.StatusForbidden)
+			return
+		}
+		approve := r.PostFormValue("action") == "approve"
+		state := r.PostFormValue("state")
+		code, err := o.serviceManager.OAuth.DecideConsent(r.Context(), r.PostFormValue("code"), approve)
+		if err != nil {
+			if errors.GetType(err) == errors.ErrInvalidArgument {
+				http.Error(w, "Invalid or expired authorization request.", http.StatusBadRequest)
+				return
+			}
+			log.Printf("Err in consent. Err: %s", err.Error())
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		if !approve {
+			o.redirect(w, r, code.RedirectURI, map[string]string{
+				"error":             model.OAuthErrAccessDenied,
+				"error_description": "User denied access.",
+				"state":             state,
+			})
+			return
+		}
+		o.redirect(w, r, code.RedirectURI, map[string]string{
+			"code":  code.Code,
+			"state": state,
+		})
+	}
+}
+
+func (o *OAuthHandler) token() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: token, handler: oauth.")
+		if err := r.ParseForm(); err != nil {
+			o.oauthError(w, r, model.NewOAuthError(model.OAuthErrInvalidRequest, "Invalid form."))
+			return
+		}
+		creds := clientCredentials(r)
+		if len(creds.ClientID) == 0 {
+			creds.ClientID = contextClientID(r)
+		}
+		req := &model.TokenRequest{
+			GrantType:    r.PostFormValue("grant_type"),
+			Credentials:  *creds,
+			Code:         r.PostFormValue("code"),
+			DeviceCode:   r.PostFormValue("device_code"),
+			RedirectURI:  r.PostFormValue("redirect_uri"),
+			CodeVerifier: r.PostFormValue("code_verifier"),
+			RefToken:     r.PostFormValue("refresh_token"),
+			Scope:        r.PostFormValue("scope"),
+		}
+		responce, err := o.serviceManager.OAuth.Token(r.Context(), req)
+		if err != nil {
+			o.oauthError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("Pragma", "no-cache")
+		o.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (o *OAuthHandler) deviceAuthorization() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deviceAuthorization, handler: oauth.")
+		if err := r.ParseForm(); err != nil {
+			o.oauthError(w, r, model.NewOAuthError(model.OAuthErrInvalidRequest, "Invalid form."))
+			return
+		}
+		creds := clientCredentials(r)
+		if len(creds.ClientID) == 0 {
+			creds.ClientID = contextClientID(r)
+		}
+		responce, err := o.serviceManager.OAuth.AuthorizeDevice(r.Context(), creds, r.PostFormValue("scope"))
+		if err != nil {
+			o.oauthError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		o.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (o *OAuthHandler) devicePage() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: devicePage, handler: oauth.")
+		page := devicePage{
+			Title:            "device",
+			Action:           "device",
+			UserCode:         r.FormValue("user_code"),
+			RegistrationLink: o.registrationLink,
+			CSRFToken:        csrfToken(w, r),
+		}
+		if len(page.UserCode) > 0 {
+			_, client, err := o.serviceManager.OAuth.FindDeviceAuthorization(r.Context(), page.UserCode)
+			switch {
+			case err == nil:
+				page.Title = client.ClientName
+			case errors.GetType(err) == errors.ErrInvalidArgument:
+				page.Error = "Invalid or expired code."
+			default:
+				log.Printf("Err in find device authorization. Err: %s", err.Error())
+			}
+		}
+		o.respondHtml(w, r, http.StatusOK, filePath.DevicePageTemplate, page)
+	}
+}
+
+func (o *OAuthHandler) verifyDevice() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: verifyDevice, handler: oauth.")
+		if !checkCSRF(r) {
+			http.Error(w, "Invalid CSRF token.", http.StatusForbidden)
+			return
+		}
+		page := devicePage{
+			Title:            "device",
+			Action:           "device",
+			UserCode:         oauth_service.FormatUserCode(oauth_service.NormalizeUserCode(r.PostFormValue("user_code"))),
+			Login:            r.PostFormValue("login"),
+			RegistrationLink: o.registrationLink,
+			CSRFToken:        csrfToken(w, r),
+		}
+		_, client, err := o.serviceManager.OAuth.FindDeviceAuthorization(r.Context(), page.UserCode)
+		if err != nil {
+			o.deviceError(w, r, page, err)
+			return
+		}
+		page.Title = client.ClientName
+		user, mfaToken, failure, err := o.signIn(r, client.ID)
+		if err != nil {
+			log.Printf("Err in verify device. Err: %s", err.Error())
+			http.Error(w, "Internal server error.", http.StatusInternalServerError)
+			return
+		}
+		if user == nil {
+			page.MFAToken, page.Error = mfaToken, failure
+			page.WebAuthnOptions = o.webauthnOptions(r, mfaToken, client.ID)
+			o.respondHtml(w, r, signInStatus(failure), filePath.DevicePageTemplate, page)
+			return
+		}
+		approve := r.PostFormValue("action") != "deny"
+		err = o.serviceManager.OAuth.DecideDeviceAuthorization(r.Context(), page.UserCode, user.ID, approve)
+		if err != nil {
+			o.deviceError(w, r, page, err)
+			return
+		}
+		page.Message = "Device is authorized, you may return to it."
+		if !approve {
+			page.Message = "Device authorization is denied."
+		}
+		o.respondHtml(w, r, http.StatusOK, filePath.DevicePageTemplate, page)
+	}
+}
+
+//signIn checks login and password or second factor code sent by login form.
+//If user isn't signed in yet, it returns MFA token of the next step or the failure to show on the form.
+func (o *OAuthHandler) signIn(r *http.Request, clientID string) (*model.User, string, string, error) {
+	if mfaToken := r.PostFormValue("mfa_token"); len(mfaToken) > 0 {
+		answer := &model.MFAAnswer{Code: r.PostFormValue("code")}
+		if assertion := r.PostFormValue("webauthn"); len(assertion) > 0 {
+			answer.WebAuthn = &webauthn.AssertionResponse{}
+			if err := json.Unmarshal([]byte(assertion), answer.WebAuthn); err != nil {
+				return nil, mfaToken, "Invalid passkey.", nil
+			}
+		}
+		user, err := o.serviceManager.User.CheckMFA(r.Context(), mfaToken, clientID, answer)
+		if err == nil {
+			return user, "", "", nil
+		}
+		switch errors.GetType(err) {
+		case errors.ErrUnauthorized:
+			return nil, mfaToken, "Invalid code.", nil
+		case errors.ErrInvalidArgument:
+			return nil, "", "Code is expired, sign in again.", nil
+		}
+		return nil, "", "", err
+	}
+	user, err := o.serviceManager.User.CheckCredentials(r.Context(), r.PostFormValue("login"), r.PostFormValue("password"))
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidPasswordOrUsername {
+			return nil, "", "Invalid login or password.", nil
+		}
+		return nil, "", "", err
+	}
+	challenge, err := o.serviceManager.User.StartMFA(r.Context(), user.ID, clientID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if challenge != nil {
+		return nil, challenge.Token, "", nil
+	}
+	return user, "", "", nil
+}
+
+//webauthnOptions returns JSON options of passkey assertion if user can answer MFA challenge by passkey
+func (o *OAuthHandler) webauthnOptions(r *http.Request, mfaToken, clientID string) string {
+	if len(mfaToken) == 0 {
+		return ""
+	}
+	options, err := o.serviceManager.User.BeginWebAuthnMFA(r.Context(), mfaToken, clientID)
+	if err != nil {
+		if errors.GetType(err) != errors.ErrInvalidArgument {
+			log.Printf("Err in begin WebAuthn MFA. Err: %s", err.Error())
+		}
+		return ""
+	}
+	data, err := json.Marshal(options)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+//signInStatus returns the status of login form which isn't completed
+func signInStatus(failure string) int {
+	if len(failure) > 0 {
+		return http.StatusUnauthorized
+	}
+	return http.StatusOK
+}
+
+//deviceError renders device page with error of invalid user code
+func (o *OAuthHandler) deviceError(w http.ResponseWriter, r *http.Request, page devicePage, err error) {
+	if errors.GetType(err) != errors.ErrInvalidArgument {
+		log.Printf("Err in verify device. Err: %s", err.Error())
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return
+	}
+	page.Error = "Invalid or expired code."
+	o.respondHtml(w, r, http.StatusBadRequest, filePath.DevicePageTemplate, page)
+}
+
+func (o *OAuthHandler) introspect() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: introspect, handler: oauth.")
+		client, ok := o.authenticateClient(w, r)
+		if !ok {
+			return
+		}
+		responce, err := o.serviceManager.OAuth.Introspect(r.Context(), client.ID,
+			r.PostFormValue("token"), r.PostFormValue("token_type_hint"))
+		if err != nil {
+			o.oauthError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		o.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (o *OAuthHandler) revoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: revoke, handler: oauth.")
+		client, ok := o.authenticateClient(w, r)
+		if !ok {
+			return
+		}
+		err := o.serviceManager.OAuth.Revoke(r.Context(), client.ID,
+			r.PostFormValue("token"), r.PostFormValue("token_type_hint"))
+		if err != nil {
+			o.oauthError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+//clientCredentials returns credentials from basic auth or request body, RFC 6749 section 2.3.1 and RFC 7523
+func clientCredentials(r *http.Request) *model.ClientCredentials {
+	creds := &model.ClientCredentials{
+		ClientID:      r.PostFormValue("client_id"),
+		Secret:        r.PostFormValue("client_secret"),
+		AssertionType: r.PostFormValue("client_assertion_type"),
+		Assertion:     r.PostFormValue("client_assertion"),
+	}
+	if id, secret, ok := r.BasicAuth(); ok {
+		//basic auth credentials are form-urlencoded
+		creds.ClientID, creds.Secret = id, secret
+		if unescaped, err := url.QueryUnescape(id); err == nil {
+			creds.ClientID = unescaped
+		}
+		if unescaped, err := url.QueryUnescape(secret); err == nil {
+			creds.Secret = unescaped
+		}
+	}
+	return creds
+}
+
+//authenticateClient writes invalid_client error if confidential client credentials are wrong
+func (o *OAuthHandler) authenticateClient(w http.ResponseWriter, r *http.Request) (*model.Client, bool) {
+	if err := r.ParseForm(); err != nil {
+		o.oauthError(w, r, model.NewOAuthError(model.OAuthErrInvalidRequest, "Invalid form."))
+		return nil, false
+	}
+	client, err := o.serviceManager.Client.AuthenticateClient(r.Context(), clientCredentials(r))
+	if err != nil {
+		if errors.GetType(err) == errors.ErrUnauthorized {
+			err = model.NewOAuthError(model.OAuthErrInvalidClient, "Client authentication failed.")
+		}
+		o.oauthError(w, r, err)
+		return nil, false
+	}
+	return client, true
+}
+
+//validateAuthorizeRequest writes error responce if request is invalid.
+//Errors are sent to redirect URI only if it is registered for client.
+func (o *OAuthHandler) validateAuthorizeRequest(w http.ResponseWriter, r *http.Request, req *model.AuthorizeRequest) (*model.Client, bool) {
+	client, err := o.serviceManager.OAuth.ValidateAuthorizeRequest(r.Context(), req)
+	if err == nil {
+		return client, true
+	}
+	oauthErr, ok := err.(*model.OAuthError)
+	if !ok {
+		log.Printf("Err in validate authorization request. Err: %s", err.Error())
+		http.Error(w, "Internal server error.", http.StatusInternalServerError)
+		return nil, false
+	}
+	if client == nil {
+		http.Error(w, oauthErr.Description, http.StatusBadRequest)
+		return nil, false
+	}
+	o.redirectError(w, r, req, oauthErr)
+	return nil, false
+}
+
+func (o *OAuthHandler) redirectError(w http.ResponseWriter, r *http.Request, req *model.AuthorizeRequest, err *model.OAuthError) {
+	o.redirect(w, r, req.RedirectURI, map[string]string{
+		"error":             err.Code,
+		"error_description": err.Description,
+		"state":             req.State,
+	})
+}
+
+func (o *OAuthHandler) redirect(w http.ResponseWriter, r *http.Request, redirectURI string, params map[string]string) {
+	location, err := url.Parse(redirectURI)
+	if err != nil {
+		http.Error(w, "Invalid redirect_uri.", http.StatusBadRequest)
+		return
+	}
+	query := location.Query()
+	for k, v := range params {
+		if len(v) > 0 {
+			query.Set(k, v)
+		}
+	}
+	location.RawQuery = query.Encode()
+	http.Redirect(w, r, location.String(), http.StatusFound)
+}
+
+//oauthError writes error responce in RFC 6749 format
+func (o *OAuthHandler) oauthError(w http.ResponseWriter, r *http.Request, err error) {
+	oauthErr, ok := err.(*model.OAuthError)
+	if !ok {
+		log.Printf("Err in oauth handler. Err: %s", err.Error())
+		o.respondJson(w, r, http.StatusInternalServerError, model.NewOAuthError(model.OAuthErrServerError, ""))
+		return
+	}
+	code := http.StatusBadRequest
+	if oauthErr.Code == model.OAuthErrInvalidClient {
+		code = http.StatusUnauthorized
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+	}
+	o.respondJson(w, r, code, oauthErr)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service/services"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+type OIDCHandler struct {
+	Handler
+	serviceManager *services.Manager
+	issuer         string
+	baseURL        string
+	signingAlg     string
+}
+
+func NewOIDCHandler(manager *services.Manager, issuer, baseURL, signingAlg string) *OIDCHandler {
+	return &OIDCHandler{
+		Handler:        Handler{},
+		serviceManager: manager,
+		issuer:         issuer,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+		signingAlg:     signingAlg,
+	}
+}
+
+//ConfigureRoutes ...
+func (o *OIDCHandler) ConfigureRoutes(router *mux.Router) {
+	router.HandleFunc("/.well-known/openid-configuration", o.discovery()).Methods(http.MethodGet)
+	router.HandleFunc("/jwks.json", o.jwks()).Methods(http.MethodGet)
+	router.Handle("/userinfo", o.authorize(o.serviceManager)(o.userInfo())).Methods(http.MethodGet, http.MethodPost)
+}
+
+func (o *OIDCHandler) discovery() http.HandlerFunc {
+	configuration := &model.OpenIDConfiguration{
+		Issuer:                      o.issuer,
+		AuthorizationEndpoint:       o.baseURL + "/oauth/authorize",
+		TokenEndpoint:               o.baseURL + "/oauth/token",
+		IntrospectionEndpoint:       o.baseURL + "/oauth/introspect",
+		RevocationEndpoint:          o.baseURL + "/oauth/revoke",
+		DeviceAuthorizationEndpoint: o.baseURL + "/oauth/device_authorization",
+		RegistrationEndpoint:        o.baseURL + "/oauth/register",
+		UserInfoEndpoint:            o.baseURL + "/userinfo",
+		JwksURI:                     o.baseURL + "/jwks.json",
+		ScopesSupported:             []string{model.ScopeOpenID, model.ScopeProfile, model.ScopeEmail},
+		ResponseTypesSupported:      []string{"code"},
+		GrantTypesSupported: []string{model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeClientCredentials,
+			model.GrantTypeDeviceCode,
+		},
+		SubjectTypesSupported:            []string{"public"},
+		IDTokenSigningAlgValuesSupported: []string{o.signingAlg},
+		TokenEndpointAuthMethodsSupported: []string{
+			model.ClientAuthNone, model.ClientAuthSecretBasic, model.ClientAuthSecretPost, model.ClientAuthPrivateKeyJWT,
+		},
+		CodeChallengeMethodsSupported: []string{model.CodeChallengeMethodS256},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce",
+			"preferred_username", "name", "given_name", "middle_name", "family_name",
+			"email", "email_verified",
+		},
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: discovery, handler: oidc.")
+		o.respondJson(w, r, http.StatusOK, configuration)
+	}
+}
+
+func (o *OIDCHandler) jwks() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: jwks, handler: oidc.")
+		keys, err := o.serviceManager.Token.PublicKeys(r.Context())
+		if err != nil {
+			o.error(w, r, err)
+			return
+		}
+		o.respondJson(w, r, http.StatusOK, keys)
+	}
+}
+
+func (o *OIDCHandler) userInfo() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: userInfo, handler: oidc.")
+		info, err := o.serviceManager.OAuth.UserInfo(r.Context(), accessClaims(r))
+		if err != nil {
+			o.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		o.respondJson(w, r, http.StatusOK, info)
+	}
+}
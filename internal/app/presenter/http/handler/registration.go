@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service/services"
+	errors "auth-server/pkg/errors/types"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+//RegistrationHandler serves dynamic client registration, RFC 7591 and RFC 7592
+type RegistrationHandler struct {
+	Handler
+	serviceManager *services.Manager
+	baseURL        string
+}
+
+//updateRegistrationRequest is a body of client update request, RFC 7592 section 2.2
+type updateRegistrationRequest struct {
+	model.ClientMetadata
+	ClientID string `json:"client_id"`
+}
+
+func NewRegistrationHandler(manager *services.Manager, baseURL string) *RegistrationHandler {
+	return &RegistrationHandler{
+		Handler:        Handler{},
+		serviceManager: manager,
+		baseURL:        strings.TrimRight(baseURL, "/"),
+	}
+}
+
+//ConfigureRoutes ...
+func (h *RegistrationHandler) ConfigureRoutes(router *mux.Router) {
+	register := router.PathPrefix("/oauth/register").Subrouter()
+	register.HandleFunc("", h.register()).Methods(http.MethodPost)
+	register.HandleFunc("/{id}", h.getClient()).Methods(http.MethodGet)
+	register.HandleFunc("/{id}", h.updateClient()).Methods(http.MethodPut)
+	register.HandleFunc("/{id}", h.deleteClient()).Methods(http.MethodDelete)
+}
+
+//bearerToken returns the token of "Authorization: Bearer" header
+func bearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if !strings.HasPrefix(header, "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(header, "Bearer ")
+}
+
+//clientInformation makes responce with client configuration endpoint
+func (h *RegistrationHandler) clientInformation(client *model.Client, regToken string) *model.ClientInformation {
+	info := model.NewClientInformation(client)
+	info.RegistrationAccessToken = regToken
+	info.RegistrationClientURI = h.baseURL + "/oauth/register/" + client.ID
+	return info
+}
+
+func (h *RegistrationHandler) register() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: register, handler: registration.")
+		if err := h.serviceManager.Client.CheckInitialAccessToken(bearerToken(r)); err != nil {
+			h.registrationError(w, r, err)
+			return
+		}
+		metadata := &model.ClientMetadata{}
+		if err := json.NewDecoder(r.Body).Decode(metadata); err != nil {
+			h.registrationError(w, r, model.NewOAuthError(model.OAuthErrInvalidClientMetadata, "Invalid client metadata."))
+			return
+		}
+		client, regToken, err := h.serviceManager.Client.RegisterClient(r.Context(), metadata)
+		if err != nil {
+			h.registrationError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		h.respondJson(w, r, http.StatusCreated, h.clientInformation(client, regToken))
+	}
+}
+
+func (h *RegistrationHandler) getClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getClient, handler: registration.")
+		client, err := h.serviceManager.Client.FindRegisteredClient(r.Context(), mux.Vars(r)["id"], bearerToken(r))
+		if err != nil {
+			h.registrationError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		h.respondJson(w, r, http.StatusOK, h.clientInformation(client, ""))
+	}
+}
+
+func (h *RegistrationHandler) updateClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: updateClient, handler: registration.")
+		clientID := mux.Vars(r)["id"]
+		req := &updateRegistrationRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			h.registrationError(w, r, model.NewOAuthError(model.OAuthErrInvalidClientMetadata, "Invalid client metadata."))
+			return
+		}
+		if req.ClientID != clientID {
+			h.registrationError(w, r, model.NewOAuthError(model.OAuthErrInvalidRequest, "Client ID doesn't match."))
+			return
+		}
+		client, err := h.serviceManager.Client.UpdateRegisteredClient(r.Context(), clientID, bearerToken(r), &req.ClientMetadata)
+		if err != nil {
+			h.registrationError(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		h.respondJson(w, r, http.StatusOK, h.clientInformation(client, ""))
+	}
+}
+
+func (h *RegistrationHandler) deleteClient() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteClient, handler: registration.")
+		err := h.serviceManager.Client.DeleteRegisteredClient(r.Context(), mux.Vars(r)["id"], bearerToken(r))
+		if err != nil {
+			h.registrationError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+//registrationError writes registration error or bearer token error, RFC 7591 section 3.2.2 and RFC 6750 section 3
+func (h *RegistrationHandler) registrationError(w http.ResponseWriter, r *http.Request, err error) {
+	if oauthErr, ok := err.(*model.OAuthError); ok {
+		h.respondJson(w, r, http.StatusBadRequest, oauthErr)
+		return
+	}
+	if errors.GetType(err) == errors.ErrUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	log.Printf("Err in registration handler. Err: %s", err.Error())
+	h.respondJson(w, r, http.StatusInternalServerError, model.NewOAuthError(model.OAuthErrServerError, ""))
+}
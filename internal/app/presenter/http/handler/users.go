@@ -7,7 +7,9 @@ import (
 	"auth-server/internal/app/store"
 	"auth-server/pkg/emailsender"
 	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/webauthn"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -20,17 +22,28 @@ import (
 	"github.com/spf13/viper"
 )
 
+//maxPendingResets limits password resets which are processed in background at once
+const maxPendingResets = 32
+
+//passwordResetTimeout limits creation and sending of one password reset token
+const passwordResetTimeout = 30 * time.Second
+
 type UserHandler struct {
 	Handler
 	serviceManager *services.Manager
 	emailSender    emailsender.IEmailSender
+	resetLink      string
+	//resetSlots is a semaphore of background password resets
+	resetSlots chan struct{}
 }
 
-func NewUserHandler(manager *services.Manager, sender emailsender.IEmailSender) *UserHandler {
+func NewUserHandler(manager *services.Manager, sender emailsender.IEmailSender, resetLink string) *UserHandler {
 	return &UserHandler{
 		Handler:        Handler{},
 		serviceManager: manager,
 		emailSender:    sender,
+		resetLink:      resetLink,
+		resetSlots:     make(chan struct{}, maxPendingResets),
 	}
 }
 
@@ -83,7 +96,24 @@ func (u UserHandler) ConfigureRoutes(router *mux.Router) {
 	//register
 	users.HandleFunc("/register", u.register()).Methods(http.MethodPost)
 	users.HandleFunc("/email/confirm/{token}", u.confirmEmail()).Methods(http.MethodGet)
-
+	users.HandleFunc("/password/forgot", u.forgotPassword()).Methods(http.MethodPost)
+	users.HandleFunc("/password/reset", u.resetPassword()).Methods(http.MethodPost)
+	//current user
+	me := users.PathPrefix("/me").Subrouter()
+	me.Use(u.authorize(u.serviceManager))
+	me.HandleFunc("/sessions", u.getSessions()).Methods(http.MethodGet)
+	me.HandleFunc("/sessions", u.signOutEverywhere()).Methods(http.MethodDelete)
+	me.HandleFunc("/sessions/{id}", u.revokeSession()).Methods(http.MethodDelete)
+	me.HandleFunc("/mfa/totp", u.enrollTOTP()).Methods(http.MethodPost)
+	me.HandleFunc("/mfa/totp/confirm", u.confirmTOTP()).Methods(http.MethodPost)
+	me.HandleFunc("/mfa/totp", u.disableTOTP()).Methods(http.MethodDelete)
+	me.HandleFunc("/mfa/recovery_codes", u.regenerateRecoveryCodes()).Methods(http.MethodPost)
+	me.HandleFunc("/webauthn/register/options", u.webauthnRegistrationOptions()).Methods(http.MethodPost)
+	me.HandleFunc("/webauthn/register", u.registerWebAuthn()).Methods(http.MethodPost)
+	me.HandleFunc("/webauthn/credentials", u.getWebAuthnCredentials()).Methods(http.MethodGet)
+	me.HandleFunc("/webauthn/credentials/{id}", u.deleteWebAuthnCredential()).Methods(http.MethodDelete)
+	me.HandleFunc("/consents", u.getConsents()).Methods(http.MethodGet)
+	me.HandleFunc("/consents/{client_id}", u.revokeConsent()).Methods(http.MethodDelete)
 }
 
 func (u UserHandler) getUserByID() http.HandlerFunc {
@@ -203,3 +233,320 @@ func (u UserHandler) confirmEmail() http.HandlerFunc {
 		return
 	}
 }
+
+func (u UserHandler) forgotPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: forgotPassword, handler: user.")
+		req := &model.PasswordResetRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			err = errors.ErrInvalidArgument.New("Invalid request data.")
+			u.error(w, r, err)
+			return
+		}
+		//Token is created and sent in background, so response time doesn't depend on whether user exists.
+		//Request is dropped if too many resets are pending, response is the same.
+		select {
+		case u.resetSlots <- struct{}{}:
+			go func() {
+				defer func() { <-u.resetSlots }()
+				u.requestPasswordReset(req.Email)
+			}()
+		default:
+			log.Println("Password reset is dropped, too many resets are pending.")
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+//requestPasswordReset creates reset token and sends it if email is known, request outlives the response
+func (u UserHandler) requestPasswordReset(email string) {
+	ctx, cancel := context.WithTimeout(context.Background(), passwordResetTimeout)
+	defer cancel()
+	user, token, err := u.serviceManager.User.ForgotPassword(ctx, email)
+	if err != nil {
+		log.Printf("Err in create password reset token. Err: %s", err.Error())
+		return
+	}
+	if user != nil {
+		u.sendPasswordResetEmail(ctx, user.Email, token)
+	}
+}
+
+func (u UserHandler) sendPasswordResetEmail(ctx context.Context, email, token string) {
+	link := u.resetLink
+	if len(link) == 0 {
+		link = viper.GetString("domain") + "/users/password/reset"
+	}
+	tmpl, err := template.ParseFiles(filePath.PasswordResetTemplate)
+	if err != nil {
+		log.Printf("Err in parse password reset template. Err: %s", err.Error())
+		return
+	}
+	data := struct {
+		Email string
+		Link  string
+	}{
+		Email: email,
+		Link:  fmt.Sprintf("%s?token=%s", link, token),
+	}
+	buf := new(bytes.Buffer)
+	err = tmpl.Execute(buf, data)
+	if err != nil {
+		log.Printf("Err in execute password reset template. Err: %s", err.Error())
+		return
+	}
+	err = u.emailSender.Send(ctx, "Password reset", email, "text/html; charset=utf-8", buf.String())
+	if err != nil {
+		log.Printf("Err in send password reset email. Err: %s", err.Error())
+	}
+}
+
+func (u UserHandler) resetPassword() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: resetPassword, handler: user.")
+		req := &model.NewPasswordRequest{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil {
+			err = errors.ErrInvalidArgument.New("Invalid request data.")
+			u.error(w, r, err)
+			return
+		}
+		err = u.serviceManager.User.ResetPassword(r.Context(), req.Token, req.Password)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (u UserHandler) getSessions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getSessions, handler: user.")
+		claims := accessClaims(r)
+		sessions, err := u.serviceManager.User.FindUserSessions(r.Context(), claims.UserID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(*sessions))
+		for _, session := range *sessions {
+			session.Current = session.SessionID == claims.SessionID
+			items = append(items, session)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) signOutEverywhere() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: signOutEverywhere, handler: user.")
+		claims := accessClaims(r)
+		err := u.serviceManager.User.SignOutEverywhere(r.Context(), claims.UserID, claims.SessionID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (u UserHandler) revokeSession() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: revokeSession, handler: user.")
+		claims := accessClaims(r)
+		sessionID := mux.Vars(r)["id"]
+		err := u.serviceManager.User.SignOut(r.Context(), claims.UserID, sessionID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+//mfaCode returns the second factor code from request body
+func mfaCode(r *http.Request) (string, error) {
+	req := &struct {
+		Code string `json:"code"`
+	}{}
+	err := json.NewDecoder(r.Body).Decode(req)
+	if err != nil || len(req.Code) == 0 {
+		return "", errors.ErrInvalidArgument.New("Invalid two-factor code.")
+	}
+	return req.Code, nil
+}
+
+func (u UserHandler) enrollTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: enrollTOTP, handler: user.")
+		claims := accessClaims(r)
+		enrollment, err := u.serviceManager.User.EnrollTOTP(r.Context(), claims.UserID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(enrollment)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) confirmTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: confirmTOTP, handler: user.")
+		claims := accessClaims(r)
+		code, err := mfaCode(r)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		codes, err := u.serviceManager.User.ConfirmTOTP(r.Context(), claims.UserID, code)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(codes)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) disableTOTP() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: disableTOTP, handler: user.")
+		claims := accessClaims(r)
+		code, err := mfaCode(r)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		err = u.serviceManager.User.DisableTOTP(r.Context(), claims.UserID, code)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (u UserHandler) regenerateRecoveryCodes() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: regenerateRecoveryCodes, handler: user.")
+		claims := accessClaims(r)
+		code, err := mfaCode(r)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		codes, err := u.serviceManager.User.RegenerateRecoveryCodes(r.Context(), claims.UserID, code)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		responce := model.CreateOneOkResponce(codes)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) webauthnRegistrationOptions() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: webauthnRegistrationOptions, handler: user.")
+		claims := accessClaims(r)
+		options, err := u.serviceManager.User.BeginWebAuthnRegistration(r.Context(), claims.UserID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(options)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) registerWebAuthn() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: registerWebAuthn, handler: user.")
+		claims := accessClaims(r)
+		req := &struct {
+			Name       string                        `json:"name"`
+			Credential *webauthn.AttestationResponse `json:"credential"`
+		}{}
+		err := json.NewDecoder(r.Body).Decode(req)
+		if err != nil || req.Credential == nil {
+			u.error(w, r, errors.ErrInvalidArgument.New("Invalid credential."))
+			return
+		}
+		credential, err := u.serviceManager.User.FinishWebAuthnRegistration(r.Context(), claims.UserID, req.Name, req.Credential)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		responce := model.CreateOneOkResponce(credential)
+		u.respondJson(w, r, http.StatusCreated, responce)
+	}
+}
+
+func (u UserHandler) getWebAuthnCredentials() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getWebAuthnCredentials, handler: user.")
+		claims := accessClaims(r)
+		credentials, err := u.serviceManager.User.FindWebAuthnCredentials(r.Context(), claims.UserID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(credentials))
+		for _, v := range credentials {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) deleteWebAuthnCredential() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: deleteWebAuthnCredential, handler: user.")
+		claims := accessClaims(r)
+		err := u.serviceManager.User.DeleteWebAuthnCredential(r.Context(), claims.UserID, mux.Vars(r)["id"])
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (u UserHandler) getConsents() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: getConsents, handler: user.")
+		claims := accessClaims(r)
+		consents, err := u.serviceManager.OAuth.FindConsents(r.Context(), claims.UserID)
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		items := make([]interface{}, 0, len(consents))
+		for _, v := range consents {
+			items = append(items, v)
+		}
+		responce := model.CreateOkResponce(len(items), items)
+		u.respondJson(w, r, http.StatusOK, responce)
+	}
+}
+
+func (u UserHandler) revokeConsent() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Println("Accepted client. Method: revokeConsent, handler: user.")
+		claims := accessClaims(r)
+		err := u.serviceManager.OAuth.RevokeConsent(r.Context(), claims.UserID, mux.Vars(r)["client_id"])
+		if err != nil {
+			u.error(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+}
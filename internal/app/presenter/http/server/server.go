@@ -19,7 +19,7 @@ type server struct {
 	store          store.Store
 }
 
-func NewServer(sm *services.Manager, store store.Store, handlers ...handler.IHandler) (*server, error) {
+func NewServer(sm *services.Manager, store store.Store, middlewares []mux.MiddlewareFunc, handlers ...handler.IHandler) (*server, error) {
 	if sm == nil {
 		return nil, errors.ErrInvalidArgument.New("No service manager provided.")
 	}
@@ -27,6 +27,7 @@ func NewServer(sm *services.Manager, store store.Store, handlers ...handler.IHan
 		return nil, errors.ErrInvalidArgument.New("No store provided.")
 	}
 	router := &mux.Router{}
+	router.Use(middlewares...)
 	for _, h := range handlers {
 		h.ConfigureRoutes(router)
 	}
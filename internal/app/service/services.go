@@ -3,7 +3,10 @@ package service
 import (
 	"auth-server/internal/app/model"
 	"auth-server/internal/app/store"
+	"auth-server/pkg/webauthn"
 	"context"
+
+	"github.com/golang-jwt/jwt/v4"
 )
 
 type (
@@ -12,6 +15,11 @@ type (
 		UserCrud
 		UserSessionsFinder
 		UserAuthenticator
+		UserRoleManager
+		UserMFAManager
+		UserWebAuthnManager
+		UserPasswordResetter
+		UnlockUser(ctx context.Context, userID string) error
 		GenerateEmailConfToken(ctx context.Context, userID string) (string, error)
 	}
 	//Only methods for find user
@@ -21,8 +29,47 @@ type (
 		FindUserByName(ctx context.Context, username string, fields *store.UserFields) (*model.User, error)
 		FindUserByEmail(ctx context.Context, email string, fields *store.UserFields) (*model.User, error)
 	}
+	//Roles granted to users per client
+	UserRoleManager interface {
+		GrantRole(ctx context.Context, userID, clientID, role string) error
+		RevokeRole(ctx context.Context, userID, clientID, role string) error
+		FindRoleAssignments(ctx context.Context, clientID string) ([]model.RoleAssignment, error)
+		//ExplainRole returns where user gets the role of client from, directly or by groups
+		ExplainRole(ctx context.Context, userID, clientID, role string) ([]model.RoleSource, error)
+		//HasRole checks the role of user in client, granted directly or by groups
+		HasRole(ctx context.Context, userID, clientID, role string) (bool, error)
+	}
+	//Two-factor authentication by TOTP and recovery codes
+	UserMFAManager interface {
+		EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error)
+		ConfirmTOTP(ctx context.Context, userID, code string) (*model.RecoveryCodes, error)
+		DisableTOTP(ctx context.Context, userID, code string) error
+		RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*model.RecoveryCodes, error)
+		//StartMFA returns nil if user has no second factor, login is complete then
+		StartMFA(ctx context.Context, userID, clientID string) (*model.MFAChallenge, error)
+		CheckMFA(ctx context.Context, mfaToken, clientID string, answer *model.MFAAnswer) (*model.User, error)
+		AuthenticateMFA(ctx context.Context, mfaToken, clientID string, answer *model.MFAAnswer) (*model.Identity, error)
+	}
+	//Passkeys, they are used as second factor or instead of password
+	UserWebAuthnManager interface {
+		BeginWebAuthnRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, error)
+		FinishWebAuthnRegistration(ctx context.Context, userID, name string, resp *webauthn.AttestationResponse) (*model.WebAuthnCredential, error)
+		FindWebAuthnCredentials(ctx context.Context, userID string) ([]model.WebAuthnCredential, error)
+		DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error
+		BeginWebAuthnLogin(ctx context.Context, clientID, login string) (*webauthn.RequestOptions, error)
+		FinishWebAuthnLogin(ctx context.Context, clientID string, resp *webauthn.AssertionResponse) (*model.Identity, error)
+		BeginWebAuthnMFA(ctx context.Context, mfaToken, clientID string) (*webauthn.RequestOptions, error)
+	}
+	//Password recovery by single-use tokens sent to email
+	UserPasswordResetter interface {
+		//ForgotPassword returns nil user without error if email is unknown or token was created in cooldown
+		ForgotPassword(ctx context.Context, email string) (*model.User, string, error)
+		//ResetPassword sets new password and revokes all sessions and refresh tokens of user
+		ResetPassword(ctx context.Context, token, password string) error
+	}
 	UserSessionsFinder interface {
 		FindUserSessions(ctx context.Context, userID string) (*[]model.UserSession, error)
+		CheckSession(ctx context.Context, sessionID string) error
 	}
 	//Only methods for Create-Update-Delete
 	UserCrud interface {
@@ -35,12 +82,105 @@ type (
 	}
 	//Authenticate user :)
 	UserAuthenticator interface {
-		Authenticate(ctx context.Context, login, password, clientID string) (*model.User, *model.ClientRefToken, error)
-		UpdateRefToken(ctx context.Context, userID, clientID, refToken string) (*model.ClientRefToken, error)
+		//Authenticate returns MFA challenge instead of identity if user has second factor
+		Authenticate(ctx context.Context, login, password, clientID string) (*model.Identity, *model.MFAChallenge, error)
+		CheckCredentials(ctx context.Context, login, password string) (*model.User, error)
+		CreateIdentity(ctx context.Context, user *model.User, client *model.Client, scope string) (*model.Identity, error)
+		UpdateRefToken(ctx context.Context, clientID, refToken string) (*model.Identity, error)
 		SignOut(ctx context.Context, userID, sessionID string) error
+		SignOutEverywhere(ctx context.Context, userID, exceptSessionID string) error
+	}
+
+	//Issue and verify signed access tokens
+	TokenService interface {
+		//GenerateAccessToken signs token of client which is already loaded by caller, its lifetime is set by client
+		GenerateAccessToken(ctx context.Context, client *model.Client, claims *model.AccessClaims) (*model.Token, error)
+		ParseAccessToken(ctx context.Context, token string) (*model.AccessClaims, error)
+		GenerateIDToken(ctx context.Context, claims *model.IDClaims) (string, error)
+		PublicKeys(ctx context.Context) (*model.JSONWebKeySet, error)
+		//RevokeAccessToken adds the token to denylist till its expiration
+		RevokeAccessToken(ctx context.Context, claims *model.AccessClaims) error
+	}
+
+	//Manage groups of users and roles granted to groups
+	GroupService interface {
+		CreateGroup(ctx context.Context, group *model.Group) (*model.Group, error)
+		FindGroups(ctx context.Context) ([]model.Group, error)
+		FindGroupByID(ctx context.Context, groupID string) (*model.Group, error)
+		UpdateGroup(ctx context.Context, group *model.Group) (*model.Group, error)
+		DeleteGroup(ctx context.Context, groupID string) error
+		AddMember(ctx context.Context, groupID, userID string) error
+		RemoveMember(ctx context.Context, groupID, userID string) error
+		GrantRole(ctx context.Context, groupID, clientID, role string) error
+		RevokeRole(ctx context.Context, groupID, clientID, role string) error
+	}
+
+	//Registry of scopes and their descriptions
+	ScopeService interface {
+		CreateScope(ctx context.Context, scope *model.Scope) error
+		FindScopes(ctx context.Context) ([]model.Scope, error)
+		UpdateScope(ctx context.Context, scope *model.Scope) error
+		DeleteScope(ctx context.Context, name string) error
+		DescribeScopes(ctx context.Context, scope string) ([]model.Scope, error)
+	}
+
+	//Token bucket limits of requests per route
+	RateLimitService interface {
+		//Allow takes token for each key of route policy, keys are ip, client and user
+		Allow(ctx context.Context, route string, keys map[string]string) (*model.RateLimitResult, error)
+	}
+
+	//Manage asymmetric signing keys and their rotation
+	KeyService interface {
+		//Sign signs the claims by active key, kid is set in token header
+		Sign(ctx context.Context, claims jwt.Claims) (string, error)
+		//Parse verifies the token by key from kid header and decodes claims
+		Parse(ctx context.Context, token string, claims jwt.Claims) error
+		PublicKeys(ctx context.Context) (*model.JSONWebKeySet, error)
+		Keys(ctx context.Context) ([]model.SigningKey, error)
+		//Rotate creates new active key. If revoke is set, old keys stop verifying immediately.
+		Rotate(ctx context.Context, revoke bool) (*model.SigningKey, error)
+		//Run rotates keys on schedule till ctx is done
+		Run(ctx context.Context)
+	}
+
+	//OAuth 2.0 authorization code flow
+	OAuthService interface {
+		ValidateAuthorizeRequest(ctx context.Context, req *model.AuthorizeRequest) (*model.Client, error)
+		//CreateAuthCode returns the code and whether user has to give consent before the code is exchanged
+		CreateAuthCode(ctx context.Context, client *model.Client, req *model.AuthorizeRequest, userID string) (string, bool, error)
+		//Consent of users to third-party clients
+		DecideConsent(ctx context.Context, code string, approve bool) (*model.AuthCode, error)
+		FindConsents(ctx context.Context, userID string) ([]model.Consent, error)
+		RevokeConsent(ctx context.Context, userID, clientID string) error
+		Token(ctx context.Context, req *model.TokenRequest) (*model.OAuthTokenResponce, error)
+		UserInfo(ctx context.Context, claims *model.AccessClaims) (map[string]interface{}, error)
+		Introspect(ctx context.Context, clientID, token, tokenTypeHint string) (*model.IntrospectionResponce, error)
+		Revoke(ctx context.Context, clientID, token, tokenTypeHint string) error
+		//Device authorization grant
+		AuthorizeDevice(ctx context.Context, creds *model.ClientCredentials, scope string) (*model.DeviceAuthorizationResponce, error)
+		FindDeviceAuthorization(ctx context.Context, userCode string) (*model.DeviceAuthorization, *model.Client, error)
+		DecideDeviceAuthorization(ctx context.Context, userCode, userID string, approve bool) error
 	}
 
 	ClientService interface {
 		FindClientByID(ctx context.Context, clientID string) (*model.Client, error)
+		//AuthenticateClient checks the secret or assertion of confidential client
+		AuthenticateClient(ctx context.Context, creds *model.ClientCredentials) (*model.Client, error)
+		CreateClient(ctx context.Context, client *model.Client) (*model.Client, error)
+		FindClients(ctx context.Context) ([]model.Client, error)
+		UpdateClient(ctx context.Context, client *model.Client) (*model.Client, error)
+		DeleteClient(ctx context.Context, clientID string) error
+		//Dynamic client registration, RFC 7591 and RFC 7592
+		CheckInitialAccessToken(token string) error
+		RegisterClient(ctx context.Context, metadata *model.ClientMetadata) (*model.Client, string, error)
+		FindRegisteredClient(ctx context.Context, clientID, regToken string) (*model.Client, error)
+		UpdateRegisteredClient(ctx context.Context, clientID, regToken string, metadata *model.ClientMetadata) (*model.Client, error)
+		DeleteRegisteredClient(ctx context.Context, clientID, regToken string) error
+		CreateServiceAccount(ctx context.Context, account *model.Client) (*model.Client, error)
+		FindServiceAccounts(ctx context.Context) ([]model.Client, error)
+		DeleteServiceAccount(ctx context.Context, clientID string) error
+		ResetClientSecret(ctx context.Context, clientID string) (string, error)
+		ResetServiceAccountSecret(ctx context.Context, clientID string) (string, error)
 	}
 )
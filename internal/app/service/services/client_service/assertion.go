@@ -0,0 +1,172 @@
+package client_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+//maxAssertionTTL limits lifetime of client assertion, so denylist of used assertions stays small
+const maxAssertionTTL = 5 * time.Minute
+
+//verifyAssertion checks private_key_jwt client assertion, RFC 7523 section 3.
+//Each assertion is accepted once, its jti is kept in revoked tokens till expiration.
+func (c *ClientService) verifyAssertion(ctx context.Context, creds *model.ClientCredentials) (*model.Client, error) {
+	if creds.AssertionType != model.ClientAssertionTypeJWT {
+		return nil, errors.ErrUnauthorized.New("Unsupported client assertion type.")
+	}
+	var (
+		client *model.Client
+		keyErr error
+	)
+	claims := &jwt.RegisteredClaims{}
+	_, err := jwt.ParseWithClaims(creds.Assertion, claims, func(t *jwt.Token) (interface{}, error) {
+		var key crypto.PublicKey
+		client, key, keyErr = c.assertionKey(ctx, creds.ClientID, t)
+		return key, keyErr
+	})
+	if err != nil {
+		//storage errors are not the client's fault
+		if keyErr != nil && errors.GetType(keyErr) == errors.NoType {
+			return nil, keyErr
+		}
+		return nil, errors.ErrUnauthorized.New("Invalid client assertion.")
+	}
+	if !c.validAudience(claims.Audience) || claims.ExpiresAt == nil || len(claims.ID) == 0 ||
+		time.Until(claims.ExpiresAt.Time) > maxAssertionTTL {
+		return nil, errors.ErrUnauthorized.New("Invalid client assertion.")
+	}
+	//assertion ID is unique key of denylist, so only the first of concurrent requests inserts it
+	err = c.store.RevokedToken().Create(ctx, assertionID(client.ID, claims.ID), claims.ExpiresAt.Time)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrDuplicateEntry {
+			return nil, errors.ErrUnauthorized.New("Client assertion already used.")
+		}
+		return nil, err
+	}
+	return client, nil
+}
+
+//assertionKey returns the client which issued assertion and its public key
+func (c *ClientService) assertionKey(ctx context.Context, clientID string, t *jwt.Token) (*model.Client, crypto.PublicKey, error) {
+	claims := t.Claims.(*jwt.RegisteredClaims)
+	if claims.Issuer != claims.Subject || (len(clientID) > 0 && clientID != claims.Subject) {
+		return nil, nil, errors.ErrUnauthorized.New("Invalid client assertion issuer.")
+	}
+	client, err := c.store.Client().FindById(ctx, claims.Subject)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client.TokenEndpointAuthMethod != model.ClientAuthPrivateKeyJWT || client.JWKS == nil {
+		return nil, nil, errors.ErrUnauthorized.New("Client has no registered keys.")
+	}
+	kid, _ := t.Header["kid"].(string)
+	key, err := findPublicKey(client.JWKS, kid, t.Method.Alg())
+	if err != nil {
+		return nil, nil, err
+	}
+	return client, key, nil
+}
+
+//assertionID makes jti of assertion unique among clients
+func assertionID(clientID, jti string) string {
+	return "assertion:" + clientID + ":" + jti
+}
+
+func (c *ClientService) validAudience(audience jwt.ClaimStrings) bool {
+	for _, aud := range audience {
+		for _, v := range c.audiences {
+			if aud == v {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//findPublicKey returns client key by kid, or the only key if kid is not set
+func findPublicKey(jwks *model.JSONWebKeySet, kid, alg string) (crypto.PublicKey, error) {
+	for i := range jwks.Keys {
+		jwk := &jwks.Keys[i]
+		if (len(kid) > 0 && jwk.Kid != kid) || (len(kid) == 0 && len(jwks.Keys) > 1) {
+			continue
+		}
+		if len(jwk.Alg) > 0 && jwk.Alg != alg {
+			return nil, errors.ErrUnauthorized.New("Client key algorithm mismatch.")
+		}
+		key, err := parsePublicKey(jwk)
+		if err != nil {
+			return nil, err
+		}
+		//signing method must match key type
+		switch key.(type) {
+		case *rsa.PublicKey:
+			if alg != jwt.SigningMethodRS256.Alg() {
+				return nil, errors.ErrUnauthorized.New("Client key algorithm mismatch.")
+			}
+		case *ecdsa.PublicKey:
+			if alg != jwt.SigningMethodES256.Alg() {
+				return nil, errors.ErrUnauthorized.New("Client key algorithm mismatch.")
+			}
+		case ed25519.PublicKey:
+			if alg != jwt.SigningMethodEdDSA.Alg() {
+				return nil, errors.ErrUnauthorized.New("Client key algorithm mismatch.")
+			}
+		}
+		return key, nil
+	}
+	return nil, errors.ErrUnauthorized.New("Client key not found.")
+}
+
+//parsePublicKey decodes public JWK, RFC 7518 section 6
+func parsePublicKey(jwk *model.JSONWebKey) (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch jwk.Kty {
+	case "RSA":
+		n, errN := decode(jwk.N)
+		e, errE := decode(jwk.E)
+		if errN != nil || errE != nil || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, errors.ErrInvalidArgument.New("Invalid RSA key.")
+		}
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}, nil
+	case "EC":
+		if jwk.Crv != elliptic.P256().Params().Name {
+			return nil, errors.ErrInvalidArgument.New("Unsupported EC curve.")
+		}
+		x, errX := decode(jwk.X)
+		y, errY := decode(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, errors.ErrInvalidArgument.New("Invalid EC key.")
+		}
+		key := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !key.Curve.IsOnCurve(key.X, key.Y) {
+			return nil, errors.ErrInvalidArgument.New("Invalid EC key.")
+		}
+		return key, nil
+	case "OKP":
+		x, err := decode(jwk.X)
+		if jwk.Crv != "Ed25519" || err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, errors.ErrInvalidArgument.New("Invalid Ed25519 key.")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Unsupported key type %s.", jwk.Kty)
+	}
+}
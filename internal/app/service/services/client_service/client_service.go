@@ -0,0 +1,403 @@
+package client_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type ClientService struct {
+	store              store.Store
+	audiences          []string
+	initialAccessToken string
+}
+
+//New creates client service, audiences are accepted in client assertions.
+//Dynamic registration is closed if initialAccessToken is empty.
+func New(store store.Store, audiences []string, initialAccessToken string) (*ClientService, error) {
+	cs := ClientService{
+		store:              store,
+		audiences:          audiences,
+		initialAccessToken: initialAccessToken,
+	}
+	return &cs, nil
+}
+
+func generateClientSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate client secret.")
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+func hashClientSecret(secret string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+	if err != nil {
+		return "", errors.NoType.Wrap(err, "Err hash client secret.")
+	}
+	return string(hash), nil
+}
+
+//usesSecret checks that client authenticates by client secret
+func usesSecret(authMethod string) bool {
+	return authMethod == model.ClientAuthSecretBasic || authMethod == model.ClientAuthSecretPost
+}
+
+//validateURI checks that uri is absolute URI without fragment, RFC 6749 section 3.1.2
+func validateURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || len(u.Host) == 0 || len(u.Fragment) > 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid URI %s.", uri)
+	}
+	return nil
+}
+
+//validateClient checks registration data of client and sets default authentication method
+func validateClient(client *model.Client) error {
+	if len(client.ClientName) == 0 {
+		return errors.ErrInvalidArgument.New("Client name required.")
+	}
+	switch client.TokenEndpointAuthMethod {
+	case "":
+		client.TokenEndpointAuthMethod = model.ClientAuthSecretBasic
+	case model.ClientAuthNone, model.ClientAuthSecretBasic, model.ClientAuthSecretPost:
+	case model.ClientAuthPrivateKeyJWT:
+		if client.JWKS == nil || len(client.JWKS.Keys) == 0 {
+			return errors.ErrInvalidArgument.New("JWKS required for private_key_jwt.")
+		}
+		for i := range client.JWKS.Keys {
+			if _, err := parsePublicKey(&client.JWKS.Keys[i]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.ErrInvalidArgument.Newf("Unsupported token endpoint auth method %s.", client.TokenEndpointAuthMethod)
+	}
+	for _, v := range client.GrantTypes {
+		switch v {
+		case model.GrantTypeAuthorizationCode, model.GrantTypeRefreshToken, model.GrantTypeDeviceCode:
+		case model.GrantTypeClientCredentials:
+			if !client.IsConfidential() {
+				return errors.ErrInvalidArgument.New("Public client can't use client_credentials.")
+			}
+		default:
+			return errors.ErrInvalidArgument.Newf("Unsupported grant type %s.", v)
+		}
+	}
+	if client.HasGrantType(model.GrantTypeAuthorizationCode) && len(client.RedirectURIs) == 0 {
+		return errors.ErrInvalidArgument.New("Redirect URI required for authorization_code.")
+	}
+	for _, v := range client.RedirectURIs {
+		if err := validateURI(v); err != nil {
+			return err
+		}
+	}
+	if len(client.LogoURI) > 0 {
+		if err := validateURI(client.LogoURI); err != nil {
+			return err
+		}
+	}
+	names := make(map[string]bool)
+	for _, v := range client.RoleDefinitions {
+		if len(v.Name) == 0 || strings.ContainsAny(v.Name, " /") || names[v.Name] {
+			return errors.ErrInvalidArgument.Newf("Invalid role name %s.", v.Name)
+		}
+		names[v.Name] = true
+	}
+	if client.AccessTokenLifetime < 0 || client.RefreshTokenLifetime < 0 {
+		return errors.ErrInvalidArgument.New("Token lifetime can't be negative.")
+	}
+	return nil
+}
+
+//checkScopes checks that declared scopes are registered
+func (c *ClientService) checkScopes(ctx context.Context, scope string) error {
+	names := strings.Fields(scope)
+	if len(names) == 0 {
+		return nil
+	}
+	registered, err := c.store.Scope().FindByNames(ctx, names)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, v := range registered {
+		known[v.Name] = true
+	}
+	for _, v := range names {
+		if !known[v] {
+			return errors.ErrInvalidArgument.Newf("Unknown scope %s.", v)
+		}
+	}
+	return nil
+}
+
+func (c *ClientService) FindClientByID(ctx context.Context, clientID string) (*model.Client, error) {
+	client, err := c.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	client.Sanitize()
+	return client, nil
+}
+
+//AuthenticateClient checks client secret or private_key_jwt assertion.
+//Public clients can't be authenticated.
+func (c *ClientService) AuthenticateClient(ctx context.Context, creds *model.ClientCredentials) (*model.Client, error) {
+	var (
+		client *model.Client
+		err    error
+	)
+	switch {
+	case len(creds.Assertion) > 0:
+		client, err = c.verifyAssertion(ctx, creds)
+	case len(creds.ClientID) > 0 && len(creds.Secret) > 0:
+		client, err = c.verifySecret(ctx, creds.ClientID, creds.Secret)
+	default:
+		return nil, errors.ErrUnauthorized.New("Client credentials required.")
+	}
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, errors.ErrUnauthorized.New("Invalid client credentials.")
+		}
+		return nil, err
+	}
+	client.Sanitize()
+	return client, nil
+}
+
+func (c *ClientService) verifySecret(ctx context.Context, clientID, secret string) (*model.Client, error) {
+	client, err := c.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if client.TokenEndpointAuthMethod == model.ClientAuthPrivateKeyJWT || len(client.SecretHash) == 0 ||
+		bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(secret)) != nil {
+		return nil, errors.ErrUnauthorized.New("Invalid client credentials.")
+	}
+	return client, nil
+}
+
+//CreateServiceAccount registers confidential client which uses client_credentials grant.
+//Generated secret is returned once, only its hash is stored.
+func (c *ClientService) CreateServiceAccount(ctx context.Context, account *model.Client) (*model.Client, error) {
+	if account == nil || len(account.ClientName) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Service account name required.")
+	}
+	if err := c.checkScopes(ctx, account.Scope); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	client := &model.Client{
+		ClientName:              account.ClientName,
+		TokenEndpointAuthMethod: model.ClientAuthSecretBasic,
+		GrantTypes:              []string{model.GrantTypeClientCredentials},
+		Scope:                   account.Scope,
+		Roles:                   account.Roles,
+		ServiceAccount:          true,
+		CreatedAt:               &now,
+	}
+	secret := ""
+	if account.TokenEndpointAuthMethod == model.ClientAuthPrivateKeyJWT {
+		if account.JWKS == nil || len(account.JWKS.Keys) == 0 {
+			return nil, errors.ErrInvalidArgument.New("JWKS required for private_key_jwt.")
+		}
+		for i := range account.JWKS.Keys {
+			if _, err := parsePublicKey(&account.JWKS.Keys[i]); err != nil {
+				return nil, err
+			}
+		}
+		client.TokenEndpointAuthMethod = model.ClientAuthPrivateKeyJWT
+		client.JWKS = account.JWKS
+	} else {
+		var err error
+		secret, err = generateClientSecret()
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash, err = hashClientSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	id, err := c.store.Client().Create(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	client.ID = id
+	client.SecretHash = ""
+	client.ClientSecret = secret
+	return client, nil
+}
+
+//CreateClient registers client. Generated secret is returned once if client authenticates by secret.
+func (c *ClientService) CreateClient(ctx context.Context, client *model.Client) (*model.Client, error) {
+	if client == nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid client.")
+	}
+	if err := validateClient(client); err != nil {
+		return nil, err
+	}
+	if err := c.checkScopes(ctx, client.Scope); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	client.ID = ""
+	client.ServiceAccount = false
+	client.CreatedAt = &now
+	client.ClientsRefTokens = nil
+	secret := ""
+	if usesSecret(client.TokenEndpointAuthMethod) {
+		var err error
+		secret, err = generateClientSecret()
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash, err = hashClientSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	id, err := c.store.Client().Create(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	client.ID = id
+	client.SecretHash = ""
+	client.ClientSecret = secret
+	return client, nil
+}
+
+func (c *ClientService) FindClients(ctx context.Context) ([]model.Client, error) {
+	clients, err := c.store.Client().FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range clients {
+		clients[i].Sanitize()
+	}
+	return clients, nil
+}
+
+//UpdateClient replaces registration data of client.
+//If client starts to authenticate by secret, new secret is generated and returned once.
+func (c *ClientService) UpdateClient(ctx context.Context, client *model.Client) (*model.Client, error) {
+	if client == nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid client.")
+	}
+	current, err := c.store.Client().FindById(ctx, client.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateClient(client); err != nil {
+		return nil, err
+	}
+	if err = c.checkScopes(ctx, client.Scope); err != nil {
+		return nil, err
+	}
+	//secret is written together with registration data, so client never has auth method without secret
+	secret := ""
+	switch {
+	case !usesSecret(client.TokenEndpointAuthMethod):
+		client.SecretHash = ""
+	case len(current.SecretHash) > 0:
+		client.SecretHash = current.SecretHash
+	default:
+		secret, err = generateClientSecret()
+		if err != nil {
+			return nil, err
+		}
+		client.SecretHash, err = hashClientSecret(secret)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err = c.store.Client().Update(ctx, client); err != nil {
+		return nil, err
+	}
+	client.ServiceAccount = current.ServiceAccount
+	client.CreatedAt = current.CreatedAt
+	client.ClientsRefTokens = nil
+	client.SecretHash = ""
+	client.ClientSecret = secret
+	return client, nil
+}
+
+//DeleteClient removes client together with its refresh tokens
+func (c *ClientService) DeleteClient(ctx context.Context, clientID string) error {
+	return c.store.Client().Delete(ctx, clientID)
+}
+
+func (c *ClientService) FindServiceAccounts(ctx context.Context) ([]model.Client, error) {
+	clients, err := c.store.Client().FindServiceAccounts(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range clients {
+		clients[i].Sanitize()
+	}
+	return clients, nil
+}
+
+func (c *ClientService) findServiceAccount(ctx context.Context, clientID string) (*model.Client, error) {
+	client, err := c.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if !client.ServiceAccount {
+		return nil, errors.ErrInvalidArgument.Newf("Client %s is not a service account.", clientID)
+	}
+	return client, nil
+}
+
+func (c *ClientService) DeleteServiceAccount(ctx context.Context, clientID string) error {
+	if _, err := c.findServiceAccount(ctx, clientID); err != nil {
+		return err
+	}
+	return c.store.Client().Delete(ctx, clientID)
+}
+
+//ResetClientSecret replaces secret of client, the old secret stops working immediately
+func (c *ClientService) ResetClientSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := c.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	return c.resetSecret(ctx, client)
+}
+
+//ResetServiceAccountSecret replaces secret of service account, other clients are rejected
+func (c *ClientService) ResetServiceAccountSecret(ctx context.Context, clientID string) (string, error) {
+	client, err := c.findServiceAccount(ctx, clientID)
+	if err != nil {
+		return "", err
+	}
+	return c.resetSecret(ctx, client)
+}
+
+func (c *ClientService) resetSecret(ctx context.Context, client *model.Client) (string, error) {
+	if !usesSecret(client.TokenEndpointAuthMethod) {
+		return "", errors.ErrInvalidArgument.Newf("Client uses %s.", client.TokenEndpointAuthMethod)
+	}
+	secret, err := generateClientSecret()
+	if err != nil {
+		return "", err
+	}
+	hash, err := hashClientSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	if err = c.store.Client().UpdateSecret(ctx, client.ID, hash); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
@@ -0,0 +1,121 @@
+package client_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+)
+
+func hashRegistrationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+//metadataError converts validation error of client to registration error, RFC 7591 section 3.2.2
+func metadataError(err error) error {
+	if errors.GetType(err) != errors.ErrInvalidArgument {
+		return err
+	}
+	return model.NewOAuthError(model.OAuthErrInvalidClientMetadata, err.Error())
+}
+
+//validateMetadata checks redirect URIs of self-registered client
+func validateMetadata(metadata *model.ClientMetadata) error {
+	client := model.Client{GrantTypes: metadata.GrantTypes}
+	if client.HasGrantType(model.GrantTypeAuthorizationCode) && len(metadata.RedirectURIs) == 0 {
+		return model.NewOAuthError(model.OAuthErrInvalidRedirectURI, "Redirect URI required.")
+	}
+	for _, v := range metadata.RedirectURIs {
+		if err := validateURI(v); err != nil {
+			return model.NewOAuthError(model.OAuthErrInvalidRedirectURI, err.Error())
+		}
+	}
+	return nil
+}
+
+//CheckInitialAccessToken checks the token which allows clients to register, registration is closed if no token configured
+func (c *ClientService) CheckInitialAccessToken(token string) error {
+	if len(c.initialAccessToken) == 0 || len(token) == 0 ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(c.initialAccessToken)) != 1 {
+		return errors.ErrUnauthorized.New("Invalid initial access token.")
+	}
+	return nil
+}
+
+//RegisterClient registers client by its own metadata, RFC 7591 section 3.
+//Client secret and registration access token are returned once.
+func (c *ClientService) RegisterClient(ctx context.Context, metadata *model.ClientMetadata) (*model.Client, string, error) {
+	if err := validateMetadata(metadata); err != nil {
+		return nil, "", err
+	}
+	client := &model.Client{}
+	metadata.Apply(client)
+	regToken, err := generateClientSecret()
+	if err != nil {
+		return nil, "", err
+	}
+	client.RegistrationTokenHash = hashRegistrationToken(regToken)
+	client, err = c.CreateClient(ctx, client)
+	if err != nil {
+		return nil, "", metadataError(err)
+	}
+	client.RegistrationTokenHash = ""
+	return client, regToken, nil
+}
+
+//findRegisteredClient returns the client if registration access token belongs to it.
+//Unknown clients and invalid tokens aren't distinguished, RFC 7592 section 2.
+func (c *ClientService) findRegisteredClient(ctx context.Context, clientID, regToken string) (*model.Client, error) {
+	client, err := c.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, errors.ErrUnauthorized.New("Invalid registration access token.")
+		}
+		return nil, err
+	}
+	hash := hashRegistrationToken(regToken)
+	if len(client.RegistrationTokenHash) == 0 || len(regToken) == 0 ||
+		subtle.ConstantTimeCompare([]byte(hash), []byte(client.RegistrationTokenHash)) != 1 {
+		return nil, errors.ErrUnauthorized.New("Invalid registration access token.")
+	}
+	return client, nil
+}
+
+//FindRegisteredClient reads the client configuration, RFC 7592 section 2.1
+func (c *ClientService) FindRegisteredClient(ctx context.Context, clientID, regToken string) (*model.Client, error) {
+	client, err := c.findRegisteredClient(ctx, clientID, regToken)
+	if err != nil {
+		return nil, err
+	}
+	client.Sanitize()
+	return client, nil
+}
+
+//UpdateRegisteredClient replaces the client metadata, RFC 7592 section 2.2.
+//Roles and token lifetimes set by admin are kept.
+func (c *ClientService) UpdateRegisteredClient(ctx context.Context, clientID, regToken string, metadata *model.ClientMetadata) (*model.Client, error) {
+	client, err := c.findRegisteredClient(ctx, clientID, regToken)
+	if err != nil {
+		return nil, err
+	}
+	if err = validateMetadata(metadata); err != nil {
+		return nil, err
+	}
+	metadata.Apply(client)
+	client, err = c.UpdateClient(ctx, client)
+	if err != nil {
+		return nil, metadataError(err)
+	}
+	return client, nil
+}
+
+//DeleteRegisteredClient deregisters the client, RFC 7592 section 2.3
+func (c *ClientService) DeleteRegisteredClient(ctx context.Context, clientID, regToken string) error {
+	if _, err := c.findRegisteredClient(ctx, clientID, regToken); err != nil {
+		return err
+	}
+	return c.DeleteClient(ctx, clientID)
+}
@@ -0,0 +1,152 @@
+package group_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+)
+
+type GroupService struct {
+	store store.Store
+}
+
+func New(store store.Store) (*GroupService, error) {
+	gs := GroupService{
+		store: store,
+	}
+	return &gs, nil
+}
+
+//checkParent checks that parent exists, group doesn't become its own ancestor
+//and ancestors together with subtree of group aren't deeper than MaxGroupDepth
+func (g *GroupService) checkParent(ctx context.Context, groupID, parentID string) error {
+	depth := 1
+	if len(groupID) > 0 {
+		height, err := g.subtreeHeight(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		depth = height
+	}
+	for id := parentID; len(id) > 0; depth++ {
+		if id == groupID {
+			return errors.ErrInvalidArgument.New("Group can't be nested in itself.")
+		}
+		if depth >= model.MaxGroupDepth {
+			return errors.ErrInvalidArgument.Newf("Groups can't be nested deeper than %d.", model.MaxGroupDepth)
+		}
+		parent, err := g.store.Group().FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+//subtreeHeight returns the count of levels in subtree of group, the group included.
+//Levels below MaxGroupDepth aren't counted, such subtree can't be nested anyway.
+func (g *GroupService) subtreeHeight(ctx context.Context, groupID string) (int, error) {
+	height := 1
+	level := []string{groupID}
+	for height <= model.MaxGroupDepth {
+		children, err := g.store.Group().FindChildren(ctx, level)
+		if err != nil {
+			return 0, err
+		}
+		if len(children) == 0 {
+			break
+		}
+		height++
+		level = make([]string, 0, len(children))
+		for _, v := range children {
+			level = append(level, v.ID)
+		}
+	}
+	return height, nil
+}
+
+func (g *GroupService) CreateGroup(ctx context.Context, group *model.Group) (*model.Group, error) {
+	if group == nil || len(group.Name) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Group name required.")
+	}
+	if err := g.checkParent(ctx, "", group.ParentID); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	group.CreatedAt = &now
+	group.Members = nil
+	group.Roles = nil
+	id, err := g.store.Group().Create(ctx, group)
+	if err != nil {
+		return nil, err
+	}
+	group.ID = id
+	return group, nil
+}
+
+func (g *GroupService) FindGroups(ctx context.Context) ([]model.Group, error) {
+	return g.store.Group().FindAll(ctx)
+}
+
+func (g *GroupService) FindGroupByID(ctx context.Context, groupID string) (*model.Group, error) {
+	return g.store.Group().FindByID(ctx, groupID)
+}
+
+//UpdateGroup replaces name, description and parent of group
+func (g *GroupService) UpdateGroup(ctx context.Context, group *model.Group) (*model.Group, error) {
+	if group == nil || len(group.Name) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Group name required.")
+	}
+	if _, err := g.store.Group().FindByID(ctx, group.ID); err != nil {
+		return nil, err
+	}
+	if err := g.checkParent(ctx, group.ID, group.ParentID); err != nil {
+		return nil, err
+	}
+	if err := g.store.Group().Update(ctx, group); err != nil {
+		return nil, err
+	}
+	return g.store.Group().FindByID(ctx, group.ID)
+}
+
+//DeleteGroup removes group without subgroups, members lose roles of the group
+func (g *GroupService) DeleteGroup(ctx context.Context, groupID string) error {
+	children, err := g.store.Group().CountChildren(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if children > 0 {
+		return errors.ErrInvalidArgument.New("Group has subgroups.")
+	}
+	return g.store.Group().Delete(ctx, groupID)
+}
+
+func (g *GroupService) AddMember(ctx context.Context, groupID, userID string) error {
+	if _, err := g.store.User().FindById(ctx, userID, nil); err != nil {
+		return err
+	}
+	return g.store.Group().AddMember(ctx, groupID, userID)
+}
+
+func (g *GroupService) RemoveMember(ctx context.Context, groupID, userID string) error {
+	return g.store.Group().RemoveMember(ctx, groupID, userID)
+}
+
+//GrantRole grants the role defined by client to group
+func (g *GroupService) GrantRole(ctx context.Context, groupID, clientID, role string) error {
+	client, err := g.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if !client.HasRole(role) {
+		return errors.ErrInvalidArgument.Newf("Role %s is not defined by client.", role)
+	}
+	return g.store.Group().GrantRole(ctx, groupID, clientID, role)
+}
+
+func (g *GroupService) RevokeRole(ctx context.Context, groupID, clientID, role string) error {
+	return g.store.Group().RevokeRole(ctx, groupID, clientID, role)
+}
@@ -0,0 +1,281 @@
+package key_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	"auth-server/pkg/encryption"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	//cacheTTL is how long keys are kept in memory, so rotation by other instance is seen in this time
+	cacheTTL = time.Minute
+	//rotationCheckInterval is how often Run checks the age of active key
+	rotationCheckInterval = time.Minute
+	//minReloadInterval limits reloads caused by unknown kid
+	minReloadInterval = 5 * time.Second
+)
+
+type KeyService struct {
+	repo           store.KeyRepository
+	crypter        *encryption.Cipher
+	algorithm      string
+	rotationPeriod time.Duration
+	overlap        time.Duration
+
+	mu       sync.RWMutex
+	keys     map[string]*signingKey
+	active   *signingKey
+	loadedAt time.Time
+}
+
+//New creates key service. Retired keys verify tokens during overlap, so it must be longer than tokens TTL.
+//Private keys are encrypted by crypter before they are stored.
+func New(repo store.KeyRepository, crypter *encryption.Cipher, algorithm string, rotationPeriod, overlap time.Duration) (*KeyService, error) {
+	if repo == nil {
+		return nil, errors.ErrInvalidArgument.New("Key repository is nill.")
+	}
+	if crypter == nil {
+		return nil, errors.ErrInvalidArgument.New("Key crypter is nill.")
+	}
+	switch algorithm {
+	case model.SigningAlgRS256, model.SigningAlgES256, model.SigningAlgEdDSA:
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Unsupported signing algorithm %s.", algorithm)
+	}
+	if rotationPeriod <= 0 || overlap <= 0 {
+		return nil, errors.ErrInvalidArgument.New("Key rotation period and overlap must be positive.")
+	}
+	ks := KeyService{
+		repo:           repo,
+		crypter:        crypter,
+		algorithm:      algorithm,
+		rotationPeriod: rotationPeriod,
+		overlap:        overlap,
+	}
+	return &ks, nil
+}
+
+func (k *KeyService) Sign(ctx context.Context, claims jwt.Claims) (string, error) {
+	active, err := k.activeKey(ctx)
+	if err != nil {
+		return "", err
+	}
+	token := jwt.NewWithClaims(active.method, claims)
+	token.Header["kid"] = active.ID
+	signed, err := token.SignedString(active.signer)
+	if err != nil {
+		return "", errors.NoType.Wrap(err, "Err sign token.")
+	}
+	return signed, nil
+}
+
+func (k *KeyService) Parse(ctx context.Context, token string, claims jwt.Claims) error {
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+		kid, _ := t.Header["kid"].(string)
+		key, err := k.findKey(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if t.Method.Alg() != key.Algorithm {
+			return nil, errors.ErrInvalidArgument.Newf("Unexpected signing method %v", t.Header["alg"])
+		}
+		return key.signer.Public(), nil
+	})
+	if err != nil {
+		return errors.ErrInvalidArgument.New("Invalid token.")
+	}
+	return nil
+}
+
+//PublicKeys returns keys which verify tokens, retired keys are published during overlap
+func (k *KeyService) PublicKeys(ctx context.Context) (*model.JSONWebKeySet, error) {
+	if err := k.load(ctx, false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set := &model.JSONWebKeySet{Keys: []model.JSONWebKey{}}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.jwk)
+	}
+	return set, nil
+}
+
+//Keys returns stored keys without private parts
+func (k *KeyService) Keys(ctx context.Context) ([]model.SigningKey, error) {
+	keys, err := k.repo.FindValid(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range keys {
+		keys[i].PrivateKey = ""
+	}
+	return keys, nil
+}
+
+func (k *KeyService) Rotate(ctx context.Context, revoke bool) (*model.SigningKey, error) {
+	private, err := generateKey(k.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	id, err := generateKeyID()
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := k.crypter.Encrypt([]byte(private), []byte(id))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	key := &model.SigningKey{
+		ID:         id,
+		Algorithm:  k.algorithm,
+		PrivateKey: encrypted,
+		Active:     true,
+		CreatedAt:  now,
+	}
+	if err = k.repo.Create(ctx, key); err != nil {
+		return nil, err
+	}
+	expIn := now.Add(k.overlap)
+	if revoke {
+		expIn = now
+	}
+	if err = k.repo.Retire(ctx, key.ID, expIn); err != nil {
+		return nil, err
+	}
+	log.Printf("Signing key rotated. New key: %s, revoke old keys: %t.", key.ID, revoke)
+	if err = k.load(ctx, true); err != nil {
+		return nil, err
+	}
+	key.PrivateKey = ""
+	return key, nil
+}
+
+func (k *KeyService) Run(ctx context.Context) {
+	ticker := time.NewTicker(rotationCheckInterval)
+	defer ticker.Stop()
+	for {
+		if err := k.rotateIfDue(ctx); err != nil {
+			log.Printf("Err in scheduled key rotation. Err: %s", err.Error())
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (k *KeyService) rotateIfDue(ctx context.Context) error {
+	//reload, so the key rotated by other instance is not rotated again
+	if err := k.load(ctx, true); err != nil {
+		return err
+	}
+	k.mu.RLock()
+	due := k.active == nil || time.Since(k.active.CreatedAt) >= k.rotationPeriod
+	k.mu.RUnlock()
+	if !due {
+		return nil
+	}
+	_, err := k.Rotate(ctx, false)
+	return err
+}
+
+func (k *KeyService) activeKey(ctx context.Context) (*signingKey, error) {
+	if err := k.load(ctx, false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	active := k.active
+	k.mu.RUnlock()
+	if active != nil {
+		return active, nil
+	}
+	if _, err := k.Rotate(ctx, false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.active == nil {
+		return nil, errors.NoType.New("No active signing key.")
+	}
+	return k.active, nil
+}
+
+func (k *KeyService) findKey(ctx context.Context, kid string) (*signingKey, error) {
+	if err := k.load(ctx, false); err != nil {
+		return nil, err
+	}
+	k.mu.RLock()
+	key, ok := k.keys[kid]
+	stale := time.Since(k.loadedAt) >= minReloadInterval
+	k.mu.RUnlock()
+	if !ok && stale {
+		//key may be created by other instance after last load
+		if err := k.load(ctx, true); err != nil {
+			return nil, err
+		}
+		k.mu.RLock()
+		key, ok = k.keys[kid]
+		k.mu.RUnlock()
+	}
+	if !ok || !key.IsValid(time.Now()) {
+		return nil, errors.ErrInvalidArgument.New("Unknown signing key.")
+	}
+	return key, nil
+}
+
+//load reads keys from repository if cache is expired or force is set
+func (k *KeyService) load(ctx context.Context, force bool) error {
+	k.mu.RLock()
+	fresh := !force && time.Since(k.loadedAt) < cacheTTL
+	k.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	stored, err := k.repo.FindValid(ctx)
+	if err != nil {
+		return err
+	}
+	keys := make(map[string]*signingKey, len(stored))
+	var active *signingKey
+	for i := range stored {
+		key, err := k.decryptKey(&stored[i])
+		if err != nil {
+			log.Printf("Err in load signing key. Err: %s", err.Error())
+			continue
+		}
+		keys[key.ID] = key
+		if key.Active && (active == nil || key.CreatedAt.After(active.CreatedAt)) {
+			active = key
+		}
+	}
+	k.mu.Lock()
+	k.keys = keys
+	k.active = active
+	k.loadedAt = time.Now()
+	k.mu.Unlock()
+	return nil
+}
+
+//decryptKey opens and parses stored key. Keys stored before encryption are read as is till they are rotated.
+func (k *KeyService) decryptKey(key *model.SigningKey) (*signingKey, error) {
+	if encryption.IsEncrypted(key.PrivateKey) {
+		private, err := k.crypter.Decrypt(key.PrivateKey, []byte(key.ID))
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Signing key %s can not be decrypted.", key.ID)
+		}
+		decrypted := *key
+		decrypted.PrivateKey = string(private)
+		key = &decrypted
+	}
+	return parseKey(key)
+}
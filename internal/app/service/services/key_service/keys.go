@@ -0,0 +1,120 @@
+package key_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const rsaKeySize = 2048
+
+//signingKey is a parsed stored key
+type signingKey struct {
+	model.SigningKey
+	signer crypto.Signer
+	method jwt.SigningMethod
+	jwk    model.JSONWebKey
+}
+
+func generateKeyID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate key ID.")
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+//generateKey creates key pair of algorithm and encodes private key as PKCS#8 PEM
+func generateKey(alg string) (string, error) {
+	var (
+		signer crypto.Signer
+		err    error
+	)
+	switch alg {
+	case model.SigningAlgRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, rsaKeySize)
+	case model.SigningAlgES256:
+		signer, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case model.SigningAlgEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return "", errors.ErrInvalidArgument.Newf("Unsupported signing algorithm %s.", alg)
+	}
+	if err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate signing key.")
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return "", errors.NoType.Wrap(err, "Err encode signing key.")
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})), nil
+}
+
+//parseKey decodes stored key and checks that it matches the algorithm
+func parseKey(key *model.SigningKey) (*signingKey, error) {
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, errors.ErrInvalidArgument.Newf("Signing key %s is not PEM encoded.", key.ID)
+	}
+	private, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Signing key %s is not a private key.", key.ID)
+	}
+	parsed := &signingKey{SigningKey: *key}
+	switch k := private.(type) {
+	case *rsa.PrivateKey:
+		parsed.signer, parsed.method = k, jwt.SigningMethodRS256
+		parsed.jwk = model.JSONWebKey{
+			Kty: "RSA",
+			N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+		}
+	case *ecdsa.PrivateKey:
+		parsed.signer, parsed.method = k, jwt.SigningMethodES256
+		size := (k.Curve.Params().BitSize + 7) / 8
+		parsed.jwk = model.JSONWebKey{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   base64.RawURLEncoding.EncodeToString(padBytes(k.X.Bytes(), size)),
+			Y:   base64.RawURLEncoding.EncodeToString(padBytes(k.Y.Bytes(), size)),
+		}
+	case ed25519.PrivateKey:
+		parsed.signer, parsed.method = k, jwt.SigningMethodEdDSA
+		parsed.jwk = model.JSONWebKey{
+			Kty: "OKP",
+			Crv: "Ed25519",
+			X:   base64.RawURLEncoding.EncodeToString(k.Public().(ed25519.PublicKey)),
+		}
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Signing key %s has unsupported type.", key.ID)
+	}
+	if parsed.method.Alg() != key.Algorithm {
+		return nil, errors.ErrInvalidArgument.Newf("Signing key %s does not match algorithm %s.", key.ID, key.Algorithm)
+	}
+	parsed.jwk.Use = "sig"
+	parsed.jwk.Kid = key.ID
+	parsed.jwk.Alg = key.Algorithm
+	return parsed, nil
+}
+
+//padBytes left-pads big-endian number to fixed size, RFC 7518 section 6.2.1.2
+func padBytes(b []byte, size int) []byte {
+	if len(b) >= size {
+		return b
+	}
+	padded := make([]byte, size)
+	copy(padded[size-len(b):], b)
+	return padded
+}
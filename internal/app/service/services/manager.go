@@ -1,31 +1,168 @@
 package services
 
 import (
+	cfg "auth-server/internal/app/config"
 	"auth-server/internal/app/service"
+	"auth-server/internal/app/service/services/client_service"
+	"auth-server/internal/app/service/services/group_service"
+	"auth-server/internal/app/service/services/key_service"
+	"auth-server/internal/app/service/services/oauth_service"
+	"auth-server/internal/app/service/services/rate_limit_service"
+	"auth-server/internal/app/service/services/scope_service"
+	"auth-server/internal/app/service/services/token_service"
 	"auth-server/internal/app/service/services/user_service"
 	"auth-server/internal/app/store"
+	"auth-server/internal/app/store/file_store"
+	"auth-server/internal/app/store/memory_store"
 	"auth-server/internal/app/utils/validators"
+	"auth-server/pkg/emailsender"
+	"auth-server/pkg/encryption"
 	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/passhash"
+	"context"
+	"strings"
 )
 
 type Manager struct {
-	User   service.UserService
-	Client service.ClientService
+	User      service.UserService
+	Client    service.ClientService
+	Token     service.TokenService
+	Key       service.KeyService
+	OAuth     service.OAuthService
+	Group     service.GroupService
+	Scope     service.ScopeService
+	RateLimit service.RateLimitService
 }
 
 //NewManager created a service manager and create services.
-func NewManager(store store.Store, uv validators.IUserValidator) (*Manager, error) {
+func NewManager(store store.Store, uv validators.IUserValidator, sender emailsender.IEmailSender, config *cfg.Config) (*Manager, error) {
 	if store == nil {
 		return nil, errors.ErrInvalidArgument.New("Store is nill.")
 	}
 	if uv == nil {
 		return nil, errors.ErrInvalidArgument.New("User validator is nill.")
 	}
+	if config == nil {
+		return nil, errors.ErrInvalidArgument.New("Config is nill.")
+	}
 	//Create services
-	userService, _ := user_service.New(store, uv)
+	keyService, err := NewKeyService(store, config)
+	if err != nil {
+		return nil, err
+	}
+	tokenService, err := token_service.New(store, keyService, config.JWTIssuer, config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	passwords, err := NewPasswords(config)
+	if err != nil {
+		return nil, err
+	}
+	//TOTP secrets are encrypted by the same key as signing keys
+	crypter, err := encryption.New(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	userService, err := user_service.New(store, uv, tokenService, passwords, sender, crypter)
+	if err != nil {
+		return nil, err
+	}
+	//client assertions may be addressed to issuer or to endpoints which authenticate clients
+	baseURL := strings.TrimRight(config.AppLink, "/")
+	clientService, _ := client_service.New(store, []string{
+		config.JWTIssuer,
+		baseURL + "/oauth/token",
+		baseURL + "/oauth/introspect",
+		baseURL + "/oauth/revoke",
+	}, config.InitialAccessToken)
+	groupService, _ := group_service.New(store)
+	scopeService, _ := scope_service.New(store)
+	rateLimitService, err := NewRateLimitService(store, config)
+	if err != nil {
+		return nil, err
+	}
+	oauthService, err := oauth_service.New(store, userService, clientService, tokenService,
+		config.AuthCodeTTL, config.DeviceCodeTTL, baseURL+"/oauth/device")
+	if err != nil {
+		return nil, err
+	}
 
 	return &Manager{
-		User: userService,
-		//Client: c,
+		User:      userService,
+		Client:    clientService,
+		Token:     tokenService,
+		Key:       keyService,
+		OAuth:     oauthService,
+		Group:     groupService,
+		Scope:     scopeService,
+		RateLimit: rateLimitService,
 	}, nil
 }
+
+//NewKeyService creates key service with repository selected by config.
+//Store may be nil if keys are kept on disk.
+func NewKeyService(store store.Store, config *cfg.Config) (*key_service.KeyService, error) {
+	if config.KeyOverlap < config.AccessTokenTTL {
+		return nil, errors.ErrInvalidArgument.New("Key overlap must be longer than access token TTL.")
+	}
+	//private keys are encrypted at rest
+	crypter, err := encryption.New(config.EncryptionKey)
+	if err != nil {
+		return nil, err
+	}
+	switch config.KeyStore {
+	case cfg.KeyStoreDisk:
+		repo, err := file_store.NewKeyRepo(config.KeyStorePath)
+		if err != nil {
+			return nil, err
+		}
+		return key_service.New(repo, crypter, config.SigningAlg, config.KeyRotationPeriod, config.KeyOverlap)
+	case cfg.KeyStoreMongo:
+		if store == nil {
+			return nil, errors.ErrInvalidArgument.New("Store is nill.")
+		}
+		return key_service.New(store.Key(), crypter, config.SigningAlg, config.KeyRotationPeriod, config.KeyOverlap)
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Unknown key store %s.", config.KeyStore)
+	}
+}
+
+//NewPasswords creates password hasher selected by config.
+//Hashes of the other algorithm are still verified and upgraded on login.
+func NewPasswords(config *cfg.Config) (*passhash.Passwords, error) {
+	//hashes with parameters above the bounds can't be verified
+	if config.Argon2Memory < 0 || config.Argon2Memory > passhash.MaxArgon2Memory ||
+		config.Argon2Iterations < 0 || config.Argon2Iterations > passhash.MaxArgon2Iterations ||
+		config.Argon2Parallelism < 0 || config.Argon2Parallelism > passhash.MaxArgon2Parallelism {
+		return nil, errors.ErrInvalidArgument.New("Invalid argon2 parameters.")
+	}
+	argon := passhash.NewArgon2id(uint32(config.Argon2Memory), uint32(config.Argon2Iterations), uint8(config.Argon2Parallelism))
+	bcrypt := passhash.NewBcrypt(config.BcryptCost)
+	switch config.PasswordHasher {
+	case cfg.PasswordHasherArgon2id:
+		return passhash.New(argon, bcrypt), nil
+	case cfg.PasswordHasherBcrypt:
+		return passhash.New(bcrypt, argon), nil
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Unknown password hasher %s.", config.PasswordHasher)
+	}
+}
+
+//NewRateLimitService creates rate limiter with bucket storage selected by config.
+//Memory buckets are counted by each instance, mongo buckets are shared by the cluster.
+func NewRateLimitService(store store.Store, config *cfg.Config) (*rate_limit_service.RateLimitService, error) {
+	policies, err := rate_limit_service.ParsePolicies(config.RateLimits)
+	if err != nil {
+		return nil, err
+	}
+	switch config.RateLimitStore {
+	case cfg.RateLimitStoreMemory:
+		repo := memory_store.NewRateLimitRepo(memory_store.DefaultMaxBuckets)
+		go repo.Run(context.Background(), memory_store.DefaultSweepInterval)
+		return rate_limit_service.New(repo, policies)
+	case cfg.RateLimitStoreMongo:
+		return rate_limit_service.New(store.RateLimit(), policies)
+	default:
+		return nil, errors.ErrInvalidArgument.Newf("Unknown rate limit store %s.", config.RateLimitStore)
+	}
+}
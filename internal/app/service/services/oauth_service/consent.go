@@ -0,0 +1,87 @@
+package oauth_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+//consentTTL is a time which user has to answer consent page
+const consentTTL = 10 * time.Minute
+
+//consentRequired checks that third-party client has no consent of user to all requested scopes
+func (o *OAuthService) consentRequired(ctx context.Context, client *model.Client, userID, scope string) (bool, error) {
+	if client.FirstParty {
+		return false, nil
+	}
+	consent, err := o.store.Consent().Find(ctx, userID, client.ID)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return true, nil
+		}
+		return false, err
+	}
+	return !model.ContainsScopes(strings.Join(consent.Scopes, " "), scope), nil
+}
+
+//DecideConsent approves or denies pending authorization code.
+//Approved scopes are remembered, so user isn't asked again.
+func (o *OAuthService) DecideConsent(ctx context.Context, code string, approve bool) (*model.AuthCode, error) {
+	if !approve {
+		return o.store.AuthCode().DeletePending(ctx, code)
+	}
+	authCode, err := o.store.AuthCode().Approve(ctx, code, time.Now().Add(o.codeTTL))
+	if err != nil {
+		return nil, err
+	}
+	err = o.store.Consent().Grant(ctx, authCode.UserID, authCode.ClientID, strings.Fields(authCode.Scope))
+	if err != nil {
+		return nil, err
+	}
+	return authCode, nil
+}
+
+//FindConsents returns the clients which user authorized
+func (o *OAuthService) FindConsents(ctx context.Context, userID string) ([]model.Consent, error) {
+	consents, err := o.store.Consent().FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range consents {
+		client, err := o.store.Client().FindById(ctx, consents[i].ClientID)
+		if err != nil {
+			if errors.GetType(err) == errors.ErrInvalidArgument {
+				//Client was deleted
+				continue
+			}
+			return nil, err
+		}
+		consents[i].ClientName = client.ClientName
+		consents[i].LogoURI = client.LogoURI
+	}
+	return consents, nil
+}
+
+//RevokeConsent removes consent of user and closes the user sessions of the client
+func (o *OAuthService) RevokeConsent(ctx context.Context, userID, clientID string) error {
+	err := o.store.Consent().Delete(ctx, userID, clientID)
+	if err != nil {
+		return err
+	}
+	sessions, err := o.userService.FindUserSessions(ctx, userID)
+	if err != nil {
+		return err
+	}
+	for _, v := range *sessions {
+		if v.ClientID != clientID {
+			continue
+		}
+		if err = o.userService.SignOut(ctx, userID, v.SessionID); err != nil {
+			log.Printf("Err in sign out of revoked client. Err: %s", err.Error())
+		}
+	}
+	return nil
+}
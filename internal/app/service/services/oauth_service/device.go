@@ -0,0 +1,194 @@
+package oauth_service
+
+import (
+	"auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/rand"
+	"math/big"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	//userCodeAlphabet has no vowels and similar looking chars, RFC 8628 section 6.1
+	userCodeAlphabet = "BCDFGHJKLMNPQRSTVWXZ"
+	userCodeLength   = 8
+	//devicePollInterval is a minimal interval of token polling, it grows by slowDownStep on each too frequent poll
+	devicePollInterval = 5 * time.Second
+	slowDownStep       = 5 * time.Second
+	userCodeAttempts   = 3
+)
+
+func generateUserCode() string {
+	code := make([]byte, userCodeLength)
+	max := big.NewInt(int64(len(userCodeAlphabet)))
+	for i := range code {
+		n, _ := rand.Int(rand.Reader, max)
+		code[i] = userCodeAlphabet[n.Int64()]
+	}
+	return string(code)
+}
+
+//NormalizeUserCode drops separators and case, so the code may be typed in any form
+func NormalizeUserCode(userCode string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		if strings.ContainsRune(userCodeAlphabet, r) {
+			return r
+		}
+		return -1
+	}, userCode)
+}
+
+//FormatUserCode splits user code by dash for readability
+func FormatUserCode(userCode string) string {
+	if len(userCode) != userCodeLength {
+		return userCode
+	}
+	return userCode[:userCodeLength/2] + "-" + userCode[userCodeLength/2:]
+}
+
+//AuthorizeDevice starts device authorization flow, RFC 8628 section 3.1.
+//The device descriptor of request is saved for the session created after approval.
+func (o *OAuthService) AuthorizeDevice(ctx context.Context, creds *model.ClientCredentials, scope string) (*model.DeviceAuthorizationResponce, error) {
+	client, err := o.authenticateClient(ctx, creds)
+	if err != nil {
+		return nil, err
+	}
+	if !client.HasGrantType(model.GrantTypeDeviceCode) {
+		return nil, model.NewOAuthError(model.OAuthErrUnauthorizedClient, "Device flow is not allowed for client.")
+	}
+	if len(scope) == 0 {
+		scope = client.Scope
+	}
+	if !model.ContainsScopes(client.Scope, scope) {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidScope, "Scope is not declared by client.")
+	}
+	device, _ := ctx.Value(config.ContextDeviceKey).(string)
+	ip, _ := ctx.Value(config.ContextIPKey).(string)
+	location, _ := ctx.Value(config.ContextLocationKey).(string)
+	deviceCode, err := generateAuthCode()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	auth := &model.DeviceAuthorization{
+		DeviceCode: deviceCode,
+		ClientID:   client.ID,
+		Scope:      scope,
+		Status:     model.DeviceAuthPending,
+		Device:     device,
+		IP:         ip,
+		Location:   location,
+		Interval:   devicePollInterval,
+		ExpIn:      now.Add(o.deviceCodeTTL),
+		CreatedAt:  now,
+	}
+	//user code is short, so collisions are possible
+	for attempt := 0; ; attempt++ {
+		auth.UserCode = generateUserCode()
+		err = o.store.DeviceCode().Create(ctx, auth)
+		if err == nil || errors.GetType(err) != errors.ErrDuplicateEntry || attempt == userCodeAttempts {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	complete, _ := url.Parse(o.verificationURI)
+	query := complete.Query()
+	query.Set("user_code", FormatUserCode(auth.UserCode))
+	complete.RawQuery = query.Encode()
+	return &model.DeviceAuthorizationResponce{
+		DeviceCode:              auth.DeviceCode,
+		UserCode:                FormatUserCode(auth.UserCode),
+		VerificationURI:         o.verificationURI,
+		VerificationURIComplete: complete.String(),
+		ExpiresIn:               int64(o.deviceCodeTTL.Seconds()),
+		Interval:                int64(auth.Interval.Seconds()),
+	}, nil
+}
+
+//FindDeviceAuthorization returns pending authorization and its client for verification page
+func (o *OAuthService) FindDeviceAuthorization(ctx context.Context, userCode string) (*model.DeviceAuthorization, *model.Client, error) {
+	auth, err := o.store.DeviceCode().FindByUserCode(ctx, NormalizeUserCode(userCode))
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := o.clientService.FindClientByID(ctx, auth.ClientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return auth, client, nil
+}
+
+//DecideDeviceAuthorization approves or denies device authorization by user
+func (o *OAuthService) DecideDeviceAuthorization(ctx context.Context, userCode, userID string, approve bool) error {
+	status := model.DeviceAuthDenied
+	if approve {
+		status = model.DeviceAuthApproved
+	}
+	return o.store.DeviceCode().Decide(ctx, NormalizeUserCode(userCode), status, userID)
+}
+
+//exchangeDeviceCode answers to device polling, RFC 8628 section 3.5
+func (o *OAuthService) exchangeDeviceCode(ctx context.Context, client *model.Client, req *model.TokenRequest) (*model.OAuthTokenResponce, error) {
+	if len(req.DeviceCode) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "device_code required.")
+	}
+	auth, err := o.store.DeviceCode().Poll(ctx, req.DeviceCode)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrExpiredToken, "Device code is expired or invalid.")
+		}
+		return nil, err
+	}
+	if auth.ClientID != client.ID {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "Device code was issued to another client.")
+	}
+	if auth.Status == model.DeviceAuthPending {
+		if !auth.LastPolledAt.IsZero() && time.Since(auth.LastPolledAt) < auth.Interval {
+			if err = o.store.DeviceCode().SlowDown(ctx, req.DeviceCode, auth.Interval+slowDownStep); err != nil {
+				return nil, err
+			}
+			return nil, model.NewOAuthError(model.OAuthErrSlowDown, "")
+		}
+		return nil, model.NewOAuthError(model.OAuthErrAuthorizationPending, "")
+	}
+	auth, err = o.store.DeviceCode().Consume(ctx, req.DeviceCode)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrExpiredToken, "Device code is already used.")
+		}
+		return nil, err
+	}
+	if auth.Status != model.DeviceAuthApproved {
+		return nil, model.NewOAuthError(model.OAuthErrAccessDenied, "User denied the authorization.")
+	}
+	fields := &store.UserFields{
+		UserName: true,
+		Email:    true,
+	}
+	user, err := o.userService.FindUserByID(ctx, auth.UserID, fields)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "User not found.")
+		}
+		return nil, err
+	}
+	//session describes the device which started the flow
+	ctx = context.WithValue(ctx, config.ContextDeviceKey, auth.Device)
+	ctx = context.WithValue(ctx, config.ContextIPKey, auth.IP)
+	ctx = context.WithValue(ctx, config.ContextLocationKey, auth.Location)
+	identity, err := o.userService.CreateIdentity(ctx, user, client, auth.Scope)
+	if err != nil {
+		return nil, err
+	}
+	return model.CreateOAuthTokenResponce(identity), nil
+}
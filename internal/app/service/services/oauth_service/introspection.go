@@ -0,0 +1,139 @@
+package oauth_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+)
+
+//Introspect returns the state of access or refresh token, RFC 7662.
+//Refresh tokens are introspected only by client which they were issued to.
+func (o *OAuthService) Introspect(ctx context.Context, clientID, token, tokenTypeHint string) (*model.IntrospectionResponce, error) {
+	if len(token) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "token required.")
+	}
+	introspectors := []func(ctx context.Context, clientID, token string) (*model.IntrospectionResponce, error){
+		o.introspectAccessToken,
+		o.introspectRefToken,
+	}
+	if tokenTypeHint == model.TokenTypeHintRefreshToken {
+		introspectors[0], introspectors[1] = introspectors[1], introspectors[0]
+	}
+	for _, introspect := range introspectors {
+		responce, err := introspect(ctx, clientID, token)
+		if err != nil {
+			return nil, err
+		}
+		if responce != nil {
+			return responce, nil
+		}
+	}
+	return &model.IntrospectionResponce{Active: false}, nil
+}
+
+func (o *OAuthService) introspectAccessToken(ctx context.Context, clientID, token string) (*model.IntrospectionResponce, error) {
+	claims, err := o.tokenService.ParseAccessToken(ctx, token)
+	if err != nil {
+		return nil, ignoreInvalid(err)
+	}
+	if claims.IsClientToken() {
+		_, err = o.clientService.FindClientByID(ctx, claims.ClientID)
+	} else {
+		err = o.userService.CheckSession(ctx, claims.SessionID)
+	}
+	if err != nil {
+		return nil, ignoreInvalid(err)
+	}
+	return &model.IntrospectionResponce{
+		Active:    true,
+		Scope:     claims.Scope,
+		ClientID:  claims.ClientID,
+		TokenType: model.TokenTypeHintAccessToken,
+		Exp:       claims.ExpIn.Unix(),
+		Iat:       claims.IssuedAt.Unix(),
+		Sub:       claims.Subject(),
+		Jti:       claims.TokenID,
+	}, nil
+}
+
+func (o *OAuthService) introspectRefToken(ctx context.Context, clientID, token string) (*model.IntrospectionResponce, error) {
+	refToken, err := o.store.Client().FindRefToken(ctx, clientID, "", token)
+	if err != nil {
+		return nil, ignoreInvalid(err)
+	}
+	if refToken.ExpIn.Before(time.Now()) {
+		return nil, nil
+	}
+	if err = o.userService.CheckSession(ctx, refToken.SessionID); err != nil {
+		return nil, ignoreInvalid(err)
+	}
+	return &model.IntrospectionResponce{
+		Active:    true,
+		Scope:     refToken.Scope,
+		ClientID:  clientID,
+		TokenType: model.TokenTypeHintRefreshToken,
+		Exp:       refToken.ExpIn.Unix(),
+		Iat:       refToken.CreatedAt.Unix(),
+		Sub:       refToken.UserID,
+	}, nil
+}
+
+//Revoke invalidates access or refresh token issued to client, RFC 7009.
+//Revocation of refresh token closes its session, so access tokens of the same grant are invalidated too.
+//Unknown tokens are not an error, so the client can't probe tokens.
+func (o *OAuthService) Revoke(ctx context.Context, clientID, token, tokenTypeHint string) error {
+	if len(token) == 0 {
+		return model.NewOAuthError(model.OAuthErrInvalidRequest, "token required.")
+	}
+	revokers := []func(ctx context.Context, clientID, token string) (bool, error){
+		o.revokeRefToken,
+		o.revokeAccessToken,
+	}
+	if tokenTypeHint == model.TokenTypeHintAccessToken {
+		revokers[0], revokers[1] = revokers[1], revokers[0]
+	}
+	for _, revoke := range revokers {
+		revoked, err := revoke(ctx, clientID, token)
+		if err != nil || revoked {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o *OAuthService) revokeAccessToken(ctx context.Context, clientID, token string) (bool, error) {
+	claims, err := o.tokenService.ParseAccessToken(ctx, token)
+	if err != nil {
+		return false, ignoreInvalid(err)
+	}
+	if claims.ClientID != clientID {
+		return false, model.NewOAuthError(model.OAuthErrUnauthorizedClient, "Token was issued to another client.")
+	}
+	return true, o.tokenService.RevokeAccessToken(ctx, claims)
+}
+
+func (o *OAuthService) revokeRefToken(ctx context.Context, clientID, token string) (bool, error) {
+	refToken, err := o.store.Client().FindRefToken(ctx, clientID, "", token)
+	if err != nil {
+		return false, ignoreInvalid(err)
+	}
+	err = o.store.Client().DeleteRefToken(ctx, clientID, refToken.SessionID, token)
+	if err != nil {
+		return false, ignoreInvalid(err)
+	}
+	//access tokens of the grant are checked against session, so closing it revokes them too
+	err = o.userService.SignOut(ctx, refToken.UserID, refToken.SessionID)
+	if err != nil {
+		return true, ignoreInvalid(err)
+	}
+	return true, nil
+}
+
+//ignoreInvalid drops the errors of invalid or unknown token
+func ignoreInvalid(err error) error {
+	if errors.GetType(err) == errors.ErrInvalidArgument {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,321 @@
+package oauth_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type OAuthService struct {
+	store         store.Store
+	userService   service.UserService
+	clientService service.ClientService
+	tokenService  service.TokenService
+	codeTTL       time.Duration
+	//device flow
+	deviceCodeTTL   time.Duration
+	verificationURI string
+}
+
+func New(store store.Store, userService service.UserService, clientService service.ClientService,
+	tokenService service.TokenService, codeTTL, deviceCodeTTL time.Duration, verificationURI string) (*OAuthService, error) {
+	if codeTTL <= 0 || deviceCodeTTL <= 0 {
+		return nil, errors.ErrInvalidArgument.New("Authorization code TTL must be positive.")
+	}
+	if _, err := url.Parse(verificationURI); err != nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid verification URI.")
+	}
+	os := OAuthService{
+		store:           store,
+		userService:     userService,
+		clientService:   clientService,
+		tokenService:    tokenService,
+		codeTTL:         codeTTL,
+		deviceCodeTTL:   deviceCodeTTL,
+		verificationURI: verificationURI,
+	}
+	return &os, nil
+}
+
+func generateAuthCode() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate authorization code.")
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+//ValidateAuthorizeRequest checks the authorization request.
+//The client is returned when client and redirect URI are valid, even if other parameters are not,
+//so the error may be sent back to redirect URI.
+func (o *OAuthService) ValidateAuthorizeRequest(ctx context.Context, req *model.AuthorizeRequest) (*model.Client, error) {
+	if len(req.ClientID) == 0 || len(req.RedirectURI) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "client_id and redirect_uri required.")
+	}
+	client, err := o.store.Client().FindById(ctx, req.ClientID)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "Unknown client.")
+		}
+		return nil, err
+	}
+	if !client.HasRedirectURI(req.RedirectURI) {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "Invalid redirect_uri.")
+	}
+	if req.ResponseType != "code" {
+		return client, model.NewOAuthError(model.OAuthErrUnsupportedRespType, "Only code response type supported.")
+	}
+	if len(req.CodeChallenge) == 0 {
+		return client, model.NewOAuthError(model.OAuthErrInvalidRequest, "code_challenge required.")
+	}
+	if req.CodeChallengeMethod != model.CodeChallengeMethodS256 {
+		return client, model.NewOAuthError(model.OAuthErrInvalidRequest, "Only S256 code_challenge_method supported.")
+	}
+	//Client requests its declared scopes by default
+	if len(req.Scope) == 0 {
+		req.Scope = client.Scope
+	}
+	if !model.ContainsScopes(client.Scope, req.Scope) {
+		return client, model.NewOAuthError(model.OAuthErrInvalidScope, "Scope is not declared by client.")
+	}
+	return client, nil
+}
+
+//CreateAuthCode issues single-use authorization code for authenticated user.
+//If user has to give consent to client, the code is pending till DecideConsent.
+func (o *OAuthService) CreateAuthCode(ctx context.Context, client *model.Client, req *model.AuthorizeRequest, userID string) (string, bool, error) {
+	pending, err := o.consentRequired(ctx, client, userID, req.Scope)
+	if err != nil {
+		return "", false, err
+	}
+	expIn := time.Now().Add(o.codeTTL)
+	if pending {
+		expIn = time.Now().Add(consentTTL)
+	}
+	value, err := generateAuthCode()
+	if err != nil {
+		return "", false, err
+	}
+	code := &model.AuthCode{
+		Code:                value,
+		ClientID:            req.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               req.Scope,
+		Nonce:               req.Nonce,
+		AuthTime:            time.Now(),
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		Pending:             pending,
+		ExpIn:               expIn,
+		CreatedAt:           time.Now(),
+	}
+	err = o.store.AuthCode().Create(ctx, code)
+	if err != nil {
+		return "", false, err
+	}
+	return code.Code, pending, nil
+}
+
+func (o *OAuthService) Token(ctx context.Context, req *model.TokenRequest) (*model.OAuthTokenResponce, error) {
+	var grant func(ctx context.Context, client *model.Client, req *model.TokenRequest) (*model.OAuthTokenResponce, error)
+	switch req.GrantType {
+	case model.GrantTypeAuthorizationCode:
+		grant = o.exchangeAuthCode
+	case model.GrantTypeRefreshToken:
+		grant = o.refresh
+	case model.GrantTypeClientCredentials:
+		grant = o.clientCredentials
+	case model.GrantTypeDeviceCode:
+		grant = o.exchangeDeviceCode
+	default:
+		return nil, model.NewOAuthError(model.OAuthErrUnsupportedGrantType, "")
+	}
+	client, err := o.authenticateClient(ctx, &req.Credentials)
+	if err != nil {
+		return nil, err
+	}
+	if !client.HasGrantType(req.GrantType) {
+		return nil, model.NewOAuthError(model.OAuthErrUnauthorizedClient, "Grant type is not allowed for client.")
+	}
+	return grant(ctx, client, req)
+}
+
+//authenticateClient resolves the client of token request.
+//Confidential clients must authenticate, public clients send only client_id.
+func (o *OAuthService) authenticateClient(ctx context.Context, creds *model.ClientCredentials) (*model.Client, error) {
+	if len(creds.Secret) > 0 || len(creds.Assertion) > 0 {
+		client, err := o.clientService.AuthenticateClient(ctx, creds)
+		if err != nil {
+			if errors.GetType(err) == errors.ErrUnauthorized {
+				return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "Client authentication failed.")
+			}
+			return nil, err
+		}
+		return client, nil
+	}
+	if len(creds.ClientID) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "client_id required.")
+	}
+	client, err := o.clientService.FindClientByID(ctx, creds.ClientID)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "Unknown client.")
+		}
+		return nil, err
+	}
+	if client.IsConfidential() {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidClient, "Client authentication required.")
+	}
+	return client, nil
+}
+
+func (o *OAuthService) exchangeAuthCode(ctx context.Context, client *model.Client, req *model.TokenRequest) (*model.OAuthTokenResponce, error) {
+	if len(req.Code) == 0 || len(req.CodeVerifier) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "code and code_verifier required.")
+	}
+	code, err := o.store.AuthCode().Consume(ctx, req.Code)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "Invalid or expired code.")
+		}
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "Code was issued to another client or redirect_uri.")
+	}
+	if !verifyCodeChallenge(req.CodeVerifier, code.CodeChallenge, code.CodeChallengeMethod) {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "Invalid code_verifier.")
+	}
+	fields := &store.UserFields{
+		UserName:       true,
+		Email:          true,
+		EmailConfirmed: true,
+	}
+	user, err := o.userService.FindUserByID(ctx, code.UserID, fields)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "User not found.")
+		}
+		return nil, err
+	}
+	identity, err := o.userService.CreateIdentity(ctx, user, client, code.Scope)
+	if err != nil {
+		log.Printf("Err in exchange authorization code. Err: %s", err.Error())
+		return nil, err
+	}
+	responce := model.CreateOAuthTokenResponce(identity)
+	if model.HasScope(code.Scope, model.ScopeOpenID) {
+		claims := &model.IDClaims{
+			UserID:   user.ID,
+			ClientID: code.ClientID,
+			Nonce:    code.Nonce,
+			AuthTime: code.AuthTime,
+		}
+		if model.HasScope(code.Scope, model.ScopeEmail) {
+			claims.Email = user.Email
+			claims.EmailVerified = user.EmailConfirmed
+		}
+		responce.IDToken, err = o.tokenService.GenerateIDToken(ctx, claims)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return responce, nil
+}
+
+func (o *OAuthService) refresh(ctx context.Context, client *model.Client, req *model.TokenRequest) (*model.OAuthTokenResponce, error) {
+	if len(req.RefToken) == 0 {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidRequest, "refresh_token required.")
+	}
+	identity, err := o.userService.UpdateRefToken(ctx, client.ID, req.RefToken)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, model.NewOAuthError(model.OAuthErrInvalidGrant, "Invalid refresh token.")
+		}
+		return nil, err
+	}
+	return model.CreateOAuthTokenResponce(identity), nil
+}
+
+//clientCredentials issues access token to service account itself, no refresh token is issued, RFC 6749 section 4.4
+func (o *OAuthService) clientCredentials(ctx context.Context, client *model.Client, req *model.TokenRequest) (*model.OAuthTokenResponce, error) {
+	if !client.IsConfidential() {
+		return nil, model.NewOAuthError(model.OAuthErrUnauthorizedClient, "Public client can't use client_credentials.")
+	}
+	scope := req.Scope
+	if len(scope) == 0 {
+		scope = client.Scope
+	}
+	if !model.ContainsScopes(client.Scope, scope) {
+		return nil, model.NewOAuthError(model.OAuthErrInvalidScope, "Scope is not allowed for client.")
+	}
+	claims := &model.AccessClaims{
+		ClientID:    client.ID,
+		Scope:       scope,
+		Roles:       client.Roles,
+		Permissions: client.Permissions(client.Roles),
+	}
+	token, err := o.tokenService.GenerateAccessToken(ctx, client, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &model.OAuthTokenResponce{
+		AccessToken: token.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(token.ExpIn).Seconds()),
+		Scope:       scope,
+	}, nil
+}
+
+//UserInfo returns claims about user released by scopes of access token, OpenID Connect Core 1.0 section 5.3
+func (o *OAuthService) UserInfo(ctx context.Context, claims *model.AccessClaims) (map[string]interface{}, error) {
+	if !model.HasScope(claims.Scope, model.ScopeOpenID) {
+		return nil, errors.ErrUnauthorized.New("Token has no openid scope.")
+	}
+	fields := &store.UserFields{
+		UserName:       true,
+		Email:          true,
+		EmailConfirmed: true,
+		UserInfo:       true,
+	}
+	user, err := o.userService.FindUserByID(ctx, claims.UserID, fields)
+	if err != nil {
+		return nil, err
+	}
+	info := map[string]interface{}{
+		"sub": user.ID,
+	}
+	if model.HasScope(claims.Scope, model.ScopeProfile) {
+		info["preferred_username"] = user.UserName
+		//name parts in order of full name
+		parts := []struct{ claim, key string }{
+			{"given_name", store.UserInfoFirstName},
+			{"middle_name", store.UserInfoMidName},
+			{"family_name", store.UserInfoLastName},
+		}
+		names := []string{}
+		for _, part := range parts {
+			if v := user.UserInfo[part.key]; len(v) > 0 {
+				info[part.claim] = v
+				names = append(names, v)
+			}
+		}
+		if len(names) > 0 {
+			info["name"] = strings.Join(names, " ")
+		}
+	}
+	if model.HasScope(claims.Scope, model.ScopeEmail) {
+		info["email"] = user.Email
+		info["email_verified"] = user.EmailConfirmed
+	}
+	return info, nil
+}
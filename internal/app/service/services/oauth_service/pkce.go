@@ -0,0 +1,22 @@
+package oauth_service
+
+import (
+	"auth-server/internal/app/model"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"regexp"
+)
+
+//codeVerifierPattern is a format of PKCE code verifier, RFC 7636 section 4.1
+var codeVerifierPattern = regexp.MustCompile(`^[A-Za-z0-9\-._~]{43,128}$`)
+
+//verifyCodeChallenge checks PKCE code verifier against the challenge sent in authorization request
+func verifyCodeChallenge(verifier, challenge, method string) bool {
+	if method != model.CodeChallengeMethodS256 || !codeVerifierPattern.MatchString(verifier) {
+		return false
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,139 @@
+package rate_limit_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type RateLimitService struct {
+	repo     store.RateLimitRepository
+	policies map[string]model.RateLimitPolicy
+}
+
+func New(repo store.RateLimitRepository, policies []model.RateLimitPolicy) (*RateLimitService, error) {
+	if repo == nil {
+		return nil, errors.ErrInvalidArgument.New("Rate limit repository is nill.")
+	}
+	rs := RateLimitService{
+		repo:     repo,
+		policies: make(map[string]model.RateLimitPolicy, len(policies)),
+	}
+	for _, policy := range policies {
+		rs.policies[policy.Route] = policy
+	}
+	return &rs, nil
+}
+
+//ParsePolicies parses policies separated by ";", each one is "route=limit/period[,burst][@key+key]".
+//Route is a mux path template or "*" for routes without own policy, keys are ip, client and user.
+//For example "/users/register=10/1h,3@ip" allows 3 registrations at once and 10 per hour from one IP.
+func ParsePolicies(value string) ([]model.RateLimitPolicy, error) {
+	var policies []model.RateLimitPolicy
+	for _, entry := range strings.Split(value, ";") {
+		entry = strings.TrimSpace(entry)
+		if len(entry) == 0 {
+			continue
+		}
+		policy, err := parsePolicy(entry)
+		if err != nil {
+			return nil, err
+		}
+		policies = append(policies, *policy)
+	}
+	return policies, nil
+}
+
+func parsePolicy(entry string) (*model.RateLimitPolicy, error) {
+	invalid := errors.ErrInvalidArgument.Newf("Invalid rate limit policy %s.", entry)
+	eq := strings.LastIndex(entry, "=")
+	if eq <= 0 {
+		return nil, invalid
+	}
+	policy := &model.RateLimitPolicy{
+		Route: strings.TrimSpace(entry[:eq]),
+		Keys:  []string{model.RateLimitByIP},
+	}
+	spec := entry[eq+1:]
+	if at := strings.Index(spec, "@"); at >= 0 {
+		policy.Keys = strings.Split(spec[at+1:], "+")
+		spec = spec[:at]
+		for _, key := range policy.Keys {
+			if key != model.RateLimitByIP && key != model.RateLimitByClient && key != model.RateLimitByUser {
+				return nil, invalid
+			}
+		}
+	}
+	var err error
+	if comma := strings.Index(spec, ","); comma >= 0 {
+		if policy.Burst, err = strconv.Atoi(spec[comma+1:]); err != nil || policy.Burst <= 0 {
+			return nil, invalid
+		}
+		spec = spec[:comma]
+	}
+	parts := strings.Split(spec, "/")
+	if len(parts) != 2 {
+		return nil, invalid
+	}
+	if policy.Limit, err = strconv.Atoi(parts[0]); err != nil || policy.Limit <= 0 {
+		return nil, invalid
+	}
+	if policy.Period, err = time.ParseDuration(parts[1]); err != nil || policy.Period <= 0 {
+		return nil, invalid
+	}
+	if policy.Burst == 0 {
+		policy.Burst = policy.Limit
+	}
+	return policy, nil
+}
+
+//policy returns policy of route or default one, it returns false if route isn't limited
+func (r *RateLimitService) policy(route string) (model.RateLimitPolicy, bool) {
+	if policy, ok := r.policies[route]; ok {
+		return policy, true
+	}
+	policy, ok := r.policies[model.RateLimitDefaultRoute]
+	return policy, ok
+}
+
+//Allow takes token from bucket of each key of route policy, request is rejected if any bucket is empty.
+//Keys without value aren't limited. Result is nil if route has no policy.
+func (r *RateLimitService) Allow(ctx context.Context, route string, keys map[string]string) (*model.RateLimitResult, error) {
+	policy, ok := r.policy(route)
+	if !ok {
+		return nil, nil
+	}
+	rate := policy.Rate()
+	var result *model.RateLimitResult
+	for _, kind := range policy.Keys {
+		value := keys[kind]
+		if len(value) == 0 {
+			continue
+		}
+		bucket, allowed, err := r.repo.Take(ctx, policy.Route+"|"+kind+"|"+value, rate, policy.Burst)
+		if err != nil {
+			log.Printf("Err in take rate limit token of %s %s. Err: %s", kind, value, err.Error())
+			return nil, err
+		}
+		current := &model.RateLimitResult{
+			Allowed:    allowed,
+			Limit:      policy.Burst,
+			Quota:      policy.Limit,
+			Period:     policy.Period,
+			Remaining:  int(bucket.Tokens),
+			Reset:      bucket.FullIn(rate, policy.Burst),
+			RetryAfter: bucket.NextIn(rate),
+		}
+		//The most exhausted bucket is reported
+		if result == nil || (result.Allowed && !current.Allowed) ||
+			(result.Allowed == current.Allowed && current.Remaining < result.Remaining) {
+			result = current
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,50 @@
+package rate_limit_service
+
+import (
+	"auth-server/internal/app/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParsePolicies(t *testing.T) {
+	policies, err := ParsePolicies(" /users/register=10/1h,3@ip ; /oauth/token=100/1m@client+user;*=600/1m; ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.RateLimitPolicy{
+		{Route: "/users/register", Limit: 10, Period: time.Hour, Burst: 3, Keys: []string{model.RateLimitByIP}},
+		{Route: "/oauth/token", Limit: 100, Period: time.Minute, Burst: 100,
+			Keys: []string{model.RateLimitByClient, model.RateLimitByUser}},
+		{Route: model.RateLimitDefaultRoute, Limit: 600, Period: time.Minute, Burst: 600, Keys: []string{model.RateLimitByIP}},
+	}
+	if !reflect.DeepEqual(policies, want) {
+		t.Fatalf("got %+v, want %+v", policies, want)
+	}
+	if policies, err = ParsePolicies(""); err != nil || len(policies) != 0 {
+		t.Fatalf("empty value: got %v, err %v", policies, err)
+	}
+}
+
+func TestParseInvalidPolicies(t *testing.T) {
+	for _, value := range []string{
+		"/users",
+		"=10/1m",
+		"/users=10",
+		"/users=10/1m/1s",
+		"/users=0/1m",
+		"/users=-1/1m",
+		"/users=x/1m",
+		"/users=10/0s",
+		"/users=10/minute",
+		"/users=10/1m,0",
+		"/users=10/1m,x",
+		"/users=10/1m@session",
+		"/users=10/1m@ip+",
+		"/users=10/1m;/oauth",
+	} {
+		if _, err := ParsePolicies(value); err == nil {
+			t.Errorf("policy %q parsed", value)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package scope_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"strings"
+)
+
+type ScopeService struct {
+	store store.Store
+}
+
+func New(store store.Store) (*ScopeService, error) {
+	ss := ScopeService{
+		store: store,
+	}
+	return &ss, nil
+}
+
+//validScopeName checks scope-token syntax, RFC 6749 section 3.3
+func validScopeName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ScopeService) CreateScope(ctx context.Context, scope *model.Scope) error {
+	if scope == nil || !validScopeName(scope.Name) {
+		return errors.ErrInvalidArgument.New("Invalid scope name.")
+	}
+	return s.store.Scope().Create(ctx, scope)
+}
+
+func (s *ScopeService) FindScopes(ctx context.Context) ([]model.Scope, error) {
+	return s.store.Scope().FindAll(ctx)
+}
+
+func (s *ScopeService) UpdateScope(ctx context.Context, scope *model.Scope) error {
+	if scope == nil || len(scope.Name) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid scope name.")
+	}
+	return s.store.Scope().Update(ctx, scope)
+}
+
+//DeleteScope removes scope from registry, clients which declare it must be updated before
+func (s *ScopeService) DeleteScope(ctx context.Context, name string) error {
+	return s.store.Scope().Delete(ctx, name)
+}
+
+//DescribeScopes returns registered descriptions of space-delimited scopes in the same order
+func (s *ScopeService) DescribeScopes(ctx context.Context, scope string) ([]model.Scope, error) {
+	names := strings.Fields(scope)
+	registered, err := s.store.Scope().FindByNames(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	descriptions := make(map[string]string)
+	for _, v := range registered {
+		descriptions[v.Name] = v.Description
+	}
+	scopes := make([]model.Scope, 0, len(names))
+	for _, v := range names {
+		scopes = append(scopes, model.Scope{
+			Name:        v,
+			Description: descriptions[v],
+		})
+	}
+	return scopes, nil
+}
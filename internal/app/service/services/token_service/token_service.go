@@ -0,0 +1,272 @@
+package token_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/service"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+//accessClaims represent the payload of access token
+type accessClaims struct {
+	jwt.RegisteredClaims
+	SessionID   string   `json:"sid,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+//idClaims represent the payload of OpenID Connect ID token
+type idClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce,omitempty"`
+	AuthTime      int64  `json:"auth_time,omitempty"`
+	Email         string `json:"email,omitempty"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+}
+
+//revokedCacheTTL is how long the denylist is kept in memory, so revocation by other instance is seen in this time
+const revokedCacheTTL = 30 * time.Second
+
+//revokedGracePeriod is how long the last loaded denylist is still served after TTL when the store can't be read
+const revokedGracePeriod = 5 * time.Minute
+
+//revokedLoadTimeout limits one read of the denylist from the store
+const revokedLoadTimeout = 10 * time.Second
+
+type TokenService struct {
+	store      store.Store
+	keyService service.KeyService
+	issuer     string
+	tokenTTL   time.Duration
+
+	mu        sync.RWMutex
+	revoked   map[string]time.Time
+	revokedAt time.Time
+	//refreshDone is closed when the running refresh of denylist finishes, it is nil if no refresh runs
+	refreshDone chan struct{}
+}
+
+func New(store store.Store, keyService service.KeyService, issuer string, tokenTTL time.Duration) (*TokenService, error) {
+	if keyService == nil {
+		return nil, errors.ErrInvalidArgument.New("Key service is nill.")
+	}
+	if tokenTTL <= 0 {
+		return nil, errors.ErrInvalidArgument.New("Access token TTL must be positive.")
+	}
+	return &TokenService{
+		store:      store,
+		keyService: keyService,
+		issuer:     issuer,
+		tokenTTL:   tokenTTL,
+	}, nil
+}
+
+func generateTokenID() (string, error) {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate token ID.")
+	}
+	return hex.EncodeToString(bytes), nil
+}
+
+//GenerateAccessToken signs user token, or client token if claims have no session
+func (t *TokenService) GenerateAccessToken(ctx context.Context, client *model.Client, claims *model.AccessClaims) (*model.Token, error) {
+	if claims == nil || len(claims.ClientID) == 0 || (len(claims.UserID) == 0) != claims.IsClientToken() {
+		return nil, errors.ErrInvalidArgument.New("Invalid token claims.")
+	}
+	if client == nil || client.ID != claims.ClientID {
+		return nil, errors.ErrInvalidArgument.New("Invalid token client.")
+	}
+	tokenID, err := generateTokenID()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims.TokenID = tokenID
+	claims.IssuedAt = now
+	claims.ExpIn = now.Add(client.AccessTokenTTL(t.tokenTTL))
+
+	payload := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        claims.TokenID,
+			Issuer:    t.issuer,
+			Subject:   claims.Subject(),
+			Audience:  jwt.ClaimStrings{claims.ClientID},
+			IssuedAt:  jwt.NewNumericDate(claims.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(claims.ExpIn),
+		},
+		SessionID:   claims.SessionID,
+		Scope:       claims.Scope,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+	}
+	token, err := t.keyService.Sign(ctx, payload)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Token{
+		ExpIn: claims.ExpIn,
+		Token: token,
+	}, nil
+}
+
+func (t *TokenService) ParseAccessToken(ctx context.Context, token string) (*model.AccessClaims, error) {
+	payload := &accessClaims{}
+	err := t.keyService.Parse(ctx, token, payload)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid access token.")
+	}
+	if !payload.VerifyIssuer(t.issuer, true) || len(payload.Audience) == 0 ||
+		payload.ExpiresAt == nil || payload.IssuedAt == nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid access token.")
+	}
+	revoked, err := t.isRevoked(ctx, payload.ID)
+	if err != nil {
+		return nil, err
+	}
+	if revoked {
+		return nil, errors.ErrInvalidArgument.New("Access token revoked.")
+	}
+	claims := &model.AccessClaims{
+		TokenID:     payload.ID,
+		UserID:      payload.Subject,
+		ClientID:    payload.Audience[0],
+		SessionID:   payload.SessionID,
+		Scope:       payload.Scope,
+		Roles:       payload.Roles,
+		Permissions: payload.Permissions,
+		IssuedAt:    payload.IssuedAt.Time,
+		ExpIn:       payload.ExpiresAt.Time,
+	}
+	//subject of client token is the client
+	if claims.IsClientToken() {
+		claims.UserID = ""
+	}
+	return claims, nil
+}
+
+func (t *TokenService) RevokeAccessToken(ctx context.Context, claims *model.AccessClaims) error {
+	if claims == nil || len(claims.TokenID) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid token claims.")
+	}
+	err := t.store.RevokedToken().Create(ctx, claims.TokenID, claims.ExpIn)
+	if err != nil && errors.GetType(err) != errors.ErrDuplicateEntry {
+		return err
+	}
+	t.mu.Lock()
+	if t.revoked != nil {
+		t.revoked[claims.TokenID] = claims.ExpIn
+	}
+	t.mu.Unlock()
+	return nil
+}
+
+//isRevoked checks token ID in cached denylist, so authenticated requests don't read the store every time.
+//Stale denylist is refreshed by one goroutine and served meanwhile, until the grace period is over.
+func (t *TokenService) isRevoked(ctx context.Context, tokenID string) (bool, error) {
+	t.mu.RLock()
+	fresh := t.revoked != nil && time.Since(t.revokedAt) < revokedCacheTTL
+	_, revoked := t.revoked[tokenID]
+	t.mu.RUnlock()
+	if fresh {
+		return revoked, nil
+	}
+
+	t.mu.Lock()
+	done := t.refreshDone
+	if done == nil {
+		done = make(chan struct{})
+		t.refreshDone = done
+		go t.refreshRevoked(done)
+	}
+	usable := t.revoked != nil && time.Since(t.revokedAt) < revokedCacheTTL+revokedGracePeriod
+	_, revoked = t.revoked[tokenID]
+	t.mu.Unlock()
+	if usable {
+		return revoked, nil
+	}
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		return false, errors.NoType.Wrap(ctx.Err(), "Err revoked tokens loading")
+	}
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.revoked == nil || time.Since(t.revokedAt) >= revokedCacheTTL+revokedGracePeriod {
+		return false, errors.NoType.New("Revoked tokens can't be loaded.")
+	}
+	_, revoked = t.revoked[tokenID]
+	return revoked, nil
+}
+
+//refreshRevoked loads denylist from the store and closes done, previous denylist is kept if loading fails
+func (t *TokenService) refreshRevoked(done chan struct{}) {
+	defer close(done)
+	ctx, cancel := context.WithTimeout(context.Background(), revokedLoadTimeout)
+	defer cancel()
+	tokens, err := t.store.RevokedToken().FindActive(ctx)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.refreshDone = nil
+	if err != nil {
+		log.Println("Err revoked tokens refresh:", err)
+		return
+	}
+	if tokens == nil {
+		tokens = map[string]time.Time{}
+	}
+	now := time.Now()
+	//tokens revoked by this instance while loading may be missing in the result
+	for id, expIn := range t.revoked {
+		if _, ok := tokens[id]; !ok && expIn.After(now) {
+			tokens[id] = expIn
+		}
+	}
+	t.revoked = tokens
+	t.revokedAt = now
+}
+
+//GenerateIDToken signs ID token by active key, so relying parties verify it with JWKS
+func (t *TokenService) GenerateIDToken(ctx context.Context, claims *model.IDClaims) (string, error) {
+	if claims == nil || len(claims.UserID) == 0 || len(claims.ClientID) == 0 {
+		return "", errors.ErrInvalidArgument.New("Invalid token claims.")
+	}
+	now := time.Now()
+	claims.IssuedAt = now
+	claims.ExpIn = now.Add(t.tokenTTL)
+
+	payload := idClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    t.issuer,
+			Subject:   claims.UserID,
+			Audience:  jwt.ClaimStrings{claims.ClientID},
+			IssuedAt:  jwt.NewNumericDate(claims.IssuedAt),
+			ExpiresAt: jwt.NewNumericDate(claims.ExpIn),
+		},
+		Nonce: claims.Nonce,
+		Email: claims.Email,
+	}
+	if !claims.AuthTime.IsZero() {
+		payload.AuthTime = claims.AuthTime.Unix()
+	}
+	if len(claims.Email) > 0 {
+		payload.EmailVerified = &claims.EmailVerified
+	}
+	return t.keyService.Sign(ctx, payload)
+}
+
+//PublicKeys returns public keys for verification of tokens
+func (t *TokenService) PublicKeys(ctx context.Context) (*model.JSONWebKeySet, error) {
+	return t.keyService.PublicKeys(ctx)
+}
@@ -0,0 +1,131 @@
+package token_service
+
+import (
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type testStore struct {
+	store.Store
+	revoked *testRevokedTokenRepo
+}
+
+func (s *testStore) RevokedToken() store.RevokedTokenRepository {
+	return s.revoked
+}
+
+//testRevokedTokenRepo blocks FindActive until release is closed and fails it while failing is set
+type testRevokedTokenRepo struct {
+	tokens  map[string]time.Time
+	release chan struct{}
+	failing int32
+	loads   int32
+}
+
+func (r *testRevokedTokenRepo) Create(ctx context.Context, tokenID string, expIn time.Time) error {
+	return nil
+}
+
+func (r *testRevokedTokenRepo) FindActive(ctx context.Context) (map[string]time.Time, error) {
+	atomic.AddInt32(&r.loads, 1)
+	if r.release != nil {
+		<-r.release
+	}
+	if atomic.LoadInt32(&r.failing) == 1 {
+		return nil, errors.NoType.New("store is down")
+	}
+	tokens := make(map[string]time.Time, len(r.tokens))
+	for id, expIn := range r.tokens {
+		tokens[id] = expIn
+	}
+	return tokens, nil
+}
+
+func newTestTokenService(repo *testRevokedTokenRepo) *TokenService {
+	return &TokenService{store: &testStore{revoked: repo}, tokenTTL: time.Minute}
+}
+
+func TestIsRevokedLoadsOnce(t *testing.T) {
+	repo := &testRevokedTokenRepo{
+		tokens:  map[string]time.Time{"revoked": time.Now().Add(time.Hour)},
+		release: make(chan struct{}),
+	}
+	service := newTestTokenService(repo)
+
+	var wg sync.WaitGroup
+	results := make([]bool, 20)
+	errs := make([]error, len(results))
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = service.isRevoked(context.Background(), "revoked")
+		}(i)
+	}
+	//let all requests wait for the first load
+	time.Sleep(50 * time.Millisecond)
+	close(repo.release)
+	wg.Wait()
+
+	for i := range results {
+		if errs[i] != nil || !results[i] {
+			t.Fatalf("request %d: got %v, err %v", i, results[i], errs[i])
+		}
+	}
+	if loads := atomic.LoadInt32(&repo.loads); loads != 1 {
+		t.Fatalf("denylist loaded %d times", loads)
+	}
+}
+
+func TestIsRevokedServesStaleOnFailure(t *testing.T) {
+	repo := &testRevokedTokenRepo{tokens: map[string]time.Time{"revoked": time.Now().Add(time.Hour)}}
+	service := newTestTokenService(repo)
+	if _, err := service.isRevoked(context.Background(), "revoked"); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&repo.failing, 1)
+
+	//within the grace period the last loaded denylist is served
+	service.mu.Lock()
+	service.revokedAt = time.Now().Add(-revokedCacheTTL)
+	service.mu.Unlock()
+	if revoked, err := service.isRevoked(context.Background(), "revoked"); err != nil || !revoked {
+		t.Fatalf("within grace period: got %v, err %v", revoked, err)
+	}
+
+	//after the grace period the denylist isn't trusted anymore
+	service.mu.Lock()
+	service.revokedAt = time.Now().Add(-revokedCacheTTL - revokedGracePeriod)
+	service.mu.Unlock()
+	if _, err := service.isRevoked(context.Background(), "revoked"); err == nil {
+		t.Fatal("expired denylist served")
+	}
+}
+
+func TestIsRevokedKeepsLocalRevocations(t *testing.T) {
+	repo := &testRevokedTokenRepo{tokens: map[string]time.Time{}}
+	service := newTestTokenService(repo)
+	if _, err := service.isRevoked(context.Background(), "local"); err != nil {
+		t.Fatal(err)
+	}
+	//revocation made while loading isn't in the store result yet
+	service.mu.Lock()
+	service.revoked["local"] = time.Now().Add(time.Hour)
+	service.revokedAt = time.Now().Add(-revokedCacheTTL)
+	service.mu.Unlock()
+
+	done := make(chan struct{})
+	service.mu.Lock()
+	service.refreshDone = done
+	service.mu.Unlock()
+	service.refreshRevoked(done)
+
+	if revoked, err := service.isRevoked(context.Background(), "local"); err != nil || !revoked {
+		t.Fatalf("got %v, err %v", revoked, err)
+	}
+}
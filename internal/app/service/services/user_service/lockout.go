@@ -0,0 +1,135 @@
+package user_service
+
+import (
+	"auth-server/config/filePath"
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	"bytes"
+	"context"
+	"html/template"
+	"log"
+	"time"
+)
+
+//loginFreeFailures is a number of failed logins of account which aren't delayed, typos shouldn't block user
+const loginFreeFailures = 3
+
+func accountThrottle() *model.LoginThrottle {
+	return &model.LoginThrottle{
+		Free:        loginFreeFailures,
+		Threshold:   cfg.Cfg.LoginLockoutThreshold,
+		BackoffBase: cfg.Cfg.LoginBackoffBase,
+		Lockout:     cfg.Cfg.LoginLockoutDuration,
+	}
+}
+
+//ipThrottle lets many users behind one address to mistype
+func ipThrottle() *model.LoginThrottle {
+	return &model.LoginThrottle{
+		Free:        cfg.Cfg.LoginIPThreshold / 2,
+		Threshold:   cfg.Cfg.LoginIPThreshold,
+		BackoffBase: cfg.Cfg.LoginBackoffBase,
+		Lockout:     cfg.Cfg.LoginLockoutDuration,
+	}
+}
+
+func accountAttemptsKey(userID string) string {
+	return "user:" + userID
+}
+
+//mfaAttemptsKey counts wrong second factor codes of signed in user
+func mfaAttemptsKey(userID string) string {
+	return "mfa:" + userID
+}
+
+func ipAttemptsKey(ip string) string {
+	return "ip:" + ip
+}
+
+func contextIP(ctx context.Context) string {
+	ip, _ := ctx.Value(cfg.ContextIPKey).(string)
+	return ip
+}
+
+//loginBlocked reports whether key is in backoff or locked, logins aren't blocked if the store fails
+func (u *UserService) loginBlocked(ctx context.Context, key string) bool {
+	attempts, err := u.store.LoginAttempt().Find(ctx, key)
+	if err != nil {
+		log.Printf("Err in find login attempts of %s. Err: %s", key, err.Error())
+		return false
+	}
+	return attempts.Blocked(time.Now())
+}
+
+//loginFailed counts failed login and blocks key, it returns true if key has been locked by this failure
+func (u *UserService) loginFailed(ctx context.Context, key string, policy *model.LoginThrottle) bool {
+	attempts, err := u.store.LoginAttempt().Fail(ctx, key, policy)
+	if err != nil {
+		log.Printf("Err in count failed login of %s. Err: %s", key, err.Error())
+		return false
+	}
+	//Counter is incremented atomically, so only one failure reaches the threshold
+	return attempts.Locked && attempts.Failures == policy.Threshold
+}
+
+//loginFailedBy counts failed login of the IP and of the user if login is known
+func (u *UserService) loginFailedBy(ctx context.Context, user *model.User) {
+	if ip := contextIP(ctx); len(ip) > 0 {
+		u.loginFailed(ctx, ipAttemptsKey(ip), ipThrottle())
+	}
+	if user == nil {
+		return
+	}
+	if u.loginFailed(ctx, accountAttemptsKey(user.ID), accountThrottle()) {
+		u.recordEvent(ctx, model.EventAccountLocked, user.ID, "")
+		go u.notifyLockout(user.Email, time.Now().Add(cfg.Cfg.LoginLockoutDuration))
+	}
+}
+
+func (u *UserService) loginSucceeded(ctx context.Context, userID string) {
+	if err := u.store.LoginAttempt().Reset(ctx, accountAttemptsKey(userID)); err != nil {
+		log.Printf("Err in reset login attempts of user %s. Err: %s", userID, err.Error())
+	}
+}
+
+//UnlockUser removes lockout and backoff of account
+func (u *UserService) UnlockUser(ctx context.Context, userID string) error {
+	_, err := u.store.User().FindById(ctx, userID, &store.UserFields{})
+	if err != nil {
+		return err
+	}
+	err = u.store.LoginAttempt().Reset(ctx, accountAttemptsKey(userID))
+	if err != nil {
+		return err
+	}
+	u.recordEvent(ctx, model.EventAccountUnlocked, userID, "")
+	return nil
+}
+
+func (u *UserService) notifyLockout(email string, until time.Time) {
+	if u.emailSender == nil || len(email) == 0 {
+		return
+	}
+	tmpl, err := template.ParseFiles(filePath.AccountLockedTemplate)
+	if err != nil {
+		log.Printf("Err in parse account locked template. Err: %s", err.Error())
+		return
+	}
+	data := struct {
+		Email string
+		Until string
+	}{
+		Email: email,
+		Until: until.UTC().Format("2006-01-02 15:04 MST"),
+	}
+	buf := new(bytes.Buffer)
+	if err = tmpl.Execute(buf, data); err != nil {
+		log.Printf("Err in execute account locked template. Err: %s", err.Error())
+		return
+	}
+	err = u.emailSender.Send(context.Background(), "Account locked", email, "text/html; charset=utf-8", buf.String())
+	if err != nil {
+		log.Printf("Err in send account locked email. Err: %s", err.Error())
+	}
+}
@@ -0,0 +1,86 @@
+package user_service
+
+import (
+	"auth-server/internal/app/model"
+	"context"
+	"testing"
+	"time"
+)
+
+func TestLoginThrottleBackoff(t *testing.T) {
+	throttle := &model.LoginThrottle{Free: 3, Threshold: 8, BackoffBase: time.Second, Lockout: 5 * time.Second}
+	want := []struct {
+		delay  time.Duration
+		locked bool
+	}{
+		{0, false},
+		{0, false},
+		{0, false},
+		{time.Second, false},
+		{2 * time.Second, false},
+		{4 * time.Second, false},
+		{5 * time.Second, false},
+		{5 * time.Second, true},
+		{5 * time.Second, true},
+	}
+	for i, w := range want {
+		delay, locked := throttle.Backoff(i + 1)
+		if delay != w.delay || locked != w.locked {
+			t.Errorf("failure %d: got %v %v, want %v %v", i+1, delay, locked, w.delay, w.locked)
+		}
+	}
+}
+
+func TestLoginFailedLocksOnce(t *testing.T) {
+	u, s := newTestUserService(t, nil)
+	throttle := &model.LoginThrottle{Free: 1, Threshold: 3, BackoffBase: time.Second, Lockout: time.Minute}
+	ctx := context.Background()
+	var lockedBy []int
+	for i := 1; i <= 5; i++ {
+		if u.loginFailed(ctx, "user:1", throttle) {
+			lockedBy = append(lockedBy, i)
+		}
+	}
+	if len(lockedBy) != 1 || lockedBy[0] != 3 {
+		t.Fatalf("locked by failures %v, want [3]", lockedBy)
+	}
+	attempts, _ := s.attempts.Find(ctx, "user:1")
+	if !attempts.Locked || !attempts.Blocked(time.Now().Add(59*time.Second)) {
+		t.Fatalf("unexpected attempts %+v", attempts)
+	}
+}
+
+func TestLoginFailuresResetAfterSecondFactor(t *testing.T) {
+	u, s := newTestUserService(t, &model.User{ID: "user", UserName: "user"})
+	s.users.mfa = model.MFA{TOTPEnabled: true, TOTPSecret: rfcSecret}
+	ctx := context.Background()
+	key := accountAttemptsKey("user")
+	s.attempts.attempts[key] = &model.LoginAttempts{Key: key, Failures: 2}
+
+	//valid password of user with second factor doesn't reset failures
+	challenge, err := u.StartMFA(ctx, "user", "client")
+	if err != nil || challenge == nil {
+		t.Fatalf("challenge %v, err %v", challenge, err)
+	}
+	if _, ok := s.attempts.attempts[key]; !ok {
+		t.Fatal("failures reset before second factor")
+	}
+	totpKey, _ := totpEncoding.DecodeString(rfcSecret)
+	answer := &model.MFAAnswer{Code: hotp(totpKey, time.Now().Unix()/totpPeriod)}
+	if _, err = u.CheckMFA(ctx, challenge.Token, "client", answer); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.attempts.attempts[key]; ok {
+		t.Fatal("failures aren't reset after second factor")
+	}
+
+	//login of user without second factor is complete after password
+	s.users.mfa = model.MFA{}
+	s.attempts.attempts[key] = &model.LoginAttempts{Key: key, Failures: 2}
+	if challenge, err = u.StartMFA(ctx, "user", "client"); err != nil || challenge != nil {
+		t.Fatalf("challenge %v, err %v", challenge, err)
+	}
+	if _, ok := s.attempts.attempts[key]; ok {
+		t.Fatal("failures aren't reset after login without second factor")
+	}
+}
@@ -0,0 +1,264 @@
+package user_service
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	"auth-server/pkg/encryption"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"log"
+	"strings"
+	"time"
+)
+
+//EnrollTOTP starts TOTP enrollment, the secret isn't used till ConfirmTOTP
+func (u *UserService) EnrollTOTP(ctx context.Context, userID string) (*model.TOTPEnrollment, error) {
+	user, err := u.FindUserByID(ctx, userID, &store.UserFields{UserName: true})
+	if err != nil {
+		return nil, err
+	}
+	secret, err := generateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	//secret is bound to user, so it can't be copied to another account
+	encrypted, err := u.crypter.Encrypt([]byte(secret), []byte(userID))
+	if err != nil {
+		return nil, err
+	}
+	if err = u.store.User().SetTOTPSecret(ctx, userID, encrypted); err != nil {
+		return nil, err
+	}
+	return &model.TOTPEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totpProvisioningURI(cfg.Cfg.MFAIssuer, user.UserName, secret),
+	}, nil
+}
+
+//ConfirmTOTP enables TOTP if code is valid and returns the recovery codes
+func (u *UserService) ConfirmTOTP(ctx context.Context, userID, code string) (*model.RecoveryCodes, error) {
+	mfa, err := u.store.User().FindMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.TOTPEnabled {
+		return nil, errors.ErrInvalidArgument.New("Two-factor authentication is already enabled.")
+	}
+	if len(mfa.TOTPSecret) == 0 {
+		return nil, errors.ErrInvalidArgument.New("TOTP enrollment is not started.")
+	}
+	secret, err := u.totpSecret(userID, mfa)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(secret, strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, errors.ErrInvalidArgument.New("Invalid two-factor code.")
+	}
+	codes, hashes, err := generateRecoveryCodes(model.RecoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	if err = u.store.User().EnableTOTP(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+	u.recordEvent(ctx, model.EventMFAEnabled, userID, "")
+	return &model.RecoveryCodes{Codes: codes}, nil
+}
+
+//DisableTOTP turns off two-factor authentication, user confirms it by second factor code
+func (u *UserService) DisableTOTP(ctx context.Context, userID, code string) error {
+	if err := u.confirmSecondFactor(ctx, userID, code); err != nil {
+		return err
+	}
+	if err := u.store.User().DisableTOTP(ctx, userID); err != nil {
+		return err
+	}
+	u.recordEvent(ctx, model.EventMFADisabled, userID, "")
+	return nil
+}
+
+//RegenerateRecoveryCodes replaces unused recovery codes by new ones
+func (u *UserService) RegenerateRecoveryCodes(ctx context.Context, userID, code string) (*model.RecoveryCodes, error) {
+	if err := u.confirmSecondFactor(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := generateRecoveryCodes(model.RecoveryCodesCount)
+	if err != nil {
+		return nil, err
+	}
+	if err = u.store.User().SetRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return &model.RecoveryCodes{Codes: codes}, nil
+}
+
+//confirmSecondFactor checks code which confirms change of second factor.
+//Wrong codes are throttled like failed logins, so holder of stolen session can't guess them.
+func (u *UserService) confirmSecondFactor(ctx context.Context, userID, code string) error {
+	key := mfaAttemptsKey(userID)
+	if u.loginBlocked(ctx, key) {
+		log.Printf("Second factor of user %s is throttled.", userID)
+		return errors.ErrTooManyRequests.New("Too many invalid two-factor codes. Try again later.")
+	}
+	err := u.verifySecondFactor(ctx, userID, code)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrUnauthorized {
+			u.loginFailed(ctx, key, accountThrottle())
+		}
+		return err
+	}
+	if err = u.store.LoginAttempt().Reset(ctx, key); err != nil {
+		log.Printf("Err in reset second factor attempts of user %s. Err: %s", userID, err.Error())
+	}
+	return nil
+}
+
+//verifySecondFactor checks TOTP or recovery code, each code is accepted once
+func (u *UserService) verifySecondFactor(ctx context.Context, userID, code string) error {
+	mfa, err := u.store.User().FindMFA(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !mfa.TOTPEnabled {
+		return errors.ErrInvalidArgument.New("Two-factor authentication is disabled.")
+	}
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		secret, err := u.totpSecret(userID, mfa)
+		if err != nil {
+			return err
+		}
+		step, ok := validateTOTP(secret, code, time.Now())
+		if !ok {
+			return errors.ErrUnauthorized.New("Invalid two-factor code.")
+		}
+		ok, err = u.store.User().UseTOTPStep(ctx, userID, step)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return errors.ErrUnauthorized.New("Invalid two-factor code.")
+		}
+		return nil
+	}
+	ok, err := u.store.User().UseRecoveryCode(ctx, userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.ErrUnauthorized.New("Invalid two-factor code.")
+	}
+	u.recordEvent(ctx, model.EventRecoveryCodeUsed, userID, "")
+	return nil
+}
+
+//totpSecret decrypts TOTP secret. Secrets saved before encryption are read as is till TOTP is enrolled again.
+func (u *UserService) totpSecret(userID string, mfa *model.MFA) (string, error) {
+	if !encryption.IsEncrypted(mfa.TOTPSecret) {
+		return mfa.TOTPSecret, nil
+	}
+	secret, err := u.crypter.Decrypt(mfa.TOTPSecret, []byte(userID))
+	if err != nil {
+		return "", errors.NoType.Newf("TOTP secret of user %s can not be decrypted.", userID)
+	}
+	return string(secret), nil
+}
+
+//StartMFA creates MFA challenge if user has TOTP or passkeys, otherwise login is complete and it returns nil
+func (u *UserService) StartMFA(ctx context.Context, userID, clientID string) (*model.MFAChallenge, error) {
+	mfa, err := u.store.User().FindMFA(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := u.store.User().FindWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	var methods []string
+	if mfa.TOTPEnabled {
+		methods = append(methods, model.MFAMethodTOTP)
+	}
+	if len(credentials) > 0 {
+		methods = append(methods, model.MFAMethodWebAuthn)
+	}
+	if len(methods) == 0 {
+		u.loginSucceeded(ctx, userID)
+		return nil, nil
+	}
+	challenge := &model.MFAChallenge{
+		MFARequired: true,
+		Token:       generateRefreshToken(),
+		Methods:     methods,
+		UserID:      userID,
+		ClientID:    clientID,
+		ExpIn:       time.Now().Add(mfaChallengeTTL),
+		CreatedAt:   time.Now(),
+	}
+	if err = u.store.MFAChallenge().Create(ctx, challenge); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+//CheckMFA returns the user if second factor answers the challenge of the client.
+//Challenge is dropped after model.MFAMaxAttempts wrong answers, all wrong answers are counted as failed logins
+//of the account, so new challenges don't give more guesses.
+func (u *UserService) CheckMFA(ctx context.Context, mfaToken, clientID string, answer *model.MFAAnswer) (*model.User, error) {
+	if answer == nil {
+		return nil, errors.ErrInvalidArgument.New("Second factor required.")
+	}
+	challenge, err := u.store.MFAChallenge().Attempt(ctx, mfaToken, model.MFAMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.ClientID != clientID {
+		return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+	}
+	if u.loginBlocked(ctx, accountAttemptsKey(challenge.UserID)) {
+		log.Printf("Second factor of user %s is throttled.", challenge.UserID)
+		return nil, errors.ErrTooManyRequests.New("Too many failed logins. Try again later.")
+	}
+	user, err := u.FindUserByID(ctx, challenge.UserID, &store.UserFields{UserName: true, Email: true})
+	if err != nil {
+		return nil, err
+	}
+	if answer.WebAuthn != nil {
+		err = u.verifyWebAuthnMFA(ctx, user.ID, clientID, answer.WebAuthn)
+	} else {
+		err = u.verifySecondFactor(ctx, user.ID, answer.Code)
+	}
+	if err != nil {
+		if errors.GetType(err) == errors.ErrUnauthorized {
+			u.loginFailedBy(ctx, user)
+		}
+		return nil, err
+	}
+	if err = u.store.MFAChallenge().Consume(ctx, mfaToken); err != nil {
+		return nil, err
+	}
+	u.loginSucceeded(ctx, user.ID)
+	return user, nil
+}
+
+//AuthenticateMFA is a second step of login, session is created after valid second factor
+func (u *UserService) AuthenticateMFA(ctx context.Context, mfaToken, clientID string, answer *model.MFAAnswer) (*model.Identity, error) {
+	user, err := u.CheckMFA(ctx, mfaToken, clientID, answer)
+	if err != nil {
+		return nil, err
+	}
+	return u.createClientIdentity(ctx, user, clientID)
+}
+
+func (u *UserService) recordEvent(ctx context.Context, eventType, userID, clientID string) {
+	event := &model.SecurityEvent{
+		Type:      eventType,
+		UserID:    userID,
+		ClientID:  clientID,
+		CreatedAt: time.Now(),
+	}
+	if err := u.store.Event().Create(ctx, event); err != nil {
+		log.Printf("Err in record security event %s. Err: %s", eventType, err.Error())
+	}
+}
@@ -0,0 +1,130 @@
+package user_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/pkg/encryption"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecoveryCodeSingleUse(t *testing.T) {
+	u, s := newTestUserService(t, nil)
+	codes, hashes, err := generateRecoveryCodes(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.users.mfa = model.MFA{TOTPEnabled: true, RecoveryCodes: hashes}
+	ctx := context.Background()
+
+	if err := u.verifySecondFactor(ctx, "user", codes[0]); err != nil {
+		t.Fatalf("unused code rejected: %v", err)
+	}
+	if err := u.verifySecondFactor(ctx, "user", codes[0]); errors.GetType(err) != errors.ErrUnauthorized {
+		t.Fatalf("used code: got %v, want unauthorized", err)
+	}
+	//user may type the code without dash in upper case
+	typed := strings.ToUpper(strings.ReplaceAll(codes[1], "-", ""))
+	if err := u.verifySecondFactor(ctx, "user", typed); err != nil {
+		t.Fatalf("code typed without dash rejected: %v", err)
+	}
+	if len(s.users.mfa.RecoveryCodes) != 0 {
+		t.Fatalf("%d recovery codes left", len(s.users.mfa.RecoveryCodes))
+	}
+}
+
+func TestTOTPCodeSingleUse(t *testing.T) {
+	u, s := newTestUserService(t, nil)
+	s.users.mfa = model.MFA{TOTPEnabled: true, TOTPSecret: rfcSecret}
+	ctx := context.Background()
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	code := hotp(key, time.Now().Unix()/totpPeriod)
+
+	if err := u.verifySecondFactor(ctx, "user", code); err != nil {
+		t.Fatalf("valid code rejected: %v", err)
+	}
+	if err := u.verifySecondFactor(ctx, "user", code); errors.GetType(err) != errors.ErrUnauthorized {
+		t.Fatalf("replayed code: got %v, want unauthorized", err)
+	}
+}
+
+func TestTOTPSecretEncrypted(t *testing.T) {
+	u, s := newTestUserService(t, &model.User{ID: "user", UserName: "user"})
+	ctx := context.Background()
+
+	enrollment, err := u.EnrollTOTP(ctx, "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	stored := s.users.mfa.TOTPSecret
+	if !encryption.IsEncrypted(stored) || strings.Contains(stored, enrollment.Secret) {
+		t.Fatalf("TOTP secret is stored in plaintext: %s", stored)
+	}
+	secret, err := u.totpSecret("user", &s.users.mfa)
+	if err != nil || secret != enrollment.Secret {
+		t.Fatalf("decrypted secret %q, err %v", secret, err)
+	}
+	//secret is bound to user
+	if _, err = u.totpSecret("another", &s.users.mfa); err == nil {
+		t.Fatal("secret of another user decrypted")
+	}
+	//secrets saved before encryption are read as is
+	secret, err = u.totpSecret("user", &model.MFA{TOTPSecret: rfcSecret})
+	if err != nil || secret != rfcSecret {
+		t.Fatalf("plaintext secret %q, err %v", secret, err)
+	}
+}
+
+func TestConfirmSecondFactorThrottled(t *testing.T) {
+	u, s := newTestUserService(t, nil)
+	s.users.mfa = model.MFA{TOTPEnabled: true, TOTPSecret: rfcSecret}
+	ctx := context.Background()
+
+	for i := 0; i < loginFreeFailures; i++ {
+		if err := u.confirmSecondFactor(ctx, "user", "00000-00000"); errors.GetType(err) != errors.ErrUnauthorized {
+			t.Fatalf("attempt %d: got %v, want unauthorized", i+1, err)
+		}
+	}
+	//the first failure after free ones is delayed
+	u.confirmSecondFactor(ctx, "user", "00000-00000")
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	code := hotp(key, time.Now().Unix()/totpPeriod)
+	if err := u.confirmSecondFactor(ctx, "user", code); errors.GetType(err) != errors.ErrTooManyRequests {
+		t.Fatalf("throttled attempt: got %v, want too many requests", err)
+	}
+	if s.users.mfa.TOTPLastStep != 0 {
+		t.Fatal("code was checked while throttled")
+	}
+}
+
+func TestCheckMFAThrottledAcrossChallenges(t *testing.T) {
+	u, s := newTestUserService(t, &model.User{ID: "user", UserName: "user"})
+	s.users.mfa = model.MFA{TOTPEnabled: true, TOTPSecret: rfcSecret}
+	ctx := context.Background()
+	wrong := &model.MFAAnswer{Code: "00000-00000"}
+
+	//each challenge gets fewer wrong answers than its limit, but they add up on the account
+	for i := 0; i <= loginFreeFailures; i++ {
+		challenge, err := u.StartMFA(ctx, "user", "client")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = u.CheckMFA(ctx, challenge.Token, "client", wrong); errors.GetType(err) != errors.ErrUnauthorized {
+			t.Fatalf("challenge %d: got %v, want unauthorized", i+1, err)
+		}
+	}
+	challenge, err := u.StartMFA(ctx, "user", "client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+	valid := &model.MFAAnswer{Code: hotp(key, time.Now().Unix()/totpPeriod)}
+	if _, err = u.CheckMFA(ctx, challenge.Token, "client", valid); errors.GetType(err) != errors.ErrTooManyRequests {
+		t.Fatalf("throttled challenge: got %v, want too many requests", err)
+	}
+	if s.users.mfa.TOTPLastStep != 0 {
+		t.Fatal("code was checked while throttled")
+	}
+}
@@ -0,0 +1,114 @@
+package user_service
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	"auth-server/internal/app/utils/validators"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"log"
+	"time"
+)
+
+//ForgotPassword creates reset token, previous tokens of user stop working.
+//Token isn't created again till the cooldown is over, so repeated requests don't flood the mailbox.
+func (u *UserService) ForgotPassword(ctx context.Context, email string) (*model.User, string, error) {
+	user, err := u.FindUserByEmail(ctx, email, &store.UserFields{Email: true})
+	if err != nil {
+		//Caller must not reveal that email is unknown
+		if errors.GetType(err) == errors.ErrInvalidArgument {
+			return nil, "", nil
+		}
+		return nil, "", err
+	}
+	last, err := u.store.PasswordReset().FindLastByUser(ctx, user.ID)
+	if err == nil && time.Since(last.CreatedAt) < cfg.Cfg.PasswordResetCooldown {
+		log.Printf("Password reset of user %s is requested in cooldown.", user.ID)
+		return nil, "", nil
+	}
+	if err != nil && errors.GetType(err) != errors.ErrInvalidArgument {
+		return nil, "", err
+	}
+	err = u.store.PasswordReset().DeleteByUser(ctx, user.ID)
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	reset := &model.PasswordReset{
+		Token:     generateRefreshToken(),
+		UserID:    user.ID,
+		ExpIn:     now.Add(cfg.Cfg.PasswordResetTTL),
+		CreatedAt: now,
+	}
+	err = u.store.PasswordReset().Create(ctx, reset)
+	if err != nil {
+		return nil, "", err
+	}
+	return user, reset.Token, nil
+}
+
+func (u *UserService) ResetPassword(ctx context.Context, token, password string) error {
+	if len(token) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid or expired password reset token.")
+	}
+	//Token is used only by valid password, so user may retry with the same token
+	reset, err := u.store.PasswordReset().Find(ctx, token)
+	if err != nil {
+		return err
+	}
+	user, err := u.store.User().FindById(ctx, reset.UserID, &store.UserFields{UserName: true, Email: true})
+	if err != nil {
+		return err
+	}
+	history, err := u.passwordHistory(ctx, reset.UserID)
+	if err != nil {
+		return err
+	}
+	err = u.userValidator.ValidatePassword(password, user, history)
+	if err != nil {
+		return err
+	}
+	passHash, err := u.hashUserPassword(password)
+	if err != nil {
+		return err
+	}
+	if _, err = u.store.PasswordReset().Consume(ctx, token); err != nil {
+		return err
+	}
+	err = u.store.User().SetPasswordHash(ctx, reset.UserID, passHash, u.userValidator.PasswordHistorySize())
+	if err != nil {
+		return err
+	}
+	if err = u.store.PasswordReset().DeleteByUser(ctx, reset.UserID); err != nil {
+		log.Printf("Err in delete password reset tokens of user %s. Err: %s", reset.UserID, err.Error())
+	}
+	u.recordEvent(ctx, model.EventPasswordReset, reset.UserID, "")
+	//User who proved access to email may log in at once
+	u.loginSucceeded(ctx, reset.UserID)
+	return u.SignOutEverywhere(ctx, reset.UserID, "")
+}
+
+//passwordHistory returns check of the last passwords of user, current one is the first
+func (u *UserService) passwordHistory(ctx context.Context, userID string) (validators.PasswordHistory, error) {
+	size := u.userValidator.PasswordHistorySize()
+	if size == 0 {
+		return nil, nil
+	}
+	hashes, err := u.store.User().FindPasswordHistory(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	//History may be longer if its size was decreased, the oldest hashes are skipped
+	if len(hashes) > size {
+		hashes = append(hashes[:1], hashes[len(hashes)-size+1:]...)
+	}
+	return func(password string) bool {
+		for _, hash := range hashes {
+			if ok, _, _ := u.passwords.Verify(hash, password); ok {
+				return true
+			}
+		}
+		return false
+	}, nil
+}
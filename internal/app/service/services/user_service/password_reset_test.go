@@ -0,0 +1,82 @@
+package user_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/utils/validators"
+	"auth-server/pkg/passhash"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+//testValidator keeps password history of size, other checks aren't used by tests
+type testValidator struct {
+	validators.IUserValidator
+	historySize int
+}
+
+func (v testValidator) PasswordHistorySize() int {
+	return v.historySize
+}
+
+func TestPasswordHistoryTruncated(t *testing.T) {
+	u, s := newTestUserService(t, nil)
+	u.passwords = passhash.New(passhash.NewArgon2id(1024, 1, 1))
+	//current password and 4 previous ones from the oldest as store keeps them
+	for i := 0; i < 5; i++ {
+		hash, err := u.passwords.Hash(fmt.Sprintf("password-%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.users.history = append(s.users.history, hash)
+	}
+	//history size was decreased to 3, so current and 2 latest previous passwords are checked
+	u.userValidator = testValidator{historySize: 3}
+	history, err := u.passwordHistory(context.Background(), "user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []bool{true, false, false, true, true} {
+		if got := history(fmt.Sprintf("password-%d", i)); got != want {
+			t.Errorf("password %d: reused %v, want %v", i, got, want)
+		}
+	}
+	if history("password-5") {
+		t.Error("new password is reported as reused")
+	}
+
+	u.userValidator = testValidator{historySize: 0}
+	if history, _ = u.passwordHistory(context.Background(), "user"); history != nil {
+		t.Error("history is checked while it is disabled")
+	}
+}
+
+func TestForgotPasswordCooldown(t *testing.T) {
+	u, s := newTestUserService(t, &model.User{ID: "user", Email: "user@example.com"})
+	ctx := context.Background()
+
+	user, token, err := u.ForgotPassword(ctx, "user@example.com")
+	if err != nil || user == nil || len(token) == 0 {
+		t.Fatalf("user %v, token %q, err %v", user, token, err)
+	}
+	//the second request in cooldown keeps the first token
+	if user, token, err = u.ForgotPassword(ctx, "user@example.com"); err != nil || user != nil || len(token) != 0 {
+		t.Fatalf("in cooldown: user %v, token %q, err %v", user, token, err)
+	}
+	if len(s.resets.resets) != 1 {
+		t.Fatalf("%d tokens created", len(s.resets.resets))
+	}
+	//token is created again after cooldown
+	s.resets.resets[0].CreatedAt = time.Now().Add(-time.Hour)
+	if user, token, err = u.ForgotPassword(ctx, "user@example.com"); err != nil || user == nil || len(token) == 0 {
+		t.Fatalf("after cooldown: user %v, token %q, err %v", user, token, err)
+	}
+	if len(s.resets.resets) != 1 || s.resets.resets[0].Token != token {
+		t.Fatalf("previous token isn't replaced: %+v", s.resets.resets)
+	}
+	//unknown email isn't revealed
+	if user, token, err = u.ForgotPassword(ctx, "unknown@example.com"); err != nil || user != nil || len(token) != 0 {
+		t.Fatalf("unknown email: user %v, token %q, err %v", user, token, err)
+	}
+}
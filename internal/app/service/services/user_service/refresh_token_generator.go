@@ -3,8 +3,12 @@ package user_service
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"time"
 )
 
+//refreshTokenTTL is a lifetime of refresh token, each rotation extends it
+const refreshTokenTTL = 720 * time.Hour
+
 func generateRefreshToken() string {
 	bytes := make([]byte, 32)
 	token := ""
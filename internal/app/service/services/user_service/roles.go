@@ -0,0 +1,164 @@
+package user_service
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+)
+
+//GrantRole grants the role defined by client to user
+func (u *UserService) GrantRole(ctx context.Context, userID, clientID, role string) error {
+	client, err := u.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if !client.HasRole(role) {
+		return errors.ErrInvalidArgument.Newf("Role %s is not defined by client.", role)
+	}
+	return u.store.User().GrantRole(ctx, userID, clientID, role)
+}
+
+//RevokeRole revokes the role of client from user, roles which client no longer defines can be revoked too
+func (u *UserService) RevokeRole(ctx context.Context, userID, clientID, role string) error {
+	return u.store.User().RevokeRole(ctx, userID, clientID, role)
+}
+
+func (u *UserService) FindRoleAssignments(ctx context.Context, clientID string) ([]model.RoleAssignment, error) {
+	if _, err := u.store.Client().FindById(ctx, clientID); err != nil {
+		return nil, err
+	}
+	return u.store.User().FindRoleAssignments(ctx, clientID)
+}
+
+//walkUserGroups visits groups of user and their parent groups, each group once.
+//Path contains names of groups from group of user to the visited group.
+//Groups nested deeper than MaxGroupDepth are an error, so inherited roles are never dropped silently.
+func (u *UserService) walkUserGroups(ctx context.Context, userID string, visit func(group *model.Group, path []string)) error {
+	level, err := u.store.Group().FindUserGroups(ctx, userID)
+	if err != nil {
+		return err
+	}
+	visited := make(map[string]bool)
+	paths := make(map[string][]string)
+	for depth := 0; len(level) > 0; depth++ {
+		if depth >= model.MaxGroupDepth {
+			return errors.NoType.Newf("Groups of user %s are nested deeper than %d.", userID, model.MaxGroupDepth)
+		}
+		parentIDs := make([]string, 0)
+		parentPaths := make(map[string][]string)
+		for i := range level {
+			group := &level[i]
+			if visited[group.ID] {
+				continue
+			}
+			visited[group.ID] = true
+			path := append(append([]string{}, paths[group.ID]...), group.Name)
+			visit(group, path)
+			if len(group.ParentID) == 0 || visited[group.ParentID] {
+				continue
+			}
+			if _, ok := parentPaths[group.ParentID]; !ok {
+				parentIDs = append(parentIDs, group.ParentID)
+				parentPaths[group.ParentID] = path
+			}
+		}
+		if len(parentIDs) == 0 {
+			break
+		}
+		level, err = u.store.Group().FindByIDs(ctx, parentIDs)
+		if err != nil {
+			return err
+		}
+		paths = parentPaths
+	}
+	return nil
+}
+
+//effectiveRoles returns roles of user in the client granted directly and inherited from groups
+func (u *UserService) effectiveRoles(ctx context.Context, userID, clientID string) ([]string, error) {
+	clientRoles, err := u.store.User().FindUserClientRoles(ctx, userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	roles := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(granted []string) {
+		for _, v := range granted {
+			if !seen[v] {
+				seen[v] = true
+				roles = append(roles, v)
+			}
+		}
+	}
+	for _, v := range clientRoles {
+		add(v.Roles)
+	}
+	err = u.walkUserGroups(ctx, userID, func(group *model.Group, path []string) {
+		add(group.ClientRoles(clientID))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (u *UserService) ExplainRole(ctx context.Context, userID, clientID, role string) ([]model.RoleSource, error) {
+	clientRoles, err := u.store.User().FindUserClientRoles(ctx, userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]model.RoleSource, 0)
+	for _, v := range clientRoles {
+		if hasRole(v.Roles, role) {
+			sources = append(sources, model.RoleSource{Direct: true})
+			break
+		}
+	}
+	err = u.walkUserGroups(ctx, userID, func(group *model.Group, path []string) {
+		if hasRole(group.ClientRoles(clientID), role) {
+			sources = append(sources, model.RoleSource{
+				GroupID:   group.ID,
+				GroupName: group.Name,
+				Path:      path,
+			})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sources, nil
+}
+
+func (u *UserService) HasRole(ctx context.Context, userID, clientID, role string) (bool, error) {
+	roles, err := u.effectiveRoles(ctx, userID, clientID)
+	if err != nil {
+		return false, err
+	}
+	return hasRole(roles, role), nil
+}
+
+func hasRole(roles []string, role string) bool {
+	for _, v := range roles {
+		if v == role {
+			return true
+		}
+	}
+	return false
+}
+
+//fillUserRoles sets client names and permissions of user roles
+func (u *UserService) fillUserRoles(ctx context.Context, user *model.User) error {
+	for i := range user.Roles {
+		client, err := u.store.Client().FindById(ctx, user.Roles[i].ClientID)
+		if err != nil {
+			if errors.GetType(err) == errors.ErrInvalidArgument {
+				//Client was deleted, its roles give nothing
+				continue
+			}
+			return err
+		}
+		user.Roles[i].ClientName = client.ClientName
+		user.Roles[i].Permissions = client.Permissions(user.Roles[i].Roles)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package user_service
+
+import (
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	"auth-server/pkg/encryption"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+//testStore implements repositories used by tests, calls of other repositories panic
+type testStore struct {
+	store.Store
+	users      *testUserRepo
+	attempts   *testLoginAttemptRepo
+	challenges *testMFAChallengeRepo
+	resets     *testPasswordResetRepo
+}
+
+func (s *testStore) User() store.UserRepository {
+	return s.users
+}
+
+func (s *testStore) Event() store.EventRepository {
+	return testEventRepo{}
+}
+
+func (s *testStore) LoginAttempt() store.LoginAttemptRepository {
+	return s.attempts
+}
+
+func (s *testStore) MFAChallenge() store.MFAChallengeRepository {
+	return s.challenges
+}
+
+func (s *testStore) PasswordReset() store.PasswordResetRepository {
+	return s.resets
+}
+
+type testUserRepo struct {
+	store.UserRepository
+	user *model.User
+	mfa  model.MFA
+	//history is current password hash and hashes of previous passwords
+	history []string
+}
+
+func (r *testUserRepo) FindById(ctx context.Context, id string, params *store.UserFields) (*model.User, error) {
+	if r.user == nil || r.user.ID != id {
+		return nil, errors.ErrInvalidArgument.New("User not found.")
+	}
+	user := *r.user
+	return &user, nil
+}
+
+func (r *testUserRepo) FindByEmail(ctx context.Context, email string, params *store.UserFields) (*model.User, error) {
+	if r.user == nil || r.user.Email != email {
+		return nil, errors.ErrInvalidArgument.New("User not found.")
+	}
+	user := *r.user
+	return &user, nil
+}
+
+func (r *testUserRepo) FindPasswordHistory(ctx context.Context, userID string) ([]string, error) {
+	return r.history, nil
+}
+
+func (r *testUserRepo) FindMFA(ctx context.Context, userID string) (*model.MFA, error) {
+	mfa := r.mfa
+	return &mfa, nil
+}
+
+func (r *testUserRepo) FindWebAuthnCredentials(ctx context.Context, userID string) ([]model.WebAuthnCredential, error) {
+	return nil, nil
+}
+
+func (r *testUserRepo) SetTOTPSecret(ctx context.Context, userID, secret string) error {
+	r.mfa.TOTPSecret = secret
+	return nil
+}
+
+func (r *testUserRepo) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	if step <= r.mfa.TOTPLastStep {
+		return false, nil
+	}
+	r.mfa.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *testUserRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	for i, hash := range r.mfa.RecoveryCodes {
+		if hash == codeHash {
+			r.mfa.RecoveryCodes = append(r.mfa.RecoveryCodes[:i], r.mfa.RecoveryCodes[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type testEventRepo struct{}
+
+func (testEventRepo) Create(ctx context.Context, event *model.SecurityEvent) error {
+	return nil
+}
+
+type testLoginAttemptRepo struct {
+	attempts map[string]*model.LoginAttempts
+}
+
+func (r *testLoginAttemptRepo) Find(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	if attempts, ok := r.attempts[key]; ok {
+		found := *attempts
+		return &found, nil
+	}
+	return &model.LoginAttempts{Key: key}, nil
+}
+
+func (r *testLoginAttemptRepo) Fail(ctx context.Context, key string, throttle *model.LoginThrottle) (*model.LoginAttempts, error) {
+	attempts, ok := r.attempts[key]
+	if !ok {
+		attempts = &model.LoginAttempts{Key: key}
+		r.attempts[key] = attempts
+	}
+	attempts.Fail(throttle, time.Now())
+	failed := *attempts
+	return &failed, nil
+}
+
+func (r *testLoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	delete(r.attempts, key)
+	return nil
+}
+
+type testMFAChallengeRepo struct {
+	challenges map[string]*model.MFAChallenge
+}
+
+func (r *testMFAChallengeRepo) Create(ctx context.Context, challenge *model.MFAChallenge) error {
+	created := *challenge
+	r.challenges[challenge.Token] = &created
+	return nil
+}
+
+func (r *testMFAChallengeRepo) Find(ctx context.Context, token string) (*model.MFAChallenge, error) {
+	challenge, ok := r.challenges[token]
+	if !ok {
+		return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+	}
+	found := *challenge
+	return &found, nil
+}
+
+func (r *testMFAChallengeRepo) Attempt(ctx context.Context, token string, maxAttempts int) (*model.MFAChallenge, error) {
+	challenge, ok := r.challenges[token]
+	if !ok || challenge.Attempts >= maxAttempts {
+		return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+	}
+	challenge.Attempts++
+	found := *challenge
+	return &found, nil
+}
+
+func (r *testMFAChallengeRepo) Consume(ctx context.Context, token string) error {
+	delete(r.challenges, token)
+	return nil
+}
+
+type testPasswordResetRepo struct {
+	store.PasswordResetRepository
+	resets []model.PasswordReset
+}
+
+func (r *testPasswordResetRepo) Create(ctx context.Context, reset *model.PasswordReset) error {
+	r.resets = append(r.resets, *reset)
+	return nil
+}
+
+func (r *testPasswordResetRepo) FindLastByUser(ctx context.Context, userID string) (*model.PasswordReset, error) {
+	for i := len(r.resets) - 1; i >= 0; i-- {
+		if r.resets[i].UserID == userID {
+			reset := r.resets[i]
+			reset.Token = ""
+			return &reset, nil
+		}
+	}
+	return nil, errors.ErrInvalidArgument.New("Password reset token not found.")
+}
+
+func (r *testPasswordResetRepo) DeleteByUser(ctx context.Context, userID string) error {
+	resets := r.resets[:0]
+	for _, reset := range r.resets {
+		if reset.UserID != userID {
+			resets = append(resets, reset)
+		}
+	}
+	r.resets = resets
+	return nil
+}
+
+func newTestUserService(t *testing.T, user *model.User) (*UserService, *testStore) {
+	crypter, err := encryption.New(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testStore{
+		users:      &testUserRepo{user: user},
+		attempts:   &testLoginAttemptRepo{attempts: map[string]*model.LoginAttempts{}},
+		challenges: &testMFAChallengeRepo{challenges: map[string]*model.MFAChallenge{}},
+		resets:     &testPasswordResetRepo{},
+	}
+	return &UserService{store: s, crypter: crypter}, s
+}
@@ -0,0 +1,116 @@
+package user_service
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//TOTP parameters, RFC 6238. Authenticator apps support only these defaults reliably.
+const (
+	totpPeriod = 30
+	totpDigits = 6
+	//totpSkew is a number of time steps accepted before and after current one, clocks of devices drift
+	totpSkew = 1
+	//totpSecretSize is a size of HMAC-SHA1 key, RFC 4226 section 4
+	totpSecretSize = 20
+)
+
+//mfaChallengeTTL is a time which user has to enter second factor code after password
+const mfaChallengeTTL = 5 * time.Minute
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func generateTOTPSecret() (string, error) {
+	secret := make([]byte, totpSecretSize)
+	if _, err := rand.Read(secret); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate TOTP secret.")
+	}
+	return totpEncoding.EncodeToString(secret), nil
+}
+
+//hotp computes one-time password of counter, RFC 4226 section 5.3
+func hotp(key []byte, counter int64) string {
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(counter))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, code%uint32(math.Pow10(totpDigits)))
+}
+
+//validateTOTP returns the time step which code belongs to
+func validateTOTP(secret, code string, t time.Time) (int64, bool) {
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil || len(code) != totpDigits {
+		return 0, false
+	}
+	current := t.Unix() / totpPeriod
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if hmac.Equal([]byte(hotp(key, step)), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+//isTOTPCode checks that code looks like TOTP code, other codes are treated as recovery codes
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+//totpProvisioningURI returns the key URI which authenticator apps read from QR code
+func totpProvisioningURI(issuer, account, secret string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(totpDigits))
+	query.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer) + ":" + url.PathEscape(account)
+	//Key URI format expects spaces encoded as %20, literal pluses are already escaped
+	return "otpauth://totp/" + label + "?" + strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+//generateRecoveryCodes returns the codes to show to user and their hashes to store
+func generateRecoveryCodes(count int) ([]string, []string, error) {
+	codes := make([]string, 0, count)
+	hashes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		bytes := make([]byte, 5)
+		if _, err := rand.Read(bytes); err != nil {
+			return nil, nil, errors.NoType.Wrap(err, "Err generate recovery code.")
+		}
+		code := hex.EncodeToString(bytes)
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashRecoveryCode(code))
+	}
+	return codes, hashes, nil
+}
+
+//hashRecoveryCode hashes the code, user may type it without dash or in upper case
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(code, "-", ""))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
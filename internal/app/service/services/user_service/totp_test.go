@@ -0,0 +1,69 @@
+package user_service
+
+import (
+	"testing"
+	"time"
+)
+
+//rfcSecret is the key of test vectors of RFC 4226 and RFC 6238
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestHOTP(t *testing.T) {
+	//RFC 4226 appendix D
+	vectors := []string{"755224", "287082", "359152", "969429", "338314"}
+	for counter, want := range vectors {
+		if got := hotp([]byte("12345678901234567890"), int64(counter)); got != want {
+			t.Errorf("hotp(%d) = %s, want %s", counter, got, want)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	current := now.Unix() / totpPeriod
+	key, _ := totpEncoding.DecodeString(rfcSecret)
+
+	//RFC 6238 appendix B, the last 6 digits of 07081804
+	step, ok := validateTOTP(rfcSecret, "081804", now)
+	if !ok || step != current {
+		t.Fatalf("code of current step rejected, step %d ok %v", step, ok)
+	}
+	for _, offset := range []int64{-totpSkew, totpSkew} {
+		step, ok = validateTOTP(rfcSecret, hotp(key, current+offset), now)
+		if !ok || step != current+offset {
+			t.Errorf("code of step %+d rejected", offset)
+		}
+	}
+	for _, offset := range []int64{-totpSkew - 1, totpSkew + 1} {
+		if _, ok = validateTOTP(rfcSecret, hotp(key, current+offset), now); ok {
+			t.Errorf("code of step %+d accepted", offset)
+		}
+	}
+}
+
+func TestValidateTOTPInvalid(t *testing.T) {
+	now := time.Unix(1111111109, 0)
+	for _, code := range []string{"", "08180", "0818045", "181804"} {
+		if _, ok := validateTOTP(rfcSecret, code, now); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	if _, ok := validateTOTP("not base32!", "081804", now); ok {
+		t.Error("code of invalid secret accepted")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, hashes, err := generateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 3 || len(hashes) != 3 {
+		t.Fatalf("got %d codes and %d hashes", len(codes), len(hashes))
+	}
+	for i, code := range codes {
+		if hashRecoveryCode(code) != hashes[i] {
+			t.Errorf("hash of code %s mismatch", code)
+		}
+	}
+}
@@ -3,12 +3,15 @@ package user_service
 import (
 	cfg "auth-server/internal/app/config"
 	"auth-server/internal/app/model"
+	"auth-server/internal/app/service"
 	"auth-server/internal/app/store"
 	"auth-server/internal/app/utils/validators"
+	"auth-server/pkg/emailsender"
+	"auth-server/pkg/encryption"
 	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/passhash"
 	"context"
 	"github.com/spf13/viper"
-	"golang.org/x/crypto/bcrypt"
 	"log"
 	"strings"
 	"time"
@@ -17,24 +20,29 @@ import (
 type UserService struct {
 	store         store.Store
 	userValidator validators.IUserValidator
+	tokenService  service.TokenService
+	passwords     *passhash.Passwords
+	emailSender   emailsender.IEmailSender
+	//crypter encrypts TOTP secrets at rest
+	crypter *encryption.Cipher
 }
 
-func (u *UserService) hashUserPassword(passBytes []byte) ([]byte, error) {
-	bytes, err := bcrypt.GenerateFromPassword(passBytes, bcrypt.MinCost)
-	if err != nil {
-		return nil, errors.NoType.Wrap(err, "Err generate hash password.")
-	}
-	return bytes, nil
-}
-func (u *UserService) compareHashAndPassword(hash, pass []byte) error {
-	err := bcrypt.CompareHashAndPassword(hash, pass)
-	return err
+func (u *UserService) hashUserPassword(password string) (string, error) {
+	return u.passwords.Hash(password)
 }
 
-func New(store store.Store, uvalidator validators.IUserValidator) (*UserService, error) {
+func New(store store.Store, uvalidator validators.IUserValidator, tokenService service.TokenService,
+	passwords *passhash.Passwords, sender emailsender.IEmailSender, crypter *encryption.Cipher) (*UserService, error) {
+	if crypter == nil {
+		return nil, errors.ErrInvalidArgument.New("Cipher is nill.")
+	}
 	us := UserService{
 		store:         store,
 		userValidator: uvalidator,
+		tokenService:  tokenService,
+		passwords:     passwords,
+		emailSender:   sender,
+		crypter:       crypter,
 	}
 	return &us, nil
 }
@@ -44,6 +52,11 @@ func (u *UserService) FindUserByID(ctx context.Context, userID string, fields *s
 	if err != nil {
 		return nil, err
 	}
+	if fields != nil && fields.UserRoles {
+		if err = u.fillUserRoles(ctx, usr); err != nil {
+			return nil, err
+		}
+	}
 	usr.Sanitize()
 	return usr, nil
 }
@@ -69,6 +82,11 @@ func (u *UserService) FindUserByName(ctx context.Context, username string, field
 		if err != nil {
 			return nil, err
 		}
+		if fields != nil && fields.UserRoles {
+			if err = u.fillUserRoles(ctx, usr); err != nil {
+				return nil, err
+			}
+		}
 		usr.Sanitize()
 		return usr, nil
 	}
@@ -85,6 +103,11 @@ func (u *UserService) FindUserByEmail(ctx context.Context, email string, fields
 	if err != nil {
 		return nil, err
 	}
+	if fields != nil && fields.UserRoles {
+		if err = u.fillUserRoles(ctx, usr); err != nil {
+			return nil, err
+		}
+	}
 	usr.Sanitize()
 	return usr, nil
 }
@@ -115,12 +138,11 @@ func (u *UserService) Registration(ctx context.Context, user *model.User) (strin
 			return "", errors.NoType.Newf("")
 		}
 	}
-	passBytes := []byte(user.Password)
-	passHash, err := u.hashUserPassword(passBytes)
+	passHash, err := u.hashUserPassword(user.Password)
 	if err != nil {
 		return "", err
 	}
-	user.PasswordHash = string(passHash)
+	user.PasswordHash = passHash
 	user.SanitizeForRegistration()
 
 	user.UserInfo = userInfo
@@ -166,12 +188,40 @@ func (u *UserService) ConfirmEmail(ctx context.Context, userID, token string) er
 	return nil
 }
 
-//TODO: Implement FindUserSessions method
 func (u *UserService) FindUserSessions(ctx context.Context, userID string) (*[]model.UserSession, error) {
-	panic("implement me")
+	return u.store.User().FindSessions(ctx, userID)
+}
+
+//CheckSession checks that session is not closed and marks its activity
+func (u *UserService) CheckSession(ctx context.Context, sessionID string) error {
+	return u.store.User().CheckSession(ctx, sessionID)
+}
+
+//Authenticate creates a session if user has no second factor, otherwise it returns MFA challenge
+func (u *UserService) Authenticate(ctx context.Context, login, password, clientID string) (*model.Identity, *model.MFAChallenge, error) {
+	user, err := u.CheckCredentials(ctx, login, password)
+	if err != nil {
+		return nil, nil, err
+	}
+	challenge, err := u.StartMFA(ctx, user.ID, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if challenge != nil {
+		return nil, challenge, nil
+	}
+	identity, err := u.createClientIdentity(ctx, user, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return identity, nil, nil
 }
 
-func (u *UserService) Authenticate(ctx context.Context, login, password, clientID string) (*model.User, *model.ClientRefToken, error) {
+//CheckCredentials returns the user if login and password are valid.
+//Failed logins are counted per account and per IP, blocked logins are rejected before password is checked.
+//Failures of account aren't reset till the second factor is checked, StartMFA or CheckMFA completes login.
+//Hash made by outdated algorithm or parameters is upgraded, so Authenticate upgrades it on login.
+func (u *UserService) CheckCredentials(ctx context.Context, login, password string) (*model.User, error) {
 	fields := store.UserFields{
 		UserName:         true,
 		Email:            true,
@@ -182,47 +232,244 @@ func (u *UserService) Authenticate(ctx context.Context, login, password, clientI
 		UserPasswordHash: true,
 	}
 
+	//Throttled logins and unknown users get the same error as wrong password after the same
+	//password check against dummy hash, so neither response nor its time reveals lockout or login
+	ip := contextIP(ctx)
+	if len(ip) > 0 && u.loginBlocked(ctx, ipAttemptsKey(ip)) {
+		log.Printf("Login from %s is throttled.", ip)
+		u.passwords.VerifyDummy(password)
+		return nil, errors.ErrInvalidPasswordOrUsername.New("")
+	}
 	user, err := u.FindUserByLogin(ctx, login, &fields)
 	if err != nil {
 		switch errors.GetType(err) {
 		case errors.ErrInvalidArgument:
-			return nil, nil, errors.ErrInvalidPasswordOrUsername.New("")
+			u.passwords.VerifyDummy(password)
+			u.loginFailedBy(ctx, nil)
+			return nil, errors.ErrInvalidPasswordOrUsername.New("")
 		}
-		return nil, nil, err
+		return nil, err
 	}
-	err = u.compareHashAndPassword([]byte(user.PasswordHash), []byte(password))
-
+	if u.loginBlocked(ctx, accountAttemptsKey(user.ID)) {
+		log.Printf("Login of user %s is throttled.", user.ID)
+		u.passwords.VerifyDummy(password)
+		return nil, errors.ErrInvalidPasswordOrUsername.New("")
+	}
+	ok, rehash, err := u.passwords.Verify(user.PasswordHash, password)
 	if err != nil {
-		return nil, nil, errors.ErrInvalidPasswordOrUsername.New("")
+		log.Printf("Err in check password of user %s. Err: %s", user.ID, err.Error())
+	}
+	if !ok {
+		u.loginFailedBy(ctx, user)
+		return nil, errors.ErrInvalidPasswordOrUsername.New("")
+	}
+	if rehash {
+		u.rehashPassword(ctx, user.ID, password)
 	}
 	user.Sanitize()
+	return user, nil
+}
 
+//rehashPassword replaces hash of valid password by hash of preferred algorithm, login doesn't fail if it fails
+func (u *UserService) rehashPassword(ctx context.Context, userID, password string) {
+	passHash, err := u.hashUserPassword(password)
+	if err == nil {
+		err = u.store.User().SetPasswordHash(ctx, userID, passHash, 0)
+	}
+	if err != nil {
+		log.Printf("Err in rehash password of user %s. Err: %s", userID, err.Error())
+	}
+}
+
+//CreateIdentity creates a session of authenticated user and issues tokens for the client
+func (u *UserService) CreateIdentity(ctx context.Context, user *model.User, client *model.Client, scope string) (*model.Identity, error) {
+	ctx = context.WithValue(ctx, cfg.ContextClientIDKey, client.ID)
 	sessionID, err := u.store.User().CreateSession(ctx, user.ID)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 	refTokenString := generateRefreshToken()
 
 	refToken := model.ClientRefToken{
 		SessionID: sessionID,
+		UserID:    user.ID,
+		Scope:     scope,
 		RefToken:  refTokenString,
-		ExpIn:     time.Now().Add(720 * time.Hour),
+		ExpIn:     time.Now().Add(client.RefreshTokenTTL(refreshTokenTTL)),
 		CreatedAt: time.Now(),
 	}
 
-	err = u.store.Client().CreateRefToken(ctx, clientID, &refToken)
+	err = u.store.Client().CreateRefToken(ctx, client.ID, &refToken)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return user, &refToken, nil
+	authToken, err := u.generateAccessToken(ctx, client, user.ID, sessionID, scope)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Identity{
+		UserID:    user.ID,
+		UserName:  user.UserName,
+		Email:     user.Email,
+		SessionID: sessionID,
+		Scope:     scope,
+		AuthToken: *authToken,
+		RefToken: model.Token{
+			ExpIn: refToken.ExpIn,
+			Token: refToken.RefToken,
+		},
+	}, nil
 }
 
-func (u *UserService) UpdateRefToken(ctx context.Context, userID, clientID, refToken string) (*model.ClientRefToken, error) {
-	panic("implement me")
+//createClientIdentity loads the client and creates session of user in it
+func (u *UserService) createClientIdentity(ctx context.Context, user *model.User, clientID string) (*model.Identity, error) {
+	client, err := u.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return u.CreateIdentity(ctx, user, client, "")
 }
 
+//generateAccessToken signs access token with effective roles of user for the client and their permissions
+func (u *UserService) generateAccessToken(ctx context.Context, client *model.Client, userID, sessionID, scope string) (*model.Token, error) {
+	roles, err := u.effectiveRoles(ctx, userID, client.ID)
+	if err != nil {
+		return nil, err
+	}
+	claims := &model.AccessClaims{
+		UserID:      userID,
+		ClientID:    client.ID,
+		SessionID:   sessionID,
+		Scope:       scope,
+		Roles:       roles,
+		Permissions: client.Permissions(roles),
+	}
+	return u.tokenService.GenerateAccessToken(ctx, client, claims)
+}
+
+func (u *UserService) UpdateRefToken(ctx context.Context, clientID, refToken string) (*model.Identity, error) {
+	if len(refToken) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Invalid refresh token.")
+	}
+	current, err := u.store.Client().FindRefToken(ctx, clientID, "", refToken)
+	if err != nil {
+		if errors.GetType(err) != errors.ErrInvalidArgument {
+			return nil, err
+		}
+		rotated, findErr := u.store.Client().FindRotatedRefToken(ctx, clientID, refToken)
+		if findErr == nil {
+			u.revokeTokenFamily(ctx, clientID, rotated, model.EventRefreshTokenReuse)
+		}
+		return nil, errors.ErrInvalidArgument.New("Invalid refresh token.")
+	}
+	if current.ExpIn.Before(time.Now()) {
+		err = u.store.Client().DeleteSessionRefTokens(ctx, clientID, current.SessionID)
+		if err != nil {
+			log.Printf("Err in delete expired refresh token. Err: %s", err.Error())
+		}
+		return nil, errors.ErrInvalidArgument.New("Refresh token expired.")
+	}
+	err = u.store.User().CheckSession(ctx, current.SessionID)
+	if err != nil {
+		if errors.GetType(err) != errors.ErrInvalidArgument {
+			return nil, err
+		}
+		//Session already closed, refresh token is orphan
+		err = u.store.Client().DeleteSessionRefTokens(ctx, clientID, current.SessionID)
+		if err != nil {
+			log.Printf("Err in delete orphan refresh token. Err: %s", err.Error())
+		}
+		return nil, errors.ErrInvalidArgument.New("Invalid refresh token.")
+	}
+
+	client, err := u.store.Client().FindById(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	newRefToken := model.ClientRefToken{
+		SessionID: current.SessionID,
+		UserID:    current.UserID,
+		Scope:     current.Scope,
+		RefToken:  generateRefreshToken(),
+		ExpIn:     time.Now().Add(client.RefreshTokenTTL(refreshTokenTTL)),
+		CreatedAt: time.Now(),
+	}
+	err = u.store.Client().RotateRefToken(ctx, clientID, refToken, &newRefToken)
+	if err != nil {
+		if errors.GetType(err) != errors.ErrInvalidArgument {
+			return nil, err
+		}
+		//Token was rotated by concurrent request, so it is reused
+		u.revokeTokenFamily(ctx, clientID, current, model.EventRefreshTokenReuse)
+		return nil, errors.ErrInvalidArgument.New("Invalid refresh token.")
+	}
+
+	authToken, err := u.generateAccessToken(ctx, client, current.UserID, current.SessionID, current.Scope)
+	if err != nil {
+		return nil, err
+	}
+	return &model.Identity{
+		UserID:    current.UserID,
+		SessionID: current.SessionID,
+		Scope:     current.Scope,
+		AuthToken: *authToken,
+		RefToken: model.Token{
+			ExpIn: newRefToken.ExpIn,
+			Token: newRefToken.RefToken,
+		},
+	}, nil
+}
+
+//revokeTokenFamily deletes the session with all its refresh tokens and records a security event
+func (u *UserService) revokeTokenFamily(ctx context.Context, clientID string, refToken *model.ClientRefToken, eventType string) {
+	err := u.store.Client().DeleteSessionRefTokens(ctx, clientID, refToken.SessionID)
+	if err != nil {
+		log.Printf("Err in revoke refresh tokens of session %s. Err: %s", refToken.SessionID, err.Error())
+	}
+	err = u.store.User().DeleteSession(ctx, refToken.UserID, refToken.SessionID)
+	if err != nil && errors.GetType(err) != errors.ErrInvalidArgument {
+		log.Printf("Err in revoke session %s. Err: %s", refToken.SessionID, err.Error())
+	}
+	event := &model.SecurityEvent{
+		Type:      eventType,
+		UserID:    refToken.UserID,
+		ClientID:  clientID,
+		SessionID: refToken.SessionID,
+		CreatedAt: time.Now(),
+	}
+	err = u.store.Event().Create(ctx, event)
+	if err != nil {
+		log.Printf("Err in record security event %s. Err: %s", eventType, err.Error())
+	}
+	log.Printf("Security event %s. User: %s, session: %s.", eventType, refToken.UserID, refToken.SessionID)
+}
+
+//SignOut removes the session together with its refresh token
 func (u *UserService) SignOut(ctx context.Context, userID, sessionID string) error {
-	panic("implement me")
+	session, err := u.store.User().FindSession(ctx, userID, sessionID)
+	if err != nil {
+		return err
+	}
+	err = u.store.Client().DeleteSessionRefTokens(ctx, session.ClientID, session.SessionID)
+	if err != nil && errors.GetType(err) != errors.ErrInvalidArgument {
+		return err
+	}
+	return u.store.User().DeleteSession(ctx, userID, sessionID)
+}
+
+//SignOutEverywhere removes all user sessions except the exceptSessionID
+func (u *UserService) SignOutEverywhere(ctx context.Context, userID, exceptSessionID string) error {
+	sessions, err := u.store.User().DeleteSessions(ctx, userID, exceptSessionID)
+	if err != nil {
+		return err
+	}
+	for _, session := range sessions {
+		err = u.store.Client().DeleteSessionRefTokens(ctx, session.ClientID, session.SessionID)
+		if err != nil && errors.GetType(err) != errors.ErrInvalidArgument {
+			log.Printf("Err in revoke refresh tokens of session %s. Err: %s", session.SessionID, err.Error())
+		}
+	}
+	return nil
 }
 
 func (u *UserService) GenerateEmailConfToken(ctx context.Context, userID string) (string, error) {
@@ -0,0 +1,294 @@
+package user_service
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/internal/app/store"
+	errors "auth-server/pkg/errors/types"
+	"auth-server/pkg/webauthn"
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"time"
+)
+
+//relyingParty returns WebAuthn relying party, by default it is the application domain
+func relyingParty() *webauthn.RelyingParty {
+	rp := &webauthn.RelyingParty{
+		ID:   cfg.Cfg.WebAuthnRPID,
+		Name: cfg.Cfg.MFAIssuer,
+	}
+	for _, v := range strings.Split(cfg.Cfg.WebAuthnOrigins, ",") {
+		if v = strings.TrimSpace(v); len(v) > 0 {
+			rp.Origins = append(rp.Origins, v)
+		}
+	}
+	if app, err := url.Parse(cfg.Cfg.AppLink); err == nil {
+		if len(rp.ID) == 0 {
+			rp.ID = app.Hostname()
+		}
+		if len(rp.Origins) == 0 {
+			rp.Origins = []string{app.Scheme + "://" + app.Host}
+		}
+	}
+	return rp
+}
+
+//newWebAuthnChallenge saves challenge of the ceremony
+func (u *UserService) newWebAuthnChallenge(ctx context.Context, purpose, userID, clientID string) (string, error) {
+	value, err := webauthn.NewChallenge()
+	if err != nil {
+		return "", err
+	}
+	challenge := &model.WebAuthnChallenge{
+		Challenge: value,
+		Purpose:   purpose,
+		UserID:    userID,
+		ClientID:  clientID,
+		ExpIn:     time.Now().Add(model.WebAuthnChallengeTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := u.store.WebAuthnChallenge().Create(ctx, challenge); err != nil {
+		return "", err
+	}
+	return challenge.Challenge, nil
+}
+
+//consumeWebAuthnChallenge returns the challenge which client data answers, it must belong to the ceremony
+func (u *UserService) consumeWebAuthnChallenge(ctx context.Context, clientDataJSON, purpose string) (*model.WebAuthnChallenge, error) {
+	clientData, _, err := webauthn.ParseClientData(clientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := u.store.WebAuthnChallenge().Consume(ctx, clientData.Challenge)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != purpose {
+		return nil, errors.ErrUnauthorized.New("Invalid or expired WebAuthn challenge.")
+	}
+	return challenge, nil
+}
+
+//credentialDescriptors returns the credentials of user to allow or exclude in options
+func credentialDescriptors(credentials []model.WebAuthnCredential) []webauthn.CredentialDescriptor {
+	descriptors := make([]webauthn.CredentialDescriptor, 0, len(credentials))
+	for _, v := range credentials {
+		descriptors = append(descriptors, webauthn.CredentialDescriptor{
+			Type:       "public-key",
+			ID:         v.ID,
+			Transports: v.Transports,
+		})
+	}
+	return descriptors
+}
+
+//BeginWebAuthnRegistration returns options to create a passkey of user
+func (u *UserService) BeginWebAuthnRegistration(ctx context.Context, userID string) (*webauthn.CreationOptions, error) {
+	user, err := u.FindUserByID(ctx, userID, &store.UserFields{UserName: true, Email: true})
+	if err != nil {
+		return nil, err
+	}
+	credentials, err := u.store.User().FindWebAuthnCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := u.newWebAuthnChallenge(ctx, model.WebAuthnRegistration, userID, "")
+	if err != nil {
+		return nil, err
+	}
+	rp := relyingParty()
+	options := &webauthn.CreationOptions{
+		Challenge:          challenge,
+		PubKeyCredParams:   webauthn.PublicKeyCredentialParameters(),
+		Timeout:            model.WebAuthnChallengeTTL.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(credentials),
+		Attestation:        webauthn.AttestationNone,
+	}
+	options.RP.ID, options.RP.Name = rp.ID, rp.Name
+	//User handle is the user ID, so discoverable credentials identify user
+	options.User.ID = webauthnUserHandle(userID)
+	options.User.Name, options.User.DisplayName = user.UserName, user.UserName
+	options.AuthenticatorSelection.ResidentKey = "preferred"
+	options.AuthenticatorSelection.UserVerification = webauthn.UserVerificationPreferred
+	return options, nil
+}
+
+//FinishWebAuthnRegistration checks authenticator response and saves the passkey
+func (u *UserService) FinishWebAuthnRegistration(ctx context.Context, userID, name string, resp *webauthn.AttestationResponse) (*model.WebAuthnCredential, error) {
+	if resp == nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid credential.")
+	}
+	challenge, err := u.consumeWebAuthnChallenge(ctx, resp.Response.ClientDataJSON, model.WebAuthnRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.UserID != userID {
+		return nil, errors.ErrUnauthorized.New("Invalid or expired WebAuthn challenge.")
+	}
+	verified, err := relyingParty().VerifyRegistration(challenge.Challenge, resp, false)
+	if err != nil {
+		return nil, err
+	}
+	credential := &model.WebAuthnCredential{
+		ID:                verified.ID,
+		Name:              name,
+		PublicKey:         verified.PublicKey,
+		SignCount:         verified.SignCount,
+		AttestationFormat: verified.AttestationFormat,
+		AAGUID:            verified.AAGUID,
+		Transports:        verified.Transports,
+		BackupEligible:    verified.BackupEligible,
+	}
+	if err = u.store.User().AddWebAuthnCredential(ctx, userID, credential); err != nil {
+		return nil, err
+	}
+	u.recordEvent(ctx, model.EventWebAuthnRegistered, userID, "")
+	return credential, nil
+}
+
+func (u *UserService) FindWebAuthnCredentials(ctx context.Context, userID string) ([]model.WebAuthnCredential, error) {
+	return u.store.User().FindWebAuthnCredentials(ctx, userID)
+}
+
+func (u *UserService) DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error {
+	return u.store.User().DeleteWebAuthnCredential(ctx, userID, credentialID)
+}
+
+//BeginWebAuthnLogin returns options of passwordless login.
+//Without login any discoverable credential may be used, unknown login isn't revealed.
+func (u *UserService) BeginWebAuthnLogin(ctx context.Context, clientID, login string) (*webauthn.RequestOptions, error) {
+	var credentials []model.WebAuthnCredential
+	if len(login) > 0 {
+		user, err := u.FindUserByLogin(ctx, login, nil)
+		switch {
+		case err == nil:
+			credentials, err = u.store.User().FindWebAuthnCredentials(ctx, user.ID)
+			if err != nil {
+				return nil, err
+			}
+		case errors.GetType(err) != errors.ErrInvalidArgument:
+			return nil, err
+		}
+	}
+	challenge, err := u.newWebAuthnChallenge(ctx, model.WebAuthnLogin, "", clientID)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthn.RequestOptions{
+		Challenge:        challenge,
+		Timeout:          model.WebAuthnChallengeTTL.Milliseconds(),
+		RPID:             relyingParty().ID,
+		AllowCredentials: credentialDescriptors(credentials),
+		UserVerification: webauthn.UserVerificationRequired,
+	}, nil
+}
+
+//FinishWebAuthnLogin creates a session if assertion is valid.
+//User verification is required, so passkey replaces both password and second factor.
+func (u *UserService) FinishWebAuthnLogin(ctx context.Context, clientID string, resp *webauthn.AssertionResponse) (*model.Identity, error) {
+	if resp == nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid credential.")
+	}
+	challenge, err := u.consumeWebAuthnChallenge(ctx, resp.Response.ClientDataJSON, model.WebAuthnLogin)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.ClientID != clientID {
+		return nil, errors.ErrUnauthorized.New("Invalid or expired WebAuthn challenge.")
+	}
+	userID, err := u.verifyAssertion(ctx, challenge.Challenge, resp, true)
+	if err != nil {
+		return nil, err
+	}
+	user, err := u.FindUserByID(ctx, userID, &store.UserFields{UserName: true, Email: true})
+	if err != nil {
+		return nil, err
+	}
+	u.loginSucceeded(ctx, user.ID)
+	return u.createClientIdentity(ctx, user, clientID)
+}
+
+//BeginWebAuthnMFA returns options to answer MFA challenge by passkey
+func (u *UserService) BeginWebAuthnMFA(ctx context.Context, mfaToken, clientID string) (*webauthn.RequestOptions, error) {
+	mfa, err := u.store.MFAChallenge().Find(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+	if mfa.ClientID != clientID {
+		return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+	}
+	credentials, err := u.store.User().FindWebAuthnCredentials(ctx, mfa.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if len(credentials) == 0 {
+		return nil, errors.ErrInvalidArgument.New("User has no passkeys.")
+	}
+	challenge, err := u.newWebAuthnChallenge(ctx, model.WebAuthnMFA, mfa.UserID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	return &webauthn.RequestOptions{
+		Challenge:        challenge,
+		Timeout:          model.WebAuthnChallengeTTL.Milliseconds(),
+		RPID:             relyingParty().ID,
+		AllowCredentials: credentialDescriptors(credentials),
+		UserVerification: webauthn.UserVerificationPreferred,
+	}, nil
+}
+
+//verifyWebAuthnMFA checks passkey assertion which answers MFA challenge of user
+func (u *UserService) verifyWebAuthnMFA(ctx context.Context, userID, clientID string, resp *webauthn.AssertionResponse) error {
+	challenge, err := u.consumeWebAuthnChallenge(ctx, resp.Response.ClientDataJSON, model.WebAuthnMFA)
+	if err != nil {
+		return err
+	}
+	if challenge.UserID != userID || challenge.ClientID != clientID {
+		return errors.ErrUnauthorized.New("Invalid or expired WebAuthn challenge.")
+	}
+	credentialOwner, err := u.verifyAssertion(ctx, challenge.Challenge, resp, false)
+	if err != nil {
+		return err
+	}
+	if credentialOwner != userID {
+		return errors.ErrUnauthorized.New("Unknown credential.")
+	}
+	return nil
+}
+
+//verifyAssertion checks assertion of registered credential, updates its sign counter and returns the owner
+func (u *UserService) verifyAssertion(ctx context.Context, challenge string, resp *webauthn.AssertionResponse, requireUV bool) (string, error) {
+	userID, credential, err := u.store.User().FindWebAuthnCredential(ctx, resp.ID)
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Response.UserHandle) > 0 && resp.Response.UserHandle != webauthnUserHandle(userID) {
+		return "", errors.ErrUnauthorized.New("Invalid user handle.")
+	}
+	signCount, err := relyingParty().VerifyAssertion(challenge, resp, &webauthn.Credential{
+		ID:        credential.ID,
+		PublicKey: credential.PublicKey,
+		SignCount: credential.SignCount,
+	}, requireUV)
+	if err != nil {
+		if errors.GetType(err) == errors.ErrUnauthorized {
+			u.recordEvent(ctx, model.EventWebAuthnFailed, userID, "")
+		}
+		return "", err
+	}
+	ok, err := u.store.User().UpdateWebAuthnSignCount(ctx, userID, credential.ID, credential.SignCount, signCount)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", errors.ErrUnauthorized.New("Credential was used concurrently.")
+	}
+	return userID, nil
+}
+
+//webauthnUserHandle returns base64url encoded user handle of user
+func webauthnUserHandle(userID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(userID))
+}
@@ -0,0 +1,166 @@
+//Package file_store represent repositories which keep data on disk
+package file_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const keyFileExt = ".json"
+
+//signingKey is a content of key file
+type signingKey struct {
+	ID         string     `json:"kid"`
+	Algorithm  string     `json:"alg"`
+	PrivateKey string     `json:"private_key"`
+	Active     bool       `json:"active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpIn      *time.Time `json:"exp_in,omitempty"`
+}
+
+//KeyRepo keeps each signing key in separate file of directory
+type KeyRepo struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewKeyRepo(dir string) (*KeyRepo, error) {
+	if len(dir) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Key store directory not be null.")
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, errors.NoType.Wrapf(err, "Err create key store directory %s.", dir)
+	}
+	return &KeyRepo{dir: dir}, nil
+}
+
+func (k *KeyRepo) Create(ctx context.Context, key *model.SigningKey) error {
+	if key == nil || len(key.ID) == 0 || len(key.PrivateKey) == 0 ||
+		strings.ContainsAny(key.ID, `/\`) {
+		return errors.ErrInvalidArgument.New("Invalid signing key.")
+	}
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, err := os.Stat(k.path(key.ID)); err == nil {
+		return errors.ErrDuplicateEntry.New("Signing key already exists.")
+	}
+	return k.write(toFileKey(key))
+}
+
+func (k *KeyRepo) FindValid(ctx context.Context) ([]model.SigningKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fileKeys, err := k.readAll()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	keys := []model.SigningKey{}
+	for _, fileKey := range fileKeys {
+		key := toSigningKey(fileKey)
+		if !key.IsValid(now) {
+			//expired keys are removed like by TTL index
+			os.Remove(k.path(key.ID))
+			continue
+		}
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (k *KeyRepo) Retire(ctx context.Context, exceptID string, expIn time.Time) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	fileKeys, err := k.readAll()
+	if err != nil {
+		return err
+	}
+	for _, fileKey := range fileKeys {
+		if fileKey.ID == exceptID {
+			continue
+		}
+		if !fileKey.Active && fileKey.ExpIn != nil && !fileKey.ExpIn.After(expIn) {
+			continue
+		}
+		exp := expIn
+		fileKey.Active = false
+		fileKey.ExpIn = &exp
+		if err := k.write(fileKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (k *KeyRepo) path(id string) string {
+	return filepath.Join(k.dir, id+keyFileExt)
+}
+
+func (k *KeyRepo) readAll() ([]*signingKey, error) {
+	files, err := ioutil.ReadDir(k.dir)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "Err read key store directory.")
+	}
+	keys := []*signingKey{}
+	for _, file := range files {
+		if file.IsDir() || filepath.Ext(file.Name()) != keyFileExt {
+			continue
+		}
+		data, err := ioutil.ReadFile(filepath.Join(k.dir, file.Name()))
+		if err != nil {
+			return nil, errors.NoType.Wrapf(err, "Err read key file %s.", file.Name())
+		}
+		key := &signingKey{}
+		if err := json.Unmarshal(data, key); err != nil {
+			return nil, errors.NoType.Wrapf(err, "Err decode key file %s.", file.Name())
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+//write replaces key file atomically, so readers never see partial key
+func (k *KeyRepo) write(key *signingKey) error {
+	data, err := json.Marshal(key)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	tmp := k.path(key.ID) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return errors.NoType.Wrap(err, "Err write key file.")
+	}
+	if err := os.Rename(tmp, k.path(key.ID)); err != nil {
+		return errors.NoType.Wrap(err, "Err write key file.")
+	}
+	return nil
+}
+
+func toFileKey(key *model.SigningKey) *signingKey {
+	return &signingKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: key.PrivateKey,
+		Active:     key.Active,
+		CreatedAt:  key.CreatedAt,
+		ExpIn:      key.ExpIn,
+	}
+}
+
+func toSigningKey(key *signingKey) *model.SigningKey {
+	return &model.SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: key.PrivateKey,
+		Active:     key.Active,
+		CreatedAt:  key.CreatedAt,
+		ExpIn:      key.ExpIn,
+	}
+}
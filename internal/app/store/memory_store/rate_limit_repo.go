@@ -0,0 +1,96 @@
+//Package memory_store represent repositories which keep data in memory of one instance
+package memory_store
+
+import (
+	"auth-server/internal/app/model"
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+//Defaults of bucket storage. Buckets of the least recently used keys are evicted above the maximum,
+//evicted bucket is recreated full, so eviction can only let the key make more requests.
+const (
+	DefaultMaxBuckets    = 100000
+	DefaultSweepInterval = time.Minute
+)
+
+//RateLimitRepo keeps token buckets in memory, it is suitable only for single instance
+type RateLimitRepo struct {
+	mu         sync.Mutex
+	maxBuckets int
+	buckets    map[string]*list.Element
+	//recent is a list of buckets ordered by the last use, the most recent is the first
+	recent *list.List
+}
+
+type bucket struct {
+	model.TokenBucket
+	key    string
+	fullAt time.Time
+}
+
+func NewRateLimitRepo(maxBuckets int) *RateLimitRepo {
+	if maxBuckets <= 0 {
+		maxBuckets = DefaultMaxBuckets
+	}
+	return &RateLimitRepo{
+		maxBuckets: maxBuckets,
+		buckets:    make(map[string]*list.Element),
+		recent:     list.New(),
+	}
+}
+
+func (r *RateLimitRepo) Take(ctx context.Context, key string, rate float64, burst int) (*model.TokenBucket, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	element, ok := r.buckets[key]
+	if ok {
+		r.recent.MoveToFront(element)
+	} else {
+		if r.recent.Len() >= r.maxBuckets {
+			r.remove(r.recent.Back())
+		}
+		element = r.recent.PushFront(&bucket{TokenBucket: *model.NewTokenBucket(burst, now), key: key})
+		r.buckets[key] = element
+	}
+	b := element.Value.(*bucket)
+	allowed := b.Take(rate, burst, now)
+	b.fullAt = now.Add(b.FullIn(rate, burst))
+	result := b.TokenBucket
+	return &result, allowed, nil
+}
+
+//Run removes full buckets every interval till context is done
+func (r *RateLimitRepo) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			r.sweep(now)
+		}
+	}
+}
+
+//sweep removes buckets which are full, they are the same as new ones
+func (r *RateLimitRepo) sweep(now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for element := r.recent.Front(); element != nil; {
+		next := element.Next()
+		if !now.Before(element.Value.(*bucket).fullAt) {
+			r.remove(element)
+		}
+		element = next
+	}
+}
+
+func (r *RateLimitRepo) remove(element *list.Element) {
+	r.recent.Remove(element)
+	delete(r.buckets, element.Value.(*bucket).key)
+}
@@ -0,0 +1,83 @@
+package memory_store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitRepoTake(t *testing.T) {
+	r := NewRateLimitRepo(10)
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, allowed, _ := r.Take(ctx, "ip:1", 1, 2); !allowed {
+			t.Fatalf("request %d rejected", i+1)
+		}
+	}
+	bucket, allowed, _ := r.Take(ctx, "ip:1", 1, 2)
+	if allowed || bucket.Tokens >= 1 {
+		t.Fatalf("request above burst: allowed %v, tokens %v", allowed, bucket.Tokens)
+	}
+	if _, allowed, _ = r.Take(ctx, "ip:2", 1, 2); !allowed {
+		t.Fatal("request of another key rejected")
+	}
+}
+
+func TestRateLimitRepoEvictsLeastRecentlyUsed(t *testing.T) {
+	r := NewRateLimitRepo(2)
+	ctx := context.Background()
+	r.Take(ctx, "a", 1, 1)
+	r.Take(ctx, "b", 1, 1)
+	//a is used after b, so b is evicted by c
+	r.Take(ctx, "a", 1, 1)
+	r.Take(ctx, "c", 1, 1)
+	if r.recent.Len() != 2 || len(r.buckets) != 2 {
+		t.Fatalf("%d buckets in list and %d in map, want 2", r.recent.Len(), len(r.buckets))
+	}
+	if _, ok := r.buckets["b"]; ok {
+		t.Fatal("recently used bucket evicted instead of b")
+	}
+	if _, allowed, _ := r.Take(ctx, "a", 1, 1); allowed {
+		t.Fatal("bucket a was evicted")
+	}
+}
+
+func TestRateLimitRepoSweep(t *testing.T) {
+	r := NewRateLimitRepo(10)
+	ctx := context.Background()
+	r.Take(ctx, "fast", 1000, 1)
+	r.Take(ctx, "slow", 0.001, 1)
+	r.sweep(time.Now().Add(time.Second))
+	if _, ok := r.buckets["fast"]; ok {
+		t.Fatal("full bucket isn't swept")
+	}
+	if _, ok := r.buckets["slow"]; !ok || r.recent.Len() != 1 {
+		t.Fatal("bucket which isn't full is swept")
+	}
+}
+
+func TestRateLimitRepoRun(t *testing.T) {
+	r := NewRateLimitRepo(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	r.Take(ctx, "fast", 1000, 1)
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(time.Second)
+	for {
+		r.mu.Lock()
+		n := r.recent.Len()
+		r.mu.Unlock()
+		if n == 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("bucket isn't swept by timer")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+}
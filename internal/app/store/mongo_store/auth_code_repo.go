@@ -0,0 +1,163 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//AuthCode represent the "AuthCodes" collection, only hash of code is stored
+type AuthCode struct {
+	CodeHash            string             `bson:"_id"`
+	ClientID            primitive.ObjectID `bson:"client_id,omitempty"`
+	UserID              primitive.ObjectID `bson:"user_id,omitempty"`
+	RedirectURI         string             `bson:"redirect_uri,omitempty"`
+	Scope               string             `bson:"scope,omitempty"`
+	Nonce               string             `bson:"nonce,omitempty"`
+	AuthTime            primitive.DateTime `bson:"auth_time,omitempty"`
+	CodeChallenge       string             `bson:"code_challenge,omitempty"`
+	CodeChallengeMethod string             `bson:"code_challenge_method,omitempty"`
+	Pending             bool               `bson:"pending,omitempty"`
+	ExpIn               primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt           primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type AuthCodeRepo struct {
+	store    *Store
+	codesCol *mongo.Collection
+}
+
+func hashCode(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+func (a *AuthCodeRepo) Create(ctx context.Context, code *model.AuthCode) error {
+	if code == nil || len(code.Code) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid authorization code.")
+	}
+	_, err := a.codesCol.InsertOne(ctx, ToDbAuthCode(code))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Authorization code already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (a *AuthCodeRepo) Consume(ctx context.Context, code string) (*model.AuthCode, error) {
+	query := bson.M{
+		"_id":     hashCode(code),
+		"pending": bson.M{"$ne": true},
+		"exp_in": bson.M{
+			"$gt": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+	var dbCode *AuthCode
+	err := a.codesCol.FindOneAndDelete(ctx, query).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid authorization code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToAuthCode(dbCode)
+	result.Code = code
+	return result, nil
+}
+
+//Approve makes pending code exchangeable till expIn
+func (a *AuthCodeRepo) Approve(ctx context.Context, code string, expIn time.Time) (*model.AuthCode, error) {
+	query := bson.M{
+		"_id":     hashCode(code),
+		"pending": true,
+		"exp_in": bson.M{
+			"$gt": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+	update := bson.M{
+		"$set":   bson.M{"exp_in": primitive.NewDateTimeFromTime(expIn)},
+		"$unset": bson.M{"pending": ""},
+	}
+	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var dbCode *AuthCode
+	err := a.codesCol.FindOneAndUpdate(ctx, query, update, options).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid authorization code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToAuthCode(dbCode)
+	result.Code = code
+	return result, nil
+}
+
+//DeletePending removes pending code which user denied
+func (a *AuthCodeRepo) DeletePending(ctx context.Context, code string) (*model.AuthCode, error) {
+	query := bson.M{
+		"_id":     hashCode(code),
+		"pending": true,
+	}
+	var dbCode *AuthCode
+	err := a.codesCol.FindOneAndDelete(ctx, query).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid authorization code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToAuthCode(dbCode)
+	result.Code = code
+	return result, nil
+}
+
+func ToDbAuthCode(code *model.AuthCode) *AuthCode {
+	clientID, _ := primitive.ObjectIDFromHex(code.ClientID)
+	userID, _ := primitive.ObjectIDFromHex(code.UserID)
+	return &AuthCode{
+		CodeHash:            hashCode(code.Code),
+		ClientID:            clientID,
+		UserID:              userID,
+		RedirectURI:         code.RedirectURI,
+		Scope:               code.Scope,
+		Nonce:               code.Nonce,
+		AuthTime:            primitive.NewDateTimeFromTime(code.AuthTime),
+		CodeChallenge:       code.CodeChallenge,
+		CodeChallengeMethod: code.CodeChallengeMethod,
+		Pending:             code.Pending,
+		ExpIn:               primitive.NewDateTimeFromTime(code.ExpIn),
+		CreatedAt:           primitive.NewDateTimeFromTime(code.CreatedAt),
+	}
+}
+
+func ToAuthCode(dbCode *AuthCode) *model.AuthCode {
+	return &model.AuthCode{
+		ClientID:            dbCode.ClientID.Hex(),
+		UserID:              dbCode.UserID.Hex(),
+		RedirectURI:         dbCode.RedirectURI,
+		Scope:               dbCode.Scope,
+		Nonce:               dbCode.Nonce,
+		AuthTime:            dbCode.AuthTime.Time(),
+		CodeChallenge:       dbCode.CodeChallenge,
+		CodeChallengeMethod: dbCode.CodeChallengeMethod,
+		Pending:             dbCode.Pending,
+		ExpIn:               dbCode.ExpIn.Time(),
+		CreatedAt:           dbCode.CreatedAt.Time(),
+	}
+}
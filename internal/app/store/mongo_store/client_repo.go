@@ -14,19 +14,40 @@ import (
 //TODO:Testing client repository
 //Client represent the "Clients" collection
 type Client struct {
-	ID         primitive.ObjectID `bson:"_id,omitempty"`
-	ClientName string             `bson:"client_name,omitempty"`
-	RefTokens  []RefToken         `bson:"ref_tokens,omitempty"`
+	ID                      primitive.ObjectID     `bson:"_id,omitempty"`
+	ClientName              string                 `bson:"client_name,omitempty"`
+	LogoURI                 string                 `bson:"logo_uri,omitempty"`
+	RedirectURIs            []string               `bson:"redirect_uris,omitempty"`
+	SecretHash              string                 `bson:"secret_hash,omitempty"`
+	RegistrationTokenHash   string                 `bson:"registration_token_hash,omitempty"`
+	TokenEndpointAuthMethod string                 `bson:"token_endpoint_auth_method,omitempty"`
+	GrantTypes              []string               `bson:"grant_types,omitempty"`
+	Scope                   string                 `bson:"scope,omitempty"`
+	Roles                   []string               `bson:"roles,omitempty"`
+	RoleDefinitions         []model.RoleDefinition `bson:"role_definitions,omitempty"`
+	JWKS                    []model.JSONWebKey     `bson:"jwks,omitempty"`
+	AccessTokenLifetime     int64                  `bson:"access_token_lifetime,omitempty"`
+	RefreshTokenLifetime    int64                  `bson:"refresh_token_lifetime,omitempty"`
+	ServiceAccount          bool                   `bson:"service_account,omitempty"`
+	FirstParty              bool                   `bson:"first_party,omitempty"`
+	CreatedAt               primitive.DateTime     `bson:"created_at,omitempty"`
+	RefTokens               []RefToken             `bson:"ref_tokens,omitempty"`
 }
 
 //RefToken represent  attached "RefTokens" document in "Client"
 type RefToken struct {
-	SessionID primitive.ObjectID `bson:"session_id,omitempty"`
-	RefToken  string             `bson:"ref_token,omitempty"`
-	ExpIn     primitive.DateTime `bson:"exp_in,omitempty"`
-	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+	SessionID     primitive.ObjectID `bson:"session_id,omitempty"`
+	UserID        primitive.ObjectID `bson:"user_id,omitempty"`
+	Scope         string             `bson:"scope,omitempty"`
+	RefToken      string             `bson:"ref_token,omitempty"`
+	ExpIn         primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt     primitive.DateTime `bson:"created_at,omitempty"`
+	RotatedTokens []string           `bson:"rotated_tokens,omitempty"`
 }
 
+//rotatedTokensLimit is a count of previous tokens kept for reuse detection
+const rotatedTokensLimit = 50
+
 type ClientRepo struct {
 	store      store.Store
 	clientsCol *mongo.Collection
@@ -38,7 +59,7 @@ func (c *ClientRepo) fetch(ctx context.Context, query, proj bson.M) (*Client, er
 	if proj != nil {
 		options.SetProjection(proj)
 	}
-	err := c.clientsCol.FindOne(ctx, query, options).Decode(client)
+	err := c.clientsCol.FindOne(ctx, query, options).Decode(&client)
 	return client, err
 }
 
@@ -51,9 +72,12 @@ func (c *ClientRepo) FindById(ctx context.Context, id string) (*model.Client, er
 	query := bson.M{
 		"_id": oid,
 	}
+	proj := bson.M{
+		"ref_tokens": 0,
+	}
 
 	var client *Client
-	client, err = c.fetch(ctx, query, nil)
+	client, err = c.fetch(ctx, query, proj)
 
 	if err != nil {
 		switch err {
@@ -66,19 +90,130 @@ func (c *ClientRepo) FindById(ctx context.Context, id string) (*model.Client, er
 	return ToClient(client), nil
 }
 
-func (c *ClientRepo) FindRefToken(ctx context.Context, clientID, sessionID, refToken string) (*model.ClientRefToken, error) {
-	clientObjID, err := primitive.ObjectIDFromHex(clientID)
+func (c *ClientRepo) Create(ctx context.Context, client *model.Client) (string, error) {
+	if client == nil || len(client.ClientName) == 0 {
+		return "", errors.ErrInvalidArgument.New("Invalid client.")
+	}
+	dbClient := ToDbClient(client)
+	dbClient.ID = primitive.NilObjectID
+	res, err := c.clientsCol.InsertOne(ctx, dbClient)
 	if err != nil {
-		return nil, errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+		if isDuplicateKeyError(err) {
+			return "", errors.ErrDuplicateEntry.New("Client already exists.")
+		}
+		return "", errors.NoType.Wrap(err, "")
 	}
-	sessionObjID, err := primitive.ObjectIDFromHex(sessionID)
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+//FindAll returns all registered clients without refresh tokens
+func (c *ClientRepo) FindAll(ctx context.Context) ([]model.Client, error) {
+	return c.find(ctx, bson.M{})
+}
+
+//FindServiceAccounts returns the clients of service accounts
+func (c *ClientRepo) FindServiceAccounts(ctx context.Context) ([]model.Client, error) {
+	return c.find(ctx, bson.M{"service_account": true})
+}
+
+func (c *ClientRepo) find(ctx context.Context, query bson.M) ([]model.Client, error) {
+	options := options.Find().SetProjection(bson.M{
+		"ref_tokens": 0,
+	})
+	cur, err := c.clientsCol.Find(ctx, query, options)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var dbClients []Client
+	if err = cur.All(ctx, &dbClients); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	clients := make([]model.Client, 0, len(dbClients))
+	for i := range dbClients {
+		clients = append(clients, *ToClient(&dbClients[i]))
+	}
+	return clients, nil
+}
+
+//Update replaces the registration data of client, secret and refresh tokens are kept
+func (c *ClientRepo) Update(ctx context.Context, client *model.Client) error {
+	oid, err := primitive.ObjectIDFromHex(client.ID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", client.ID)
+	}
+	dbClient := ToDbClient(client)
+	update := bson.M{
+		"$set": bson.M{
+			"client_name":                dbClient.ClientName,
+			"logo_uri":                   dbClient.LogoURI,
+			"redirect_uris":              dbClient.RedirectURIs,
+			"token_endpoint_auth_method": dbClient.TokenEndpointAuthMethod,
+			"secret_hash":                dbClient.SecretHash,
+			"grant_types":                dbClient.GrantTypes,
+			"scope":                      dbClient.Scope,
+			"roles":                      dbClient.Roles,
+			"role_definitions":           dbClient.RoleDefinitions,
+			"jwks":                       dbClient.JWKS,
+			"access_token_lifetime":      dbClient.AccessTokenLifetime,
+			"refresh_token_lifetime":     dbClient.RefreshTokenLifetime,
+			"first_party":                dbClient.FirstParty,
+		},
+	}
+	res, err := c.clientsCol.UpdateOne(ctx, bson.M{"_id": oid}, update)
 	if err != nil {
-		return nil, errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", client.ID)
+	}
+	return nil
+}
+
+func (c *ClientRepo) UpdateSecret(ctx context.Context, clientID, secretHash string) error {
+	oid, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"secret_hash": secretHash,
+		},
+	}
+	res, err := c.clientsCol.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	return nil
+}
+
+func (c *ClientRepo) Delete(ctx context.Context, clientID string) error {
+	oid, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	res, err := c.clientsCol.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.DeletedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	return nil
+}
+
+func (c *ClientRepo) findRefTokenBy(ctx context.Context, clientID string, match bson.M) (*model.ClientRefToken, error) {
+	clientObjID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
 	}
 	query := bson.M{
-		"_id":                   clientObjID,
-		"ref_tokens.session_id": sessionObjID,
-		"ref_tokens.ref_token":  refToken,
+		"_id": clientObjID,
+		"ref_tokens": bson.M{
+			"$elemMatch": match,
+		},
 	}
 	proj := bson.M{
 		"ref_tokens.$": 1,
@@ -92,10 +227,29 @@ func (c *ClientRepo) FindRefToken(ctx context.Context, clientID, sessionID, refT
 			return nil, errors.NoType.Wrap(err, "")
 		}
 	}
+	if len(client.RefTokens) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Invalid refToken")
+	}
+	return ToClientRefToken(&client.RefTokens[0]), nil
+}
 
-	rToken := (*client).RefTokens[0]
+//FindRefToken returns the current refresh token of session.
+//Session ID may be empty, e.g. for introspection, refresh tokens are unique so the token is found by value.
+func (c *ClientRepo) FindRefToken(ctx context.Context, clientID, sessionID, refToken string) (*model.ClientRefToken, error) {
+	match := bson.M{"ref_token": refToken}
+	if len(sessionID) > 0 {
+		sessionObjID, err := primitive.ObjectIDFromHex(sessionID)
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
+		}
+		match["session_id"] = sessionObjID
+	}
+	return c.findRefTokenBy(ctx, clientID, match)
+}
 
-	return ToClientRefToken(&rToken), nil
+//FindRotatedRefToken returns the current refresh token of session which already rotated the refToken
+func (c *ClientRepo) FindRotatedRefToken(ctx context.Context, clientID, refToken string) (*model.ClientRefToken, error) {
+	return c.findRefTokenBy(ctx, clientID, bson.M{"rotated_tokens": refToken})
 }
 
 func (c ClientRepo) CheckRefToken(ctx context.Context, clientID, sessionID, refToken string) (bool, error) {
@@ -108,9 +262,13 @@ func (c ClientRepo) CheckRefToken(ctx context.Context, clientID, sessionID, refT
 		return false, errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
 	}
 	query := bson.M{
-		"_id":                   clientObjID,
-		"ref_tokens.session_id": sessionObjID,
-		"ref_tokens.ref_token":  refToken,
+		"_id": clientObjID,
+		"ref_tokens": bson.M{
+			"$elemMatch": bson.M{
+				"session_id": sessionObjID,
+				"ref_token":  refToken,
+			},
+		},
 	}
 	proj := bson.M{
 		"_id": 1,
@@ -181,6 +339,48 @@ func (c *ClientRepo) CreateRefToken(ctx context.Context, clientID string, refTok
 	return nil
 }
 
+//RotateRefToken atomically replaces oldRefToken by newRefToken and remembers the old one
+func (c *ClientRepo) RotateRefToken(ctx context.Context, clientID, oldRefToken string, newRefToken *model.ClientRefToken) error {
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(newRefToken.SessionID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid session ID %s", newRefToken.SessionID)
+	}
+	query := bson.M{
+		"_id": clientObjectID,
+		"ref_tokens": bson.M{
+			"$elemMatch": bson.M{
+				"session_id": sessionObjectID,
+				"ref_token":  oldRefToken,
+			},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"ref_tokens.$.ref_token":  newRefToken.RefToken,
+			"ref_tokens.$.exp_in":     primitive.NewDateTimeFromTime(newRefToken.ExpIn),
+			"ref_tokens.$.created_at": primitive.NewDateTimeFromTime(newRefToken.CreatedAt),
+		},
+		"$push": bson.M{
+			"ref_tokens.$.rotated_tokens": bson.M{
+				"$each":  []string{oldRefToken},
+				"$slice": -rotatedTokensLimit,
+			},
+		},
+	}
+	res, err := c.clientsCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 || res.ModifiedCount == 0 {
+		return errors.ErrInvalidArgument.New("Invalid refToken")
+	}
+	return nil
+}
+
 func (c *ClientRepo) DeleteRefToken(ctx context.Context, clientID, sessionID, refToken string) error {
 	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
 	//sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
@@ -194,7 +394,7 @@ func (c *ClientRepo) DeleteRefToken(ctx context.Context, clientID, sessionID, re
 	pullUpdate := bson.M{
 		"$pull": bson.M{
 			"ref_tokens": bson.M{
-				"refToken": refToken,
+				"ref_token": refToken,
 			},
 		},
 	}
@@ -212,12 +412,44 @@ func (c *ClientRepo) DeleteRefToken(ctx context.Context, clientID, sessionID, re
 	return nil
 }
 
+//DeleteSessionRefTokens removes the refresh token of session together with all rotated tokens
+func (c *ClientRepo) DeleteSessionRefTokens(ctx context.Context, clientID, sessionID string) error {
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
+	}
+	query := bson.M{
+		"_id": clientObjectID,
+	}
+	pullUpdate := bson.M{
+		"$pull": bson.M{
+			"ref_tokens": bson.M{
+				"session_id": sessionObjectID,
+			},
+		},
+	}
+	res, err := c.clientsCol.UpdateOne(ctx, query, pullUpdate)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid client id %s", clientID)
+	}
+	return nil
+}
+
 func ToClientRefToken(dbRefToken *RefToken) *model.ClientRefToken {
 	sessionID := dbRefToken.SessionID.Hex()
 	expIn := dbRefToken.ExpIn.Time()
 	createdAt := dbRefToken.CreatedAt.Time()
 	return &model.ClientRefToken{
 		SessionID: sessionID,
+		UserID:    dbRefToken.UserID.Hex(),
+		Scope:     dbRefToken.Scope,
 		RefToken:  dbRefToken.RefToken,
 		ExpIn:     expIn,
 		CreatedAt: createdAt,
@@ -225,11 +457,14 @@ func ToClientRefToken(dbRefToken *RefToken) *model.ClientRefToken {
 }
 func ToDbRefToken(clientRefToken *model.ClientRefToken) *RefToken {
 	sessionID, _ := primitive.ObjectIDFromHex(clientRefToken.SessionID)
+	userID, _ := primitive.ObjectIDFromHex(clientRefToken.UserID)
 	expIn := primitive.NewDateTimeFromTime(clientRefToken.ExpIn)
 	createdAt := primitive.NewDateTimeFromTime(clientRefToken.CreatedAt)
 
 	return &RefToken{
 		SessionID: sessionID,
+		UserID:    userID,
+		Scope:     clientRefToken.Scope,
 		RefToken:  clientRefToken.RefToken,
 		ExpIn:     expIn,
 		CreatedAt: createdAt,
@@ -241,6 +476,8 @@ func ToClient(dbclient *Client) *model.Client {
 	for _, v := range dbclient.RefTokens {
 		refToken := model.ClientRefToken{
 			SessionID: v.SessionID.Hex(),
+			UserID:    v.UserID.Hex(),
+			Scope:     v.Scope,
 			RefToken:  v.RefToken,
 			ExpIn:     v.ExpIn.Time(),
 			CreatedAt: v.CreatedAt.Time(),
@@ -248,9 +485,58 @@ func ToClient(dbclient *Client) *model.Client {
 		tokens = append(tokens, refToken)
 	}
 
-	return &model.Client{
-		ID:               dbclient.ID.Hex(),
-		ClientName:       dbclient.ClientName,
-		ClientsRefTokens: tokens,
-	}
+	client := &model.Client{
+		ID:                      dbclient.ID.Hex(),
+		ClientName:              dbclient.ClientName,
+		LogoURI:                 dbclient.LogoURI,
+		RedirectURIs:            dbclient.RedirectURIs,
+		SecretHash:              dbclient.SecretHash,
+		RegistrationTokenHash:   dbclient.RegistrationTokenHash,
+		TokenEndpointAuthMethod: dbclient.TokenEndpointAuthMethod,
+		GrantTypes:              dbclient.GrantTypes,
+		Scope:                   dbclient.Scope,
+		Roles:                   dbclient.Roles,
+		RoleDefinitions:         dbclient.RoleDefinitions,
+		AccessTokenLifetime:     dbclient.AccessTokenLifetime,
+		RefreshTokenLifetime:    dbclient.RefreshTokenLifetime,
+		ServiceAccount:          dbclient.ServiceAccount,
+		FirstParty:              dbclient.FirstParty,
+		ClientsRefTokens:        tokens,
+	}
+	if len(dbclient.JWKS) > 0 {
+		client.JWKS = &model.JSONWebKeySet{Keys: dbclient.JWKS}
+	}
+	if dbclient.CreatedAt != 0 {
+		createdAt := dbclient.CreatedAt.Time()
+		client.CreatedAt = &createdAt
+	}
+	return client
+}
+
+func ToDbClient(client *model.Client) *Client {
+	id, _ := primitive.ObjectIDFromHex(client.ID)
+	dbClient := &Client{
+		ID:                      id,
+		ClientName:              client.ClientName,
+		LogoURI:                 client.LogoURI,
+		RedirectURIs:            client.RedirectURIs,
+		SecretHash:              client.SecretHash,
+		RegistrationTokenHash:   client.RegistrationTokenHash,
+		TokenEndpointAuthMethod: client.TokenEndpointAuthMethod,
+		GrantTypes:              client.GrantTypes,
+		Scope:                   client.Scope,
+		Roles:                   client.Roles,
+		RoleDefinitions:         client.RoleDefinitions,
+		AccessTokenLifetime:     client.AccessTokenLifetime,
+		RefreshTokenLifetime:    client.RefreshTokenLifetime,
+		ServiceAccount:          client.ServiceAccount,
+		FirstParty:              client.FirstParty,
+	}
+	if client.JWKS != nil {
+		dbClient.JWKS = client.JWKS.Keys
+	}
+	if client.CreatedAt != nil {
+		dbClient.CreatedAt = primitive.NewDateTimeFromTime(*client.CreatedAt)
+	}
+	return dbClient
 }
@@ -0,0 +1,99 @@
+package mongo_store
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//grantClientRole adds the role to the "field" array of ClientRole documents of document with ID.
+//It returns false if document isn't found.
+func grantClientRole(ctx context.Context, col *mongo.Collection, id primitive.ObjectID, field string, clientID primitive.ObjectID, role string) (bool, error) {
+	addToSet := func() (*mongo.UpdateResult, error) {
+		query := bson.M{
+			"_id":                id,
+			field + ".client_id": clientID,
+		}
+		update := bson.M{
+			"$addToSet": bson.M{
+				field + ".$.roles": role,
+			},
+		}
+		return col.UpdateOne(ctx, query, update)
+	}
+	res, err := addToSet()
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+	//Document has no roles in the client yet
+	query := bson.M{
+		"_id":                id,
+		field + ".client_id": bson.M{"$ne": clientID},
+	}
+	update := bson.M{
+		"$push": bson.M{
+			field: ClientRole{
+				ClientID: clientID,
+				Roles:    []string{role},
+			},
+		},
+	}
+	res, err = col.UpdateOne(ctx, query, update)
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount > 0 {
+		return true, nil
+	}
+	//Roles of the client were pushed by concurrent request
+	res, err = addToSet()
+	if err != nil {
+		return false, err
+	}
+	return res.MatchedCount > 0, nil
+}
+
+//revokeClientRole removes the role from the "field" array of ClientRole documents of document with ID.
+//It returns false if role isn't granted.
+func revokeClientRole(ctx context.Context, col *mongo.Collection, id primitive.ObjectID, field string, clientID primitive.ObjectID, role string) (bool, error) {
+	query := bson.M{
+		"_id": id,
+		field: bson.M{
+			"$elemMatch": bson.M{
+				"client_id": clientID,
+				"roles":     role,
+			},
+		},
+	}
+	update := bson.M{
+		"$pull": bson.M{
+			field + ".$.roles": role,
+		},
+	}
+	res, err := col.UpdateOne(ctx, query, update)
+	if err != nil {
+		return false, err
+	}
+	if res.MatchedCount == 0 {
+		return false, nil
+	}
+	//Remove the client if it was the last role
+	cleanup := bson.M{
+		"$pull": bson.M{
+			field: bson.M{
+				"client_id": clientID,
+				"roles":     bson.M{"$size": 0},
+			},
+		},
+	}
+	_, err = col.UpdateOne(ctx, bson.M{"_id": id}, cleanup)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,127 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//Consent represent the "Consents" collection, user has one consent per client
+type Consent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty"`
+	ClientID  primitive.ObjectID `bson:"client_id,omitempty"`
+	Scopes    []string           `bson:"scopes,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+	UpdatedAt primitive.DateTime `bson:"updated_at,omitempty"`
+}
+
+type ConsentRepo struct {
+	store       *Store
+	consentsCol *mongo.Collection
+}
+
+//consentQuery returns the query of consent of user to client
+func consentQuery(userID, clientID string) (bson.M, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	return bson.M{
+		"user_id":   userObjectID,
+		"client_id": clientObjectID,
+	}, nil
+}
+
+func (c *ConsentRepo) Find(ctx context.Context, userID, clientID string) (*model.Consent, error) {
+	query, err := consentQuery(userID, clientID)
+	if err != nil {
+		return nil, err
+	}
+	var consent Consent
+	err = c.consentsCol.FindOne(ctx, query).Decode(&consent)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Consent not found.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToConsent(&consent), nil
+}
+
+func (c *ConsentRepo) FindByUser(ctx context.Context, userID string) ([]model.Consent, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	cur, err := c.consentsCol.Find(ctx, bson.M{"user_id": userObjectID})
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var dbConsents []Consent
+	if err = cur.All(ctx, &dbConsents); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	consents := make([]model.Consent, 0, len(dbConsents))
+	for i := range dbConsents {
+		consents = append(consents, *ToConsent(&dbConsents[i]))
+	}
+	return consents, nil
+}
+
+func (c *ConsentRepo) Grant(ctx context.Context, userID, clientID string, scopes []string) error {
+	query, err := consentQuery(userID, clientID)
+	if err != nil {
+		return err
+	}
+	now := primitive.NewDateTimeFromTime(time.Now())
+	update := bson.M{
+		"$addToSet":    bson.M{"scopes": bson.M{"$each": scopes}},
+		"$set":         bson.M{"updated_at": now},
+		"$setOnInsert": bson.M{"created_at": now},
+	}
+	_, err = c.consentsCol.UpdateOne(ctx, query, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (c *ConsentRepo) Delete(ctx context.Context, userID, clientID string) error {
+	query, err := consentQuery(userID, clientID)
+	if err != nil {
+		return err
+	}
+	res, err := c.consentsCol.DeleteOne(ctx, query)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.DeletedCount == 0 {
+		return errors.ErrInvalidArgument.New("Consent not found.")
+	}
+	return nil
+}
+
+func ToConsent(dbConsent *Consent) *model.Consent {
+	createdAt := dbConsent.CreatedAt.Time()
+	updatedAt := dbConsent.UpdatedAt.Time()
+	return &model.Consent{
+		UserID:    dbConsent.UserID.Hex(),
+		ClientID:  dbConsent.ClientID.Hex(),
+		Scopes:    dbConsent.Scopes,
+		CreatedAt: &createdAt,
+		UpdatedAt: &updatedAt,
+	}
+}
@@ -0,0 +1,210 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//DeviceCode represent the "DeviceCodes" collection, only hash of device code is stored
+type DeviceCode struct {
+	CodeHash     string             `bson:"_id"`
+	UserCode     string             `bson:"user_code,omitempty"`
+	ClientID     primitive.ObjectID `bson:"client_id,omitempty"`
+	Scope        string             `bson:"scope,omitempty"`
+	Status       string             `bson:"status,omitempty"`
+	UserID       primitive.ObjectID `bson:"user_id,omitempty"`
+	AuthTime     primitive.DateTime `bson:"auth_time,omitempty"`
+	Device       string             `bson:"device,omitempty"`
+	IP           string             `bson:"ip,omitempty"`
+	Location     string             `bson:"location,omitempty"`
+	Interval     int64              `bson:"interval,omitempty"`
+	LastPolledAt primitive.DateTime `bson:"last_polled_at,omitempty"`
+	ExpIn        primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt    primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type DeviceCodeRepo struct {
+	store      *Store
+	devicesCol *mongo.Collection
+}
+
+func unexpired() bson.M {
+	return bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())}
+}
+
+func (d *DeviceCodeRepo) Create(ctx context.Context, auth *model.DeviceAuthorization) error {
+	if auth == nil || len(auth.DeviceCode) == 0 || len(auth.UserCode) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid device authorization.")
+	}
+	_, err := d.devicesCol.InsertOne(ctx, ToDbDeviceCode(auth))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Device code already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (d *DeviceCodeRepo) FindByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error) {
+	query := bson.M{
+		"user_code": userCode,
+		"status":    model.DeviceAuthPending,
+		"exp_in":    unexpired(),
+	}
+	var dbCode *DeviceCode
+	err := d.devicesCol.FindOne(ctx, query).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid user code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToDeviceAuthorization(dbCode), nil
+}
+
+func (d *DeviceCodeRepo) Decide(ctx context.Context, userCode, status, userID string) error {
+	query := bson.M{
+		"user_code": userCode,
+		"status":    model.DeviceAuthPending,
+		"exp_in":    unexpired(),
+	}
+	set := bson.M{
+		"status": status,
+	}
+	if status == model.DeviceAuthApproved {
+		userObjID, err := primitive.ObjectIDFromHex(userID)
+		if err != nil {
+			return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		}
+		set["user_id"] = userObjID
+		set["auth_time"] = primitive.NewDateTimeFromTime(time.Now())
+	}
+	res, err := d.devicesCol.UpdateOne(ctx, query, bson.M{"$set": set})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.New("Invalid user code.")
+	}
+	return nil
+}
+
+func (d *DeviceCodeRepo) Poll(ctx context.Context, deviceCode string) (*model.DeviceAuthorization, error) {
+	query := bson.M{
+		"_id":    hashCode(deviceCode),
+		"exp_in": unexpired(),
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"last_polled_at": primitive.NewDateTimeFromTime(time.Now()),
+		},
+	}
+	var dbCode *DeviceCode
+	err := d.devicesCol.FindOneAndUpdate(ctx, query, update).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid device code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToDeviceAuthorization(dbCode)
+	result.DeviceCode = deviceCode
+	return result, nil
+}
+
+func (d *DeviceCodeRepo) SlowDown(ctx context.Context, deviceCode string, interval time.Duration) error {
+	update := bson.M{
+		"$set": bson.M{
+			"interval": int64(interval),
+		},
+	}
+	_, err := d.devicesCol.UpdateOne(ctx, bson.M{"_id": hashCode(deviceCode)}, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (d *DeviceCodeRepo) Consume(ctx context.Context, deviceCode string) (*model.DeviceAuthorization, error) {
+	query := bson.M{
+		"_id": hashCode(deviceCode),
+		"status": bson.M{
+			"$ne": model.DeviceAuthPending,
+		},
+	}
+	var dbCode *DeviceCode
+	err := d.devicesCol.FindOneAndDelete(ctx, query).Decode(&dbCode)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid device code.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToDeviceAuthorization(dbCode)
+	result.DeviceCode = deviceCode
+	return result, nil
+}
+
+func ToDbDeviceCode(auth *model.DeviceAuthorization) *DeviceCode {
+	clientID, _ := primitive.ObjectIDFromHex(auth.ClientID)
+	userID, _ := primitive.ObjectIDFromHex(auth.UserID)
+	dbCode := &DeviceCode{
+		CodeHash:  hashCode(auth.DeviceCode),
+		UserCode:  auth.UserCode,
+		ClientID:  clientID,
+		Scope:     auth.Scope,
+		Status:    auth.Status,
+		UserID:    userID,
+		Device:    auth.Device,
+		IP:        auth.IP,
+		Location:  auth.Location,
+		Interval:  int64(auth.Interval),
+		ExpIn:     primitive.NewDateTimeFromTime(auth.ExpIn),
+		CreatedAt: primitive.NewDateTimeFromTime(auth.CreatedAt),
+	}
+	if !auth.AuthTime.IsZero() {
+		dbCode.AuthTime = primitive.NewDateTimeFromTime(auth.AuthTime)
+	}
+	if !auth.LastPolledAt.IsZero() {
+		dbCode.LastPolledAt = primitive.NewDateTimeFromTime(auth.LastPolledAt)
+	}
+	return dbCode
+}
+
+func ToDeviceAuthorization(dbCode *DeviceCode) *model.DeviceAuthorization {
+	auth := &model.DeviceAuthorization{
+		UserCode:  dbCode.UserCode,
+		ClientID:  dbCode.ClientID.Hex(),
+		Scope:     dbCode.Scope,
+		Status:    dbCode.Status,
+		Device:    dbCode.Device,
+		IP:        dbCode.IP,
+		Location:  dbCode.Location,
+		Interval:  time.Duration(dbCode.Interval),
+		ExpIn:     dbCode.ExpIn.Time(),
+		CreatedAt: dbCode.CreatedAt.Time(),
+	}
+	if !dbCode.UserID.IsZero() {
+		auth.UserID = dbCode.UserID.Hex()
+	}
+	if dbCode.AuthTime != 0 {
+		auth.AuthTime = dbCode.AuthTime.Time()
+	}
+	if dbCode.LastPolledAt != 0 {
+		auth.LastPolledAt = dbCode.LastPolledAt.Time()
+	}
+	return auth
+}
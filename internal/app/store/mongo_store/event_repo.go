@@ -0,0 +1,50 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//SecurityEvent represent the "SecurityEvents" collection
+type SecurityEvent struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Type      string             `bson:"type,omitempty"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty"`
+	ClientID  primitive.ObjectID `bson:"client_id,omitempty"`
+	SessionID primitive.ObjectID `bson:"session_id,omitempty"`
+	Details   map[string]string  `bson:"details,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type EventRepo struct {
+	store     *Store
+	eventsCol *mongo.Collection
+}
+
+func (e *EventRepo) Create(ctx context.Context, event *model.SecurityEvent) error {
+	if event == nil || len(event.Type) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid security event.")
+	}
+	_, err := e.eventsCol.InsertOne(ctx, ToDbSecurityEvent(event))
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func ToDbSecurityEvent(event *model.SecurityEvent) *SecurityEvent {
+	userID, _ := primitive.ObjectIDFromHex(event.UserID)
+	clientID, _ := primitive.ObjectIDFromHex(event.ClientID)
+	sessionID, _ := primitive.ObjectIDFromHex(event.SessionID)
+	return &SecurityEvent{
+		Type:      event.Type,
+		UserID:    userID,
+		ClientID:  clientID,
+		SessionID: sessionID,
+		Details:   event.Details,
+		CreatedAt: primitive.NewDateTimeFromTime(event.CreatedAt),
+	}
+}
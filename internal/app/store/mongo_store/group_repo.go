@@ -0,0 +1,301 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//Group represent the "Groups" collection
+type Group struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty"`
+	Name        string               `bson:"name,omitempty"`
+	Description string               `bson:"description,omitempty"`
+	ParentID    primitive.ObjectID   `bson:"parent_id,omitempty"`
+	Members     []primitive.ObjectID `bson:"members,omitempty"`
+	Roles       []ClientRole         `bson:"roles,omitempty"`
+	CreatedAt   primitive.DateTime   `bson:"created_at,omitempty"`
+}
+
+type GroupRepo struct {
+	store     *Store
+	groupsCol *mongo.Collection
+}
+
+func (g *GroupRepo) Create(ctx context.Context, group *model.Group) (string, error) {
+	dbGroup, err := ToDbGroup(group)
+	if err != nil {
+		return "", err
+	}
+	dbGroup.ID = primitive.NilObjectID
+	res, err := g.groupsCol.InsertOne(ctx, dbGroup)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return "", errors.ErrDuplicateEntry.Newf("Group %s already exists.", group.Name)
+		}
+		return "", errors.NoType.Wrap(err, "")
+	}
+	return res.InsertedID.(primitive.ObjectID).Hex(), nil
+}
+
+func (g *GroupRepo) FindByID(ctx context.Context, groupID string) (*model.Group, error) {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	var group Group
+	err = g.groupsCol.FindOne(ctx, bson.M{"_id": oid}).Decode(&group)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToGroup(&group), nil
+}
+
+//FindByIDs returns the found groups, unknown IDs are skipped
+func (g *GroupRepo) FindByIDs(ctx context.Context, groupIDs []string) ([]model.Group, error) {
+	oids := make([]primitive.ObjectID, 0, len(groupIDs))
+	for _, v := range groupIDs {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", v)
+		}
+		oids = append(oids, oid)
+	}
+	return g.find(ctx, bson.M{"_id": bson.M{"$in": oids}}, nil)
+}
+
+func (g *GroupRepo) FindAll(ctx context.Context) ([]model.Group, error) {
+	//members are not listed, groups may be large
+	return g.find(ctx, bson.M{}, bson.M{"members": 0})
+}
+
+func (g *GroupRepo) FindUserGroups(ctx context.Context, userID string) ([]model.Group, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	return g.find(ctx, bson.M{"members": oid}, bson.M{"members": 0})
+}
+
+func (g *GroupRepo) find(ctx context.Context, query, proj bson.M) ([]model.Group, error) {
+	options := options.Find()
+	if proj != nil {
+		options.SetProjection(proj)
+	}
+	cur, err := g.groupsCol.Find(ctx, query, options)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var dbGroups []Group
+	if err = cur.All(ctx, &dbGroups); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	groups := make([]model.Group, 0, len(dbGroups))
+	for i := range dbGroups {
+		groups = append(groups, *ToGroup(&dbGroups[i]))
+	}
+	return groups, nil
+}
+
+func (g *GroupRepo) CountChildren(ctx context.Context, groupID string) (int64, error) {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return 0, errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	count, err := g.groupsCol.CountDocuments(ctx, bson.M{"parent_id": oid})
+	if err != nil {
+		return 0, errors.NoType.Wrap(err, "")
+	}
+	return count, nil
+}
+
+func (g *GroupRepo) FindChildren(ctx context.Context, parentIDs []string) ([]model.Group, error) {
+	oids := make([]primitive.ObjectID, 0, len(parentIDs))
+	for _, v := range parentIDs {
+		oid, err := primitive.ObjectIDFromHex(v)
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", v)
+		}
+		oids = append(oids, oid)
+	}
+	return g.find(ctx, bson.M{"parent_id": bson.M{"$in": oids}}, bson.M{"members": 0})
+}
+
+func (g *GroupRepo) Update(ctx context.Context, group *model.Group) error {
+	dbGroup, err := ToDbGroup(group)
+	if err != nil {
+		return err
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"name":        dbGroup.Name,
+			"description": dbGroup.Description,
+		},
+	}
+	if dbGroup.ParentID.IsZero() {
+		update["$unset"] = bson.M{"parent_id": ""}
+	} else {
+		update["$set"].(bson.M)["parent_id"] = dbGroup.ParentID
+	}
+	res, err := g.groupsCol.UpdateOne(ctx, bson.M{"_id": dbGroup.ID}, update)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.Newf("Group %s already exists.", group.Name)
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", group.ID)
+	}
+	return nil
+}
+
+func (g *GroupRepo) Delete(ctx context.Context, groupID string) error {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	res, err := g.groupsCol.DeleteOne(ctx, bson.M{"_id": oid})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.DeletedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	return nil
+}
+
+//updateMembers applies $addToSet or $pull of user to members of group
+func (g *GroupRepo) updateMembers(ctx context.Context, operator, groupID, userID string) error {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	update := bson.M{
+		operator: bson.M{
+			"members": userObjectID,
+		},
+	}
+	res, err := g.groupsCol.UpdateOne(ctx, bson.M{"_id": oid}, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	return nil
+}
+
+func (g *GroupRepo) AddMember(ctx context.Context, groupID, userID string) error {
+	return g.updateMembers(ctx, "$addToSet", groupID, userID)
+}
+
+func (g *GroupRepo) RemoveMember(ctx context.Context, groupID, userID string) error {
+	return g.updateMembers(ctx, "$pull", groupID, userID)
+}
+
+//GrantRole adds the role of client to group, granting the role twice has no effect
+func (g *GroupRepo) GrantRole(ctx context.Context, groupID, clientID, role string) error {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	found, err := grantClientRole(ctx, g.groupsCol, oid, "roles", clientObjectID, role)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if !found {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	return nil
+}
+
+//RevokeRole removes the role of client from group
+func (g *GroupRepo) RevokeRole(ctx context.Context, groupID, clientID, role string) error {
+	oid, err := primitive.ObjectIDFromHex(groupID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid group ID %s", groupID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	found, err := revokeClientRole(ctx, g.groupsCol, oid, "roles", clientObjectID, role)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if !found {
+		return errors.ErrInvalidArgument.Newf("Role %s is not granted to group.", role)
+	}
+	return nil
+}
+
+func ToGroup(dbGroup *Group) *model.Group {
+	members := make([]string, 0, len(dbGroup.Members))
+	for _, v := range dbGroup.Members {
+		members = append(members, v.Hex())
+	}
+	roles := make([]model.UserRole, 0, len(dbGroup.Roles))
+	for _, v := range dbGroup.Roles {
+		roles = append(roles, model.UserRole{
+			ClientID: v.ClientID.Hex(),
+			Roles:    v.Roles,
+		})
+	}
+	group := &model.Group{
+		ID:          dbGroup.ID.Hex(),
+		Name:        dbGroup.Name,
+		Description: dbGroup.Description,
+		Members:     members,
+		Roles:       roles,
+	}
+	if !dbGroup.ParentID.IsZero() {
+		group.ParentID = dbGroup.ParentID.Hex()
+	}
+	if dbGroup.CreatedAt != 0 {
+		createdAt := dbGroup.CreatedAt.Time()
+		group.CreatedAt = &createdAt
+	}
+	return group
+}
+
+//ToDbGroup converts group without members and roles, they are changed by separate methods
+func ToDbGroup(group *model.Group) (*Group, error) {
+	dbGroup := &Group{
+		Name:        group.Name,
+		Description: group.Description,
+	}
+	var err error
+	if len(group.ID) > 0 {
+		if dbGroup.ID, err = primitive.ObjectIDFromHex(group.ID); err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", group.ID)
+		}
+	}
+	if len(group.ParentID) > 0 {
+		if dbGroup.ParentID, err = primitive.ObjectIDFromHex(group.ParentID); err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid group ID %s", group.ParentID)
+		}
+	}
+	if group.CreatedAt != nil {
+		dbGroup.CreatedAt = primitive.NewDateTimeFromTime(*group.CreatedAt)
+	}
+	return dbGroup, nil
+}
@@ -0,0 +1,116 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//SigningKey represent the "SigningKeys" collection
+type SigningKey struct {
+	ID         string              `bson:"_id"`
+	Algorithm  string              `bson:"alg,omitempty"`
+	PrivateKey string              `bson:"private_key,omitempty"`
+	Active     bool                `bson:"active"`
+	CreatedAt  primitive.DateTime  `bson:"created_at,omitempty"`
+	ExpIn      *primitive.DateTime `bson:"exp_in,omitempty"`
+}
+
+type KeyRepo struct {
+	store   *Store
+	keysCol *mongo.Collection
+}
+
+func (k *KeyRepo) Create(ctx context.Context, key *model.SigningKey) error {
+	if key == nil || len(key.ID) == 0 || len(key.PrivateKey) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid signing key.")
+	}
+	_, err := k.keysCol.InsertOne(ctx, ToDbSigningKey(key))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Signing key already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (k *KeyRepo) FindValid(ctx context.Context) ([]model.SigningKey, error) {
+	query := bson.M{
+		"$or": bson.A{
+			bson.M{"active": true},
+			bson.M{"exp_in": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())}},
+		},
+	}
+	cur, err := k.keysCol.Find(ctx, query)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var dbKeys []SigningKey
+	if err = cur.All(ctx, &dbKeys); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	keys := make([]model.SigningKey, 0, len(dbKeys))
+	for i := range dbKeys {
+		keys = append(keys, *ToSigningKey(&dbKeys[i]))
+	}
+	return keys, nil
+}
+
+func (k *KeyRepo) Retire(ctx context.Context, exceptID string, expIn time.Time) error {
+	exp := primitive.NewDateTimeFromTime(expIn)
+	//already retired keys are shortened too, so forced rotation revokes them
+	query := bson.M{
+		"_id": bson.M{"$ne": exceptID},
+		"$or": bson.A{
+			bson.M{"active": true},
+			bson.M{"exp_in": bson.M{"$gt": exp}},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"active": false,
+			"exp_in": exp,
+		},
+	}
+	_, err := k.keysCol.UpdateMany(ctx, query, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func ToDbSigningKey(key *model.SigningKey) *SigningKey {
+	dbKey := &SigningKey{
+		ID:         key.ID,
+		Algorithm:  key.Algorithm,
+		PrivateKey: key.PrivateKey,
+		Active:     key.Active,
+		CreatedAt:  primitive.NewDateTimeFromTime(key.CreatedAt),
+	}
+	if key.ExpIn != nil {
+		exp := primitive.NewDateTimeFromTime(*key.ExpIn)
+		dbKey.ExpIn = &exp
+	}
+	return dbKey
+}
+
+func ToSigningKey(dbKey *SigningKey) *model.SigningKey {
+	key := &model.SigningKey{
+		ID:         dbKey.ID,
+		Algorithm:  dbKey.Algorithm,
+		PrivateKey: dbKey.PrivateKey,
+		Active:     dbKey.Active,
+		CreatedAt:  dbKey.CreatedAt.Time(),
+	}
+	if dbKey.ExpIn != nil {
+		exp := dbKey.ExpIn.Time()
+		key.ExpIn = &exp
+	}
+	return key
+}
@@ -0,0 +1,119 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//LoginAttempts represent the "LoginAttempts" collection, counters are shared by all instances
+type LoginAttempts struct {
+	Key          string             `bson:"_id"`
+	Failures     int                `bson:"failures,omitempty"`
+	Locked       bool               `bson:"locked,omitempty"`
+	BlockedUntil primitive.DateTime `bson:"blocked_until,omitempty"`
+	LastFailure  primitive.DateTime `bson:"last_failure,omitempty"`
+	ExpIn        primitive.DateTime `bson:"exp_in,omitempty"`
+}
+
+type LoginAttemptRepo struct {
+	store       *Store
+	attemptsCol *mongo.Collection
+}
+
+func (l *LoginAttemptRepo) Find(ctx context.Context, key string) (*model.LoginAttempts, error) {
+	query := bson.M{
+		"_id":    key,
+		"exp_in": unexpired(),
+	}
+	var dbAttempts *LoginAttempts
+	err := l.attemptsCol.FindOne(ctx, query).Decode(&dbAttempts)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return &model.LoginAttempts{Key: key}, nil
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToLoginAttempts(dbAttempts), nil
+}
+
+//Fail counts failure and blocks key by one update, so concurrent failures can't overwrite the block of each other.
+//It is the same computation as model.LoginAttempts.Fail, durations are counted in milliseconds.
+func (l *LoginAttemptRepo) Fail(ctx context.Context, key string, throttle *model.LoginThrottle) (*model.LoginAttempts, error) {
+	now := time.Now()
+	nowDate := primitive.NewDateTimeFromTime(now)
+	//TTL monitor removes expired counters with a delay
+	_, err := l.attemptsCol.DeleteOne(ctx, bson.M{
+		"_id":    key,
+		"exp_in": bson.M{"$lte": nowDate},
+	})
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	lockoutMs := throttle.Lockout.Milliseconds()
+	var locked interface{} = false
+	if throttle.Threshold > 0 {
+		locked = bson.M{"$gte": bson.A{"$failures", throttle.Threshold}}
+	}
+	delay := bson.M{"$min": bson.A{lockoutMs, bson.M{"$multiply": bson.A{
+		throttle.BackoffBase.Milliseconds(),
+		bson.M{"$pow": bson.A{2, bson.M{"$subtract": bson.A{"$failures", throttle.Free + 1}}}},
+	}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"failures":     bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$failures", 0}}, 1}},
+			"last_failure": nowDate,
+			"exp_in":       primitive.NewDateTimeFromTime(now.Add(throttle.Lockout)),
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"locked": locked,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"blocked_until": bson.M{"$switch": bson.M{
+				"branches": bson.A{
+					bson.M{"case": "$locked", "then": bson.M{"$add": bson.A{nowDate, lockoutMs}}},
+					bson.M{"case": bson.M{"$lte": bson.A{"$failures", throttle.Free}}, "then": "$blocked_until"},
+				},
+				"default": bson.M{"$add": bson.A{nowDate, delay}},
+			}},
+		}}},
+	}
+	options := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var dbAttempts *LoginAttempts
+	err = l.attemptsCol.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options).Decode(&dbAttempts)
+	//Concurrent upsert of the same key fails, the retry updates the inserted counter
+	if err != nil && isDuplicateKeyError(err) {
+		err = l.attemptsCol.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options).Decode(&dbAttempts)
+	}
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	return ToLoginAttempts(dbAttempts), nil
+}
+
+func (l *LoginAttemptRepo) Reset(ctx context.Context, key string) error {
+	_, err := l.attemptsCol.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func ToLoginAttempts(dbAttempts *LoginAttempts) *model.LoginAttempts {
+	return &model.LoginAttempts{
+		Key:          dbAttempts.Key,
+		Failures:     dbAttempts.Failures,
+		Locked:       dbAttempts.Locked,
+		BlockedUntil: dbAttempts.BlockedUntil.Time(),
+		LastFailure:  dbAttempts.LastFailure.Time(),
+		ExpIn:        dbAttempts.ExpIn.Time(),
+	}
+}
@@ -0,0 +1,119 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//MFAChallenge represent the "MFAChallenges" collection, only hash of token is stored
+type MFAChallenge struct {
+	TokenHash string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty"`
+	ClientID  primitive.ObjectID `bson:"client_id,omitempty"`
+	Attempts  int                `bson:"attempts"`
+	ExpIn     primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type MFAChallengeRepo struct {
+	store         *Store
+	challengesCol *mongo.Collection
+}
+
+func (m *MFAChallengeRepo) Create(ctx context.Context, challenge *model.MFAChallenge) error {
+	if challenge == nil || len(challenge.Token) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid MFA challenge.")
+	}
+	_, err := m.challengesCol.InsertOne(ctx, ToDbMFAChallenge(challenge))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("MFA challenge already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (m *MFAChallengeRepo) Find(ctx context.Context, token string) (*model.MFAChallenge, error) {
+	query := bson.M{
+		"_id":    hashCode(token),
+		"exp_in": unexpired(),
+	}
+	var dbChallenge *MFAChallenge
+	err := m.challengesCol.FindOne(ctx, query).Decode(&dbChallenge)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToMFAChallenge(dbChallenge)
+	result.Token = token
+	return result, nil
+}
+
+func (m *MFAChallengeRepo) Attempt(ctx context.Context, token string, maxAttempts int) (*model.MFAChallenge, error) {
+	query := bson.M{
+		"_id":      hashCode(token),
+		"exp_in":   unexpired(),
+		"attempts": bson.M{"$lt": maxAttempts},
+	}
+	update := bson.M{"$inc": bson.M{"attempts": 1}}
+	options := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	var dbChallenge *MFAChallenge
+	err := m.challengesCol.FindOneAndUpdate(ctx, query, update, options).Decode(&dbChallenge)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToMFAChallenge(dbChallenge)
+	result.Token = token
+	return result, nil
+}
+
+func (m *MFAChallengeRepo) Consume(ctx context.Context, token string) error {
+	res, err := m.challengesCol.DeleteOne(ctx, bson.M{"_id": hashCode(token)})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.DeletedCount == 0 {
+		return errors.ErrInvalidArgument.New("Invalid or expired MFA token.")
+	}
+	return nil
+}
+
+func ToDbMFAChallenge(challenge *model.MFAChallenge) *MFAChallenge {
+	userID, _ := primitive.ObjectIDFromHex(challenge.UserID)
+	clientID, _ := primitive.ObjectIDFromHex(challenge.ClientID)
+	return &MFAChallenge{
+		TokenHash: hashCode(challenge.Token),
+		UserID:    userID,
+		ClientID:  clientID,
+		Attempts:  challenge.Attempts,
+		ExpIn:     primitive.NewDateTimeFromTime(challenge.ExpIn),
+		CreatedAt: primitive.NewDateTimeFromTime(challenge.CreatedAt),
+	}
+}
+
+func ToMFAChallenge(dbChallenge *MFAChallenge) *model.MFAChallenge {
+	return &model.MFAChallenge{
+		MFARequired: true,
+		UserID:      dbChallenge.UserID.Hex(),
+		ClientID:    dbChallenge.ClientID.Hex(),
+		Attempts:    dbChallenge.Attempts,
+		ExpIn:       dbChallenge.ExpIn.Time(),
+		CreatedAt:   dbChallenge.CreatedAt.Time(),
+	}
+}
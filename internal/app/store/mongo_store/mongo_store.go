@@ -6,15 +6,55 @@ import (
 )
 
 const (
-	UsersCollection   = "users"
-	ClientsCollection = "client"
+	UsersCollection              = "users"
+	ClientsCollection            = "clients"
+	EventsCollection             = "security_events"
+	CodesCollection              = "auth_codes"
+	KeysCollection               = "signing_keys"
+	RevokedCollection            = "revoked_tokens"
+	DevicesCollection            = "device_codes"
+	GroupsCollection             = "groups"
+	ScopesCollection             = "scopes"
+	ConsentsCollection           = "consents"
+	MFAChallengesCollection      = "mfa_challenges"
+	WebAuthnChallengesCollection = "webauthn_challenges"
+	PasswordResetsCollection     = "password_resets"
+	LoginAttemptsCollection      = "login_attempts"
+	RateLimitsCollection         = "rate_limits"
 )
 
+//duplicateKeyErrorCode is a mongoDB error code of unique index violation
+const duplicateKeyErrorCode = 11000
+
+func isDuplicateKeyError(err error) bool {
+	if writeErr, ok := err.(mongo.WriteException); ok {
+		for _, e := range writeErr.WriteErrors {
+			if e.Code == duplicateKeyErrorCode {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 //Store is a mongoDB database storage
 type Store struct {
-	db               *mongo.Database
-	userRepository   *UserRepo
-	clientRepository *ClientRepo
+	db                *mongo.Database
+	userRepository    *UserRepo
+	clientRepository  *ClientRepo
+	eventRepository   *EventRepo
+	codeRepository    *AuthCodeRepo
+	keyRepository     *KeyRepo
+	revokedTokenRepo  *RevokedTokenRepo
+	deviceRepository  *DeviceCodeRepo
+	groupRepository   *GroupRepo
+	scopeRepository   *ScopeRepo
+	consentRepo       *ConsentRepo
+	mfaChallengeRepo  *MFAChallengeRepo
+	webAuthnRepo      *WebAuthnChallengeRepo
+	passwordResetRepo *PasswordResetRepo
+	loginAttemptRepo  *LoginAttemptRepo
+	rateLimitRepo     *RateLimitRepo
 }
 
 func NewStore(db *mongo.Database) *Store {
@@ -45,3 +85,159 @@ func (s *Store) Client() st.ClientRepository {
 
 	return s.clientRepository
 }
+
+//Event returns the "SecurityEvents" repository
+func (s *Store) Event() st.EventRepository {
+	if s.eventRepository != nil {
+		return s.eventRepository
+	}
+	s.eventRepository = &EventRepo{
+		store:     s,
+		eventsCol: s.db.Collection(EventsCollection),
+	}
+	return s.eventRepository
+}
+
+//AuthCode returns the "AuthCodes" repository
+func (s *Store) AuthCode() st.AuthCodeRepository {
+	if s.codeRepository != nil {
+		return s.codeRepository
+	}
+	s.codeRepository = &AuthCodeRepo{
+		store:    s,
+		codesCol: s.db.Collection(CodesCollection),
+	}
+	return s.codeRepository
+}
+
+//Key returns the "SigningKeys" repository
+func (s *Store) Key() st.KeyRepository {
+	if s.keyRepository != nil {
+		return s.keyRepository
+	}
+	s.keyRepository = &KeyRepo{
+		store:   s,
+		keysCol: s.db.Collection(KeysCollection),
+	}
+	return s.keyRepository
+}
+
+//RevokedToken returns the "RevokedTokens" repository
+func (s *Store) RevokedToken() st.RevokedTokenRepository {
+	if s.revokedTokenRepo != nil {
+		return s.revokedTokenRepo
+	}
+	s.revokedTokenRepo = &RevokedTokenRepo{
+		store:      s,
+		revokedCol: s.db.Collection(RevokedCollection),
+	}
+	return s.revokedTokenRepo
+}
+
+//DeviceCode returns the "DeviceCodes" repository
+func (s *Store) DeviceCode() st.DeviceCodeRepository {
+	if s.deviceRepository != nil {
+		return s.deviceRepository
+	}
+	s.deviceRepository = &DeviceCodeRepo{
+		store:      s,
+		devicesCol: s.db.Collection(DevicesCollection),
+	}
+	return s.deviceRepository
+}
+
+//Group returns the "Groups" repository
+func (s *Store) Group() st.GroupRepository {
+	if s.groupRepository != nil {
+		return s.groupRepository
+	}
+	s.groupRepository = &GroupRepo{
+		store:     s,
+		groupsCol: s.db.Collection(GroupsCollection),
+	}
+	return s.groupRepository
+}
+
+//Scope returns the "Scopes" repository
+func (s *Store) Scope() st.ScopeRepository {
+	if s.scopeRepository != nil {
+		return s.scopeRepository
+	}
+	s.scopeRepository = &ScopeRepo{
+		store:     s,
+		scopesCol: s.db.Collection(ScopesCollection),
+	}
+	return s.scopeRepository
+}
+
+//Consent returns the "Consents" repository
+func (s *Store) Consent() st.ConsentRepository {
+	if s.consentRepo != nil {
+		return s.consentRepo
+	}
+	s.consentRepo = &ConsentRepo{
+		store:       s,
+		consentsCol: s.db.Collection(ConsentsCollection),
+	}
+	return s.consentRepo
+}
+
+//MFAChallenge returns the "MFAChallenges" repository
+func (s *Store) MFAChallenge() st.MFAChallengeRepository {
+	if s.mfaChallengeRepo != nil {
+		return s.mfaChallengeRepo
+	}
+	s.mfaChallengeRepo = &MFAChallengeRepo{
+		store:         s,
+		challengesCol: s.db.Collection(MFAChallengesCollection),
+	}
+	return s.mfaChallengeRepo
+}
+
+//WebAuthnChallenge returns the "WebAuthnChallenges" repository
+func (s *Store) WebAuthnChallenge() st.WebAuthnChallengeRepository {
+	if s.webAuthnRepo != nil {
+		return s.webAuthnRepo
+	}
+	s.webAuthnRepo = &WebAuthnChallengeRepo{
+		store:         s,
+		challengesCol: s.db.Collection(WebAuthnChallengesCollection),
+	}
+	return s.webAuthnRepo
+}
+
+//PasswordReset returns the "PasswordResets" repository
+func (s *Store) PasswordReset() st.PasswordResetRepository {
+	if s.passwordResetRepo != nil {
+		return s.passwordResetRepo
+	}
+	s.passwordResetRepo = &PasswordResetRepo{
+		store:     s,
+		resetsCol: s.db.Collection(PasswordResetsCollection),
+	}
+	return s.passwordResetRepo
+}
+
+//LoginAttempt returns the "LoginAttempts" repository
+func (s *Store) LoginAttempt() st.LoginAttemptRepository {
+	if s.loginAttemptRepo != nil {
+		return s.loginAttemptRepo
+	}
+	s.loginAttemptRepo = &LoginAttemptRepo{
+		store:       s,
+		attemptsCol: s.db.Collection(LoginAttemptsCollection),
+	}
+	return s.loginAttemptRepo
+}
+
+//RateLimit returns the "RateLimits" repository
+func (s *Store) RateLimit() st.RateLimitRepository {
+	if s.rateLimitRepo != nil {
+		return s.rateLimitRepo
+	}
+	s.rateLimitRepo = &RateLimitRepo{
+		store:      s,
+		bucketsCol: s.db.Collection(RateLimitsCollection),
+	}
+	return s.rateLimitRepo
+}
@@ -0,0 +1,135 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//PasswordReset represent the "PasswordResets" collection, only hash of token is stored
+type PasswordReset struct {
+	TokenHash string             `bson:"_id"`
+	UserID    primitive.ObjectID `bson:"user_id,omitempty"`
+	ExpIn     primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type PasswordResetRepo struct {
+	store     *Store
+	resetsCol *mongo.Collection
+}
+
+func (p *PasswordResetRepo) Create(ctx context.Context, reset *model.PasswordReset) error {
+	if reset == nil || len(reset.Token) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid password reset token.")
+	}
+	if _, err := primitive.ObjectIDFromHex(reset.UserID); err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", reset.UserID)
+	}
+	_, err := p.resetsCol.InsertOne(ctx, ToDbPasswordReset(reset))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Password reset token already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (p *PasswordResetRepo) Find(ctx context.Context, token string) (*model.PasswordReset, error) {
+	query := bson.M{
+		"_id":    hashCode(token),
+		"exp_in": unexpired(),
+	}
+	var dbReset *PasswordReset
+	err := p.resetsCol.FindOne(ctx, query).Decode(&dbReset)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid or expired password reset token.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToPasswordReset(dbReset)
+	result.Token = token
+	return result, nil
+}
+
+func (p *PasswordResetRepo) Consume(ctx context.Context, token string) (*model.PasswordReset, error) {
+	query := bson.M{
+		"_id":    hashCode(token),
+		"exp_in": unexpired(),
+	}
+	var dbReset *PasswordReset
+	err := p.resetsCol.FindOneAndDelete(ctx, query).Decode(&dbReset)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Invalid or expired password reset token.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToPasswordReset(dbReset)
+	result.Token = token
+	return result, nil
+}
+
+func (p *PasswordResetRepo) FindLastByUser(ctx context.Context, userID string) (*model.PasswordReset, error) {
+	ID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	query := bson.M{
+		"user_id": ID,
+		"exp_in":  unexpired(),
+	}
+	opt := options.FindOne().SetSort(bson.M{"created_at": -1})
+	var dbReset *PasswordReset
+	err = p.resetsCol.FindOne(ctx, query, opt).Decode(&dbReset)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.New("Password reset token not found.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToPasswordReset(dbReset), nil
+}
+
+func (p *PasswordResetRepo) DeleteByUser(ctx context.Context, userID string) error {
+	ID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	_, err = p.resetsCol.DeleteMany(ctx, bson.M{"user_id": ID})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func ToDbPasswordReset(reset *model.PasswordReset) *PasswordReset {
+	userID, _ := primitive.ObjectIDFromHex(reset.UserID)
+	return &PasswordReset{
+		TokenHash: hashCode(reset.Token),
+		UserID:    userID,
+		ExpIn:     primitive.NewDateTimeFromTime(reset.ExpIn),
+		CreatedAt: primitive.NewDateTimeFromTime(reset.CreatedAt),
+	}
+}
+
+func ToPasswordReset(dbReset *PasswordReset) *model.PasswordReset {
+	return &model.PasswordReset{
+		UserID:    dbReset.UserID.Hex(),
+		ExpIn:     dbReset.ExpIn.Time(),
+		CreatedAt: dbReset.CreatedAt.Time(),
+	}
+}
@@ -0,0 +1,66 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//RateLimitBucket represent the "RateLimits" collection, buckets are shared by all instances
+type RateLimitBucket struct {
+	Key       string             `bson:"_id"`
+	Tokens    float64            `bson:"tokens"`
+	Allowed   bool               `bson:"allowed"`
+	UpdatedAt int64              `bson:"updated_at"`
+	ExpIn     primitive.DateTime `bson:"exp_in,omitempty"`
+}
+
+type RateLimitRepo struct {
+	store      *Store
+	bucketsCol *mongo.Collection
+}
+
+//Take refills and takes token by one update, so concurrent requests of instances don't lose tokens.
+//It is the same computation as model.TokenBucket.Take, time is counted in milliseconds.
+func (r *RateLimitRepo) Take(ctx context.Context, key string, rate float64, burst int) (*model.TokenBucket, bool, error) {
+	now := time.Now()
+	nowMs := now.UnixNano() / int64(time.Millisecond)
+	fullMs := int64(float64(burst) / rate * 1000)
+	elapsed := bson.M{"$max": bson.A{0, bson.M{"$subtract": bson.A{nowMs, bson.M{"$ifNull": bson.A{"$updated_at", nowMs}}}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$min": bson.A{burst, bson.M{"$add": bson.A{
+				bson.M{"$ifNull": bson.A{"$tokens", burst}},
+				bson.M{"$multiply": bson.A{elapsed, rate / 1000}},
+			}}}},
+			"updated_at": nowMs,
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"allowed": bson.M{"$gte": bson.A{"$tokens", 1}},
+		}}},
+		{{Key: "$set", Value: bson.M{
+			"tokens": bson.M{"$cond": bson.A{"$allowed", bson.M{"$subtract": bson.A{"$tokens", 1}}, "$tokens"}},
+			"exp_in": primitive.NewDateTimeFromTime(now.Add(time.Duration(fullMs) * time.Millisecond)),
+		}}},
+	}
+	options := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	var dbBucket *RateLimitBucket
+	err := r.bucketsCol.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options).Decode(&dbBucket)
+	//Concurrent upsert of the same key fails, the retry updates the inserted bucket
+	if err != nil && isDuplicateKeyError(err) {
+		err = r.bucketsCol.FindOneAndUpdate(ctx, bson.M{"_id": key}, update, options).Decode(&dbBucket)
+	}
+	if err != nil {
+		return nil, false, errors.NoType.Wrap(err, "")
+	}
+	return &model.TokenBucket{
+		Tokens:    dbBucket.Tokens,
+		UpdatedAt: now,
+	}, dbBucket.Allowed, nil
+}
@@ -0,0 +1,58 @@
+package mongo_store
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//RevokedToken represent the "RevokedTokens" collection, documents are removed by TTL index after token expiration
+type RevokedToken struct {
+	TokenID string             `bson:"_id"`
+	ExpIn   primitive.DateTime `bson:"exp_in,omitempty"`
+}
+
+type RevokedTokenRepo struct {
+	store      *Store
+	revokedCol *mongo.Collection
+}
+
+func (r *RevokedTokenRepo) Create(ctx context.Context, tokenID string, expIn time.Time) error {
+	if len(tokenID) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid token ID.")
+	}
+	_, err := r.revokedCol.InsertOne(ctx, &RevokedToken{
+		TokenID: tokenID,
+		ExpIn:   primitive.NewDateTimeFromTime(expIn),
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Token already revoked.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (r *RevokedTokenRepo) FindActive(ctx context.Context) (map[string]time.Time, error) {
+	query := bson.M{
+		"exp_in": bson.M{"$gt": primitive.NewDateTimeFromTime(time.Now())},
+	}
+	cursor, err := r.revokedCol.Find(ctx, query)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	dbTokens := []RevokedToken{}
+	if err = cursor.All(ctx, &dbTokens); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	tokens := make(map[string]time.Time, len(dbTokens))
+	for _, token := range dbTokens {
+		tokens[token.TokenID] = token.ExpIn.Time()
+	}
+	return tokens, nil
+}
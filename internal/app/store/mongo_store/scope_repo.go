@@ -0,0 +1,86 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//Scope represent the "Scopes" collection, scope name is the ID
+type Scope struct {
+	Name        string `bson:"_id"`
+	Description string `bson:"description,omitempty"`
+}
+
+type ScopeRepo struct {
+	store     *Store
+	scopesCol *mongo.Collection
+}
+
+func (s *ScopeRepo) Create(ctx context.Context, scope *model.Scope) error {
+	_, err := s.scopesCol.InsertOne(ctx, Scope{Name: scope.Name, Description: scope.Description})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.Newf("Scope %s already exists.", scope.Name)
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (s *ScopeRepo) FindAll(ctx context.Context) ([]model.Scope, error) {
+	return s.find(ctx, bson.M{})
+}
+
+func (s *ScopeRepo) FindByNames(ctx context.Context, names []string) ([]model.Scope, error) {
+	return s.find(ctx, bson.M{"_id": bson.M{"$in": names}})
+}
+
+func (s *ScopeRepo) find(ctx context.Context, query bson.M) ([]model.Scope, error) {
+	cur, err := s.scopesCol.Find(ctx, query)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var dbScopes []Scope
+	if err = cur.All(ctx, &dbScopes); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	scopes := make([]model.Scope, 0, len(dbScopes))
+	for _, v := range dbScopes {
+		scopes = append(scopes, model.Scope{
+			Name:        v.Name,
+			Description: v.Description,
+		})
+	}
+	return scopes, nil
+}
+
+func (s *ScopeRepo) Update(ctx context.Context, scope *model.Scope) error {
+	update := bson.M{
+		"$set": bson.M{
+			"description": scope.Description,
+		},
+	}
+	res, err := s.scopesCol.UpdateOne(ctx, bson.M{"_id": scope.Name}, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Unknown scope %s.", scope.Name)
+	}
+	return nil
+}
+
+func (s *ScopeRepo) Delete(ctx context.Context, name string) error {
+	res, err := s.scopesCol.DeleteOne(ctx, bson.M{"_id": name})
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.DeletedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Unknown scope %s.", name)
+	}
+	return nil
+}
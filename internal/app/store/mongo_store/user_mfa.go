@@ -0,0 +1,144 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//UserMFA represent attached "MFA" document in "User"
+type UserMFA struct {
+	TOTPSecret    string   `bson:"totp_secret,omitempty"`
+	TOTPEnabled   bool     `bson:"totp_enabled,omitempty"`
+	TOTPLastStep  int64    `bson:"totp_last_step,omitempty"`
+	RecoveryCodes []string `bson:"recovery_codes,omitempty"`
+}
+
+//updateMFA updates the user found by query, it returns false if no user is matched
+func (u UserRepo) updateMFA(ctx context.Context, userID string, query, update bson.M) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	query["_id"] = oid
+	res, err := u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return false, errors.NoType.Wrap(err, "")
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (u UserRepo) FindMFA(ctx context.Context, userID string) (*model.MFA, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	opt := options.FindOne().SetProjection(bson.M{"mfa": true})
+	var usr struct {
+		MFA UserMFA `bson:"mfa,omitempty"`
+	}
+	err = u.usersCol.FindOne(ctx, bson.M{"_id": oid}, opt).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	return ToMFA(&usr.MFA), nil
+}
+
+func (u UserRepo) SetTOTPSecret(ctx context.Context, userID, secret string) error {
+	query := bson.M{"mfa.totp_enabled": bson.M{"$ne": true}}
+	update := bson.M{"$set": bson.M{"mfa.totp_secret": secret}}
+	found, err := u.updateMFA(ctx, userID, query, update)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.ErrInvalidArgument.New("Two-factor authentication is already enabled.")
+	}
+	return nil
+}
+
+func (u UserRepo) EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodes []string) error {
+	query := bson.M{
+		"mfa.totp_enabled": bson.M{"$ne": true},
+		"mfa.totp_secret":  bson.M{"$exists": true},
+	}
+	update := bson.M{"$set": bson.M{
+		"mfa.totp_enabled":   true,
+		"mfa.totp_last_step": step,
+		"mfa.recovery_codes": recoveryCodes,
+	}}
+	found, err := u.updateMFA(ctx, userID, query, update)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.ErrInvalidArgument.New("TOTP enrollment is not started.")
+	}
+	return nil
+}
+
+func (u UserRepo) DisableTOTP(ctx context.Context, userID string) error {
+	update := bson.M{"$unset": bson.M{"mfa": ""}}
+	found, err := u.updateMFA(ctx, userID, bson.M{}, update)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	return nil
+}
+
+func (u UserRepo) SetRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) error {
+	query := bson.M{"mfa.totp_enabled": true}
+	update := bson.M{"$set": bson.M{"mfa.recovery_codes": recoveryCodes}}
+	found, err := u.updateMFA(ctx, userID, query, update)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return errors.ErrInvalidArgument.New("Two-factor authentication is disabled.")
+	}
+	return nil
+}
+
+//UseTOTPStep saves the step only if it's later than last accepted one, so concurrent logins can't use the same code
+func (u UserRepo) UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	query := bson.M{
+		"mfa.totp_enabled": true,
+		"$or": bson.A{
+			bson.M{"mfa.totp_last_step": bson.M{"$lt": step}},
+			bson.M{"mfa.totp_last_step": bson.M{"$exists": false}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"mfa.totp_last_step": step}}
+	return u.updateMFA(ctx, userID, query, update)
+}
+
+func (u UserRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	query := bson.M{
+		"mfa.totp_enabled":   true,
+		"mfa.recovery_codes": codeHash,
+	}
+	update := bson.M{"$pull": bson.M{"mfa.recovery_codes": codeHash}}
+	return u.updateMFA(ctx, userID, query, update)
+}
+
+func ToMFA(dbMFA *UserMFA) *model.MFA {
+	return &model.MFA{
+		TOTPSecret:    dbMFA.TOTPSecret,
+		TOTPEnabled:   dbMFA.TOTPEnabled,
+		TOTPLastStep:  dbMFA.TOTPLastStep,
+		RecoveryCodes: dbMFA.RecoveryCodes,
+	}
+}
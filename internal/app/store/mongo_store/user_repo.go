@@ -6,7 +6,6 @@ import (
 	"auth-server/internal/app/store"
 	errors "auth-server/pkg/errors/types"
 	"context"
-	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -48,6 +47,8 @@ type (
 		ID             primitive.ObjectID `bson:"id,omitempty"`
 		ClientID       primitive.ObjectID `bson:"client_id,omitempty"`
 		Device         string             `bson:"device,omitempty"`
+		IP             string             `bson:"ip,omitempty"`
+		Location       string             `bson:"location,omitempty"`
 		LastActiveDate primitive.DateTime `bson:"last_active_time,omitempty"`
 	}
 
@@ -61,21 +62,18 @@ type (
 		CreatedAt      *primitive.DateTime `bson:"created_at,omitempty"`
 		UserInfo       map[string]string   `bson:"user_info,omitempty"`
 		UserSessions   []UserSessionClient `bson:"user_sessions,omitempty"`
-		Roles          []UserClientRole    `bson:"roles,omitempty"`
+		ClientRoles    []ClientRole        `bson:"user_roles,omitempty"`
 	}
 
 	//UserSessionClient represent attached "Sessions" document in "UserClient"
 	UserSessionClient struct {
-		SessionID      string             `bson:"session_id,omitempty"`
+		ID             primitive.ObjectID `bson:"id,omitempty"`
 		Client         Client             `bson:"client,omitempty"`
 		Device         string             `bson:"device,omitempty"`
+		IP             string             `bson:"ip,omitempty"`
+		Location       string             `bson:"location,omitempty"`
 		LastActiveDate primitive.DateTime `bson:"last_active_time,omitempty"`
 	}
-	//UserClientRole represent attached user roles document in "UserClient"
-	UserClientRole struct {
-		Client Client   `bson:"client,omitempty"`
-		Roles  []string `bson:"roles,omitempty"`
-	}
 	//
 	UserRepo struct {
 		store    *Store
@@ -95,6 +93,9 @@ func (u UserRepo) fetch(ctx context.Context, query bson.M, params *store.UserFie
 	if params.Email {
 		projection["email"] = params.Email
 	}
+	if params.EmailConfirmed {
+		projection["email_confirmed"] = params.EmailConfirmed
+	}
 	if params.CreatedAt {
 		projection["created_at"] = params.CreatedAt
 	}
@@ -103,7 +104,7 @@ func (u UserRepo) fetch(ctx context.Context, query bson.M, params *store.UserFie
 	}
 
 	if params.UserRoles {
-		projection["user_info"] = params.UserRoles
+		projection["user_roles"] = params.UserRoles
 	}
 	if params.UserPasswordHash {
 		projection["password_hash"] = params.UserPasswordHash
@@ -121,22 +122,51 @@ func (u UserRepo) fetch(ctx context.Context, query bson.M, params *store.UserFie
 			}
 		}
 	} else {
-		proj := bson.D{{"$project", projection}}
-		match := bson.D{{"$match", query}}
-		pip := bson.D{{"$lookup", bson.D{
-			{"from", "clients"},
-			{"localField", "user_sessions.client_id"},
-			{"foreignField", "_id"},
-			{"as", "user_sessions"}}}}
-		cur, err := u.usersCol.Aggregate(ctx, mongo.Pipeline{match, pip, proj})
-		defer cur.Close(ctx)
+		projection["user_sessions"] = true
+		match := bson.D{{Key: "$match", Value: query}}
+		proj := bson.D{{Key: "$project", Value: projection}}
+		lookup := bson.D{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: ClientsCollection},
+			{Key: "localField", Value: "user_sessions.client_id"},
+			{Key: "foreignField", Value: "_id"},
+			{Key: "as", Value: "session_clients"}}}}
+		//Attach the client document to each session
+		attach := bson.D{{Key: "$addFields", Value: bson.M{
+			"user_sessions": bson.M{
+				"$map": bson.M{
+					"input": bson.M{"$ifNull": bson.A{"$user_sessions", bson.A{}}},
+					"as":    "s",
+					"in": bson.M{
+						"$mergeObjects": bson.A{"$$s", bson.M{
+							"client": bson.M{
+								"$arrayElemAt": bson.A{bson.M{
+									"$filter": bson.M{
+										"input": "$session_clients",
+										"as":    "c",
+										"cond":  bson.M{"$eq": bson.A{"$$c._id", "$$s.client_id"}},
+									},
+								}, 0},
+							},
+						}},
+					},
+				},
+			},
+		}}}
+		clean := bson.D{{Key: "$project", Value: bson.M{
+			"session_clients":                 0,
+			"user_sessions.client.ref_tokens": 0,
+		}}}
+		cur, err := u.usersCol.Aggregate(ctx, mongo.Pipeline{match, proj, lookup, attach, clean})
 		if err != nil {
 			switch err {
 			case mongo.ErrClientDisconnected:
-				return nil, errors.NoType.New("")
+				return nil, errors.ErrDatabaseDown.New("")
+			default:
+				return nil, errors.NoType.Wrap(err, "")
 			}
 		}
-		if cur != nil && cur.Next(ctx) {
+		defer cur.Close(ctx)
+		if cur.Next(ctx) {
 			err = cur.Decode(&usr)
 			if err != nil {
 				return nil, errors.NoType.New("")
@@ -204,14 +234,175 @@ func (u UserRepo) Update(ctx context.Context, userID string, user *model.User) e
 }
 
 func (u UserRepo) FindSessions(ctx context.Context, id string) (*[]model.UserSession, error) {
-	panic("implement me")
+	params := &store.UserFields{
+		UserSessions: true,
+	}
+	usr, err := u.FindById(ctx, id, params)
+	if err != nil {
+		return nil, err
+	}
+	return &usr.UserSessions, nil
 }
+
+//sessionActivityInterval limits writes of session activity, it is marked at most once per interval
+const sessionActivityInterval = time.Minute
+
+//CheckSession checks that session is alive and marks its activity
 func (u UserRepo) CheckSession(ctx context.Context, id string) error {
-	panic("implement me")
+	sessionObjectID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid session ID %s", id)
+	}
+	now := time.Now()
+	query := bson.M{
+		"user_sessions": bson.M{
+			"$elemMatch": bson.M{
+				"id":               sessionObjectID,
+				"last_active_time": bson.M{"$not": bson.M{"$gte": primitive.NewDateTimeFromTime(now.Add(-sessionActivityInterval))}},
+			},
+		},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"user_sessions.$.last_active_time": primitive.NewDateTimeFromTime(now),
+		},
+	}
+	res, err := u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount > 0 {
+		return nil
+	}
+	//activity is marked recently or session is closed
+	count, err := u.usersCol.CountDocuments(ctx, bson.M{"user_sessions.id": sessionObjectID}, options.Count().SetLimit(1))
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if count == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid session ID %s", id)
+	}
+	return nil
 }
 
 func (u UserRepo) FindUserClientRoles(ctx context.Context, userID, clientID string) ([]model.UserRole, error) {
-	panic("implement me")
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	query := bson.M{
+		"_id": userObjectID,
+	}
+	proj := bson.M{
+		"user_roles": bson.M{
+			"$elemMatch": bson.M{
+				"client_id": clientObjectID,
+			},
+		},
+	}
+	var usr *User
+	err = u.usersCol.FindOne(ctx, query, options.FindOne().SetProjection(proj)).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	roles := make([]model.UserRole, 0)
+	for _, v := range usr.ClientRoles {
+		role := model.UserRole{
+			ClientID: v.ClientID.Hex(),
+			Roles:    v.Roles,
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+//GrantRole adds the role of client to user, granting the role twice has no effect
+func (u UserRepo) GrantRole(ctx context.Context, userID, clientID, role string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	found, err := grantClientRole(ctx, u.usersCol, userObjectID, "user_roles", clientObjectID, role)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if !found {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	return nil
+}
+
+//RevokeRole removes the role of client from user
+func (u UserRepo) RevokeRole(ctx context.Context, userID, clientID, role string) error {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	found, err := revokeClientRole(ctx, u.usersCol, userObjectID, "user_roles", clientObjectID, role)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if !found {
+		return errors.ErrInvalidArgument.Newf("Role %s is not granted to user.", role)
+	}
+	return nil
+}
+
+//FindRoleAssignments returns the users who have roles in the client
+func (u UserRepo) FindRoleAssignments(ctx context.Context, clientID string) ([]model.RoleAssignment, error) {
+	clientObjectID, err := primitive.ObjectIDFromHex(clientID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid client ID %s", clientID)
+	}
+	query := bson.M{
+		"user_roles.client_id": clientObjectID,
+	}
+	proj := bson.M{
+		"username": 1,
+		"user_roles": bson.M{
+			"$elemMatch": bson.M{
+				"client_id": clientObjectID,
+			},
+		},
+	}
+	cur, err := u.usersCol.Find(ctx, query, options.Find().SetProjection(proj))
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	var users []User
+	if err = cur.All(ctx, &users); err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	assignments := make([]model.RoleAssignment, 0, len(users))
+	for _, v := range users {
+		if len(v.ClientRoles) == 0 {
+			continue
+		}
+		assignments = append(assignments, model.RoleAssignment{
+			UserID:   v.ID.Hex(),
+			UserName: v.UserName,
+			ClientID: clientID,
+			Roles:    v.ClientRoles[0].Roles,
+		})
+	}
+	return assignments, nil
 }
 func (u UserRepo) DeleteById(ctx context.Context, userID string) error {
 	ID, err := primitive.ObjectIDFromHex(userID)
@@ -331,9 +522,11 @@ func (u *UserRepo) CreateSession(ctx context.Context, userID string) (string, er
 		return "", errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
 	}
 
-	client := fmt.Sprintf("%v", ctx.Value(config.ContextClientIDKey))
+	client, _ := ctx.Value(config.ContextClientIDKey).(string)
 	clientObjectID, _ := primitive.ObjectIDFromHex(client)
-	device := fmt.Sprintf("%v", ctx.Value(config.ContextDeviceKey))
+	device, _ := ctx.Value(config.ContextDeviceKey).(string)
+	ip, _ := ctx.Value(config.ContextIPKey).(string)
+	location, _ := ctx.Value(config.ContextLocationKey).(string)
 
 	query := bson.M{
 		"_id": userObjectID,
@@ -345,6 +538,8 @@ func (u *UserRepo) CreateSession(ctx context.Context, userID string) (string, er
 		ID:             sessionObjectID,
 		ClientID:       clientObjectID,
 		Device:         device,
+		IP:             ip,
+		Location:       location,
 		LastActiveDate: primitive.NewDateTimeFromTime(time.Now()),
 	}
 
@@ -386,7 +581,9 @@ func (u *UserRepo) DeleteSession(ctx context.Context, userID, sessionID string)
 
 	update := bson.M{
 		"$pull": bson.M{
-			"user_sessions": sessionObjectID,
+			"user_sessions": bson.M{
+				"id": sessionObjectID,
+			},
 		},
 	}
 
@@ -406,23 +603,123 @@ func (u *UserRepo) DeleteSession(ctx context.Context, userID, sessionID string)
 	return nil
 }
 
+func (u *UserRepo) FindSession(ctx context.Context, userID, sessionID string) (*model.UserSession, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	sessionObjectID, err := primitive.ObjectIDFromHex(sessionID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
+	}
+	query := bson.M{
+		"_id": userObjectID,
+	}
+	proj := bson.M{
+		"user_sessions": bson.M{
+			"$elemMatch": bson.M{
+				"id": sessionObjectID,
+			},
+		},
+	}
+	var usr *User
+	err = u.usersCol.FindOne(ctx, query, options.FindOne().SetProjection(proj)).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	if len(usr.UserSessions) == 0 {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid session ID %s", sessionID)
+	}
+	return ToUserSession(&usr.UserSessions[0]), nil
+}
+
+//DeleteSessions removes all user sessions except exceptSessionID and returns the removed ones
+func (u *UserRepo) DeleteSessions(ctx context.Context, userID, exceptSessionID string) ([]model.UserSession, error) {
+	userObjectID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	query := bson.M{
+		"_id": userObjectID,
+	}
+	proj := bson.M{
+		"user_sessions": 1,
+	}
+	var usr *User
+	err = u.usersCol.FindOne(ctx, query, options.FindOne().SetProjection(proj)).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	sessions := make([]model.UserSession, 0)
+	sessionIDs := make([]primitive.ObjectID, 0)
+	for _, v := range usr.UserSessions {
+		if v.ID.Hex() == exceptSessionID {
+			continue
+		}
+		sessions = append(sessions, *ToUserSession(&v))
+		sessionIDs = append(sessionIDs, v.ID)
+	}
+	if len(sessionIDs) == 0 {
+		return sessions, nil
+	}
+	update := bson.M{
+		"$pull": bson.M{
+			"user_sessions": bson.M{
+				"id": bson.M{
+					"$in": sessionIDs,
+				},
+			},
+		},
+	}
+	_, err = u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	return sessions, nil
+}
+
+//ToUserSession converts "Session" database model to "DTO" model
+func ToUserSession(session *UserSession) *model.UserSession {
+	return &model.UserSession{
+		SessionID:      session.ID.Hex(),
+		ClientID:       session.ClientID.Hex(),
+		Device:         session.Device,
+		IP:             session.IP,
+		Location:       session.Location,
+		LastActiveTime: session.LastActiveDate.Time(),
+	}
+}
+
 //Convert "User" database model to "DTO" model without ObjectID's
 func ToUserClient(usr *UserClient) *model.User {
 	sessions := make([]model.UserSession, 0)
 	roles := make([]model.UserRole, 0)
 	for _, v := range usr.UserSessions {
 		session := model.UserSession{
-			SessionID:      v.SessionID,
+			SessionID:      v.ID.Hex(),
+			ClientID:       v.Client.ID.Hex(),
 			ClientName:     v.Client.ClientName,
 			Device:         v.Device,
+			IP:             v.IP,
+			Location:       v.Location,
 			LastActiveTime: v.LastActiveDate.Time(),
 		}
 		sessions = append(sessions, session)
 	}
-	for _, v := range usr.Roles {
+	for _, v := range usr.ClientRoles {
 		role := model.UserRole{
-			ClientName: v.Client.ClientName,
-			Roles:      v.Roles,
+			ClientID: v.ClientID.Hex(),
+			Roles:    v.Roles,
 		}
 		roles = append(roles, role)
 	}
@@ -434,13 +731,14 @@ func ToUserClient(usr *UserClient) *model.User {
 	}
 
 	return &model.User{
-		ID:           usr.ID.Hex(),
-		UserName:     usr.UserName,
-		Email:        usr.Email,
-		UserInfo:     usr.UserInfo,
-		UserSessions: sessions,
-		CreatedAt:    date,
-		Roles:        roles,
+		ID:             usr.ID.Hex(),
+		UserName:       usr.UserName,
+		Email:          usr.Email,
+		EmailConfirmed: usr.EmailConfirmed,
+		UserInfo:       usr.UserInfo,
+		UserSessions:   sessions,
+		CreatedAt:      date,
+		Roles:          roles,
 	}
 }
 
@@ -454,3 +752,65 @@ func ToDb(usr *model.User) *User {
 	}
 	return user
 }
+
+func (u UserRepo) SetPasswordHash(ctx context.Context, userID, passwordHash string, historySize int) error {
+	ID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid userID %s", userID)
+	}
+	var update interface{} = bson.M{
+		"$set": bson.M{"password_hash": passwordHash},
+	}
+	//Current password counts in history, so historySize-1 previous hashes are kept
+	if historySize > 1 {
+		update = mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{
+				"password_history": bson.M{"$slice": bson.A{
+					bson.M{"$concatArrays": bson.A{
+						bson.M{"$ifNull": bson.A{"$password_history", bson.A{}}},
+						bson.A{"$password_hash"},
+					}},
+					-(historySize - 1),
+				}},
+				"password_hash": passwordHash,
+			}}},
+		}
+	}
+	res, err := u.usersCol.UpdateOne(ctx, bson.M{"_id": ID}, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid userID %s", userID)
+	}
+	return nil
+}
+
+func (u UserRepo) FindPasswordHistory(ctx context.Context, userID string) ([]string, error) {
+	ID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid userID %s", userID)
+	}
+	opt := options.FindOne().SetProjection(bson.M{
+		"password_hash":    1,
+		"password_history": 1,
+	})
+	var user struct {
+		PasswordHash    string   `bson:"password_hash,omitempty"`
+		PasswordHistory []string `bson:"password_history,omitempty"`
+	}
+	err = u.usersCol.FindOne(ctx, bson.M{"_id": ID}, opt).Decode(&user)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid userID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	history := make([]string, 0, len(user.PasswordHistory)+1)
+	if len(user.PasswordHash) > 0 {
+		history = append(history, user.PasswordHash)
+	}
+	return append(history, user.PasswordHistory...), nil
+}
@@ -0,0 +1,181 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+//WebAuthnCredential represent attached "WebAuthnCredentials" document in "User"
+type WebAuthnCredential struct {
+	ID                string              `bson:"id"`
+	Name              string              `bson:"name,omitempty"`
+	PublicKey         []byte              `bson:"public_key"`
+	SignCount         int64               `bson:"sign_count"`
+	AttestationFormat string              `bson:"attestation_format,omitempty"`
+	AAGUID            string              `bson:"aaguid,omitempty"`
+	Transports        []string            `bson:"transports,omitempty"`
+	BackupEligible    bool                `bson:"backup_eligible,omitempty"`
+	CreatedAt         *primitive.DateTime `bson:"created_at,omitempty"`
+	LastUsedAt        *primitive.DateTime `bson:"last_used_at,omitempty"`
+}
+
+//userWebAuthn is a projection of user to its credentials
+type userWebAuthn struct {
+	ID          primitive.ObjectID   `bson:"_id"`
+	Credentials []WebAuthnCredential `bson:"webauthn_credentials,omitempty"`
+}
+
+func (u UserRepo) AddWebAuthnCredential(ctx context.Context, userID string, credential *model.WebAuthnCredential) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	dbCredential := ToDbWebAuthnCredential(credential)
+	createdAt := primitive.NewDateTimeFromTime(time.Now())
+	dbCredential.CreatedAt = &createdAt
+	query := bson.M{
+		"_id":                     oid,
+		"webauthn_credentials.id": bson.M{"$ne": credential.ID},
+	}
+	update := bson.M{"$push": bson.M{"webauthn_credentials": dbCredential}}
+	res, err := u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("Credential is already registered.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrDuplicateEntry.New("Credential is already registered.")
+	}
+	return nil
+}
+
+func (u UserRepo) FindWebAuthnCredentials(ctx context.Context, userID string) ([]model.WebAuthnCredential, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	opt := options.FindOne().SetProjection(bson.M{"webauthn_credentials": true})
+	var usr userWebAuthn
+	err = u.usersCol.FindOne(ctx, bson.M{"_id": oid}, opt).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	credentials := make([]model.WebAuthnCredential, 0, len(usr.Credentials))
+	for i := range usr.Credentials {
+		credentials = append(credentials, *ToWebAuthnCredential(&usr.Credentials[i]))
+	}
+	return credentials, nil
+}
+
+func (u UserRepo) FindWebAuthnCredential(ctx context.Context, credentialID string) (string, *model.WebAuthnCredential, error) {
+	query := bson.M{"webauthn_credentials.id": credentialID}
+	opt := options.FindOne().SetProjection(bson.M{
+		"webauthn_credentials": bson.M{"$elemMatch": bson.M{"id": credentialID}},
+	})
+	var usr userWebAuthn
+	err := u.usersCol.FindOne(ctx, query, opt).Decode(&usr)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return "", nil, errors.ErrUnauthorized.New("Unknown credential.")
+		default:
+			return "", nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	if len(usr.Credentials) == 0 {
+		return "", nil, errors.ErrUnauthorized.New("Unknown credential.")
+	}
+	return usr.ID.Hex(), ToWebAuthnCredential(&usr.Credentials[0]), nil
+}
+
+func (u UserRepo) UpdateWebAuthnSignCount(ctx context.Context, userID, credentialID string, oldCount, newCount uint32) (bool, error) {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return false, errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	query := bson.M{
+		"_id": oid,
+		"webauthn_credentials": bson.M{"$elemMatch": bson.M{
+			"id":         credentialID,
+			"sign_count": int64(oldCount),
+		}},
+	}
+	update := bson.M{"$set": bson.M{
+		"webauthn_credentials.$.sign_count":   int64(newCount),
+		"webauthn_credentials.$.last_used_at": primitive.NewDateTimeFromTime(time.Now()),
+	}}
+	res, err := u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return false, errors.NoType.Wrap(err, "")
+	}
+	return res.MatchedCount > 0, nil
+}
+
+func (u UserRepo) DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error {
+	oid, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return errors.ErrInvalidArgument.Newf("Invalid user ID %s", userID)
+	}
+	query := bson.M{
+		"_id":                     oid,
+		"webauthn_credentials.id": credentialID,
+	}
+	update := bson.M{"$pull": bson.M{"webauthn_credentials": bson.M{"id": credentialID}}}
+	res, err := u.usersCol.UpdateOne(ctx, query, update)
+	if err != nil {
+		return errors.NoType.Wrap(err, "")
+	}
+	if res.MatchedCount == 0 {
+		return errors.ErrInvalidArgument.Newf("Invalid credential ID %s", credentialID)
+	}
+	return nil
+}
+
+func ToDbWebAuthnCredential(credential *model.WebAuthnCredential) *WebAuthnCredential {
+	return &WebAuthnCredential{
+		ID:                credential.ID,
+		Name:              credential.Name,
+		PublicKey:         credential.PublicKey,
+		SignCount:         int64(credential.SignCount),
+		AttestationFormat: credential.AttestationFormat,
+		AAGUID:            credential.AAGUID,
+		Transports:        credential.Transports,
+		BackupEligible:    credential.BackupEligible,
+	}
+}
+
+func ToWebAuthnCredential(dbCredential *WebAuthnCredential) *model.WebAuthnCredential {
+	credential := &model.WebAuthnCredential{
+		ID:                dbCredential.ID,
+		Name:              dbCredential.Name,
+		PublicKey:         dbCredential.PublicKey,
+		SignCount:         uint32(dbCredential.SignCount),
+		AttestationFormat: dbCredential.AttestationFormat,
+		AAGUID:            dbCredential.AAGUID,
+		Transports:        dbCredential.Transports,
+		BackupEligible:    dbCredential.BackupEligible,
+	}
+	if dbCredential.CreatedAt != nil {
+		createdAt := dbCredential.CreatedAt.Time()
+		credential.CreatedAt = &createdAt
+	}
+	if dbCredential.LastUsedAt != nil {
+		lastUsedAt := dbCredential.LastUsedAt.Time()
+		credential.LastUsedAt = &lastUsedAt
+	}
+	return credential
+}
@@ -0,0 +1,88 @@
+package mongo_store
+
+import (
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+//WebAuthnChallenge represent the "WebAuthnChallenges" collection, only hash of challenge is stored
+type WebAuthnChallenge struct {
+	ChallengeHash string             `bson:"_id"`
+	Purpose       string             `bson:"purpose,omitempty"`
+	UserID        primitive.ObjectID `bson:"user_id,omitempty"`
+	ClientID      primitive.ObjectID `bson:"client_id,omitempty"`
+	ExpIn         primitive.DateTime `bson:"exp_in,omitempty"`
+	CreatedAt     primitive.DateTime `bson:"created_at,omitempty"`
+}
+
+type WebAuthnChallengeRepo struct {
+	store         *Store
+	challengesCol *mongo.Collection
+}
+
+func (w *WebAuthnChallengeRepo) Create(ctx context.Context, challenge *model.WebAuthnChallenge) error {
+	if challenge == nil || len(challenge.Challenge) == 0 {
+		return errors.ErrInvalidArgument.New("Invalid WebAuthn challenge.")
+	}
+	_, err := w.challengesCol.InsertOne(ctx, ToDbWebAuthnChallenge(challenge))
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			return errors.ErrDuplicateEntry.New("WebAuthn challenge already exists.")
+		}
+		return errors.NoType.Wrap(err, "")
+	}
+	return nil
+}
+
+func (w *WebAuthnChallengeRepo) Consume(ctx context.Context, challenge string) (*model.WebAuthnChallenge, error) {
+	query := bson.M{
+		"_id":    hashCode(challenge),
+		"exp_in": unexpired(),
+	}
+	var dbChallenge *WebAuthnChallenge
+	err := w.challengesCol.FindOneAndDelete(ctx, query).Decode(&dbChallenge)
+	if err != nil {
+		switch err {
+		case mongo.ErrNoDocuments:
+			return nil, errors.ErrUnauthorized.New("Invalid or expired WebAuthn challenge.")
+		default:
+			return nil, errors.NoType.Wrap(err, "")
+		}
+	}
+	result := ToWebAuthnChallenge(dbChallenge)
+	result.Challenge = challenge
+	return result, nil
+}
+
+func ToDbWebAuthnChallenge(challenge *model.WebAuthnChallenge) *WebAuthnChallenge {
+	userID, _ := primitive.ObjectIDFromHex(challenge.UserID)
+	clientID, _ := primitive.ObjectIDFromHex(challenge.ClientID)
+	return &WebAuthnChallenge{
+		ChallengeHash: hashCode(challenge.Challenge),
+		Purpose:       challenge.Purpose,
+		UserID:        userID,
+		ClientID:      clientID,
+		ExpIn:         primitive.NewDateTimeFromTime(challenge.ExpIn),
+		CreatedAt:     primitive.NewDateTimeFromTime(challenge.CreatedAt),
+	}
+}
+
+func ToWebAuthnChallenge(dbChallenge *WebAuthnChallenge) *model.WebAuthnChallenge {
+	challenge := &model.WebAuthnChallenge{
+		Purpose:   dbChallenge.Purpose,
+		ExpIn:     dbChallenge.ExpIn.Time(),
+		CreatedAt: dbChallenge.CreatedAt.Time(),
+	}
+	if !dbChallenge.UserID.IsZero() {
+		challenge.UserID = dbChallenge.UserID.Hex()
+	}
+	if !dbChallenge.ClientID.IsZero() {
+		challenge.ClientID = dbChallenge.ClientID.Hex()
+	}
+	return challenge
+}
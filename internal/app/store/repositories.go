@@ -3,6 +3,7 @@ package store
 import (
 	"auth-server/internal/app/model"
 	"context"
+	"time"
 )
 
 const (
@@ -27,6 +28,9 @@ type (
 		UserCrud
 		UserSessionsFinder
 		UserPassChecker
+		UserRolesManager
+		UserMFAManager
+		UserWebAuthnManager
 	}
 	UserCrud interface {
 		FindById(ctx context.Context, id string, params *UserFields) (*model.User, error)
@@ -38,12 +42,49 @@ type (
 		DeleteById(ctx context.Context, userID string) error
 		DeleteByName(ctx context.Context, userID string) error
 		CreateSession(ctx context.Context, userID string) (string, error)
+		FindSession(ctx context.Context, userID, sessionID string) (*model.UserSession, error)
 		DeleteSession(ctx context.Context, userID, sessionID string) error
+		DeleteSessions(ctx context.Context, userID, exceptSessionID string) ([]model.UserSession, error)
 	}
 	UserPassChecker interface {
 		CheckPassByID(ctx context.Context, userID, passwordHash string) error
 		CheckPassByName(ctx context.Context, username, passwordHash string) error
 		CheckPassByEmail(ctx context.Context, email, passwordHash string) error
+		//SetPasswordHash replaces password hash, the old one is kept in history of historySize passwords.
+		//Zero historySize replaces hash of the same password without history change.
+		SetPasswordHash(ctx context.Context, userID, passwordHash string, historySize int) error
+		//FindPasswordHistory returns current password hash and hashes of previous passwords
+		FindPasswordHistory(ctx context.Context, userID string) ([]string, error)
+	}
+	//Roles granted to users per client
+	UserRolesManager interface {
+		GrantRole(ctx context.Context, userID, clientID, role string) error
+		RevokeRole(ctx context.Context, userID, clientID, role string) error
+		FindRoleAssignments(ctx context.Context, clientID string) ([]model.RoleAssignment, error)
+	}
+	//Second factor of users
+	UserMFAManager interface {
+		FindMFA(ctx context.Context, userID string) (*model.MFA, error)
+		//SetTOTPSecret saves secret of pending enrollment, it fails if TOTP is enabled
+		SetTOTPSecret(ctx context.Context, userID, secret string) error
+		//EnableTOTP enables pending TOTP and replaces recovery codes
+		EnableTOTP(ctx context.Context, userID string, step int64, recoveryCodes []string) error
+		DisableTOTP(ctx context.Context, userID string) error
+		SetRecoveryCodes(ctx context.Context, userID string, recoveryCodes []string) error
+		//UseTOTPStep saves time step of accepted code, it returns false if the step was already used
+		UseTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+		//UseRecoveryCode removes hash of recovery code, it returns false if user has no such code
+		UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	}
+	//Passkeys of users
+	UserWebAuthnManager interface {
+		AddWebAuthnCredential(ctx context.Context, userID string, credential *model.WebAuthnCredential) error
+		FindWebAuthnCredentials(ctx context.Context, userID string) ([]model.WebAuthnCredential, error)
+		//FindWebAuthnCredential returns the credential and ID of user who owns it
+		FindWebAuthnCredential(ctx context.Context, credentialID string) (string, *model.WebAuthnCredential, error)
+		//UpdateWebAuthnSignCount saves sign counter if it wasn't changed by concurrent login, it returns false otherwise
+		UpdateWebAuthnSignCount(ctx context.Context, userID, credentialID string, oldCount, newCount uint32) (bool, error)
+		DeleteWebAuthnCredential(ctx context.Context, userID, credentialID string) error
 	}
 	UserSessionsFinder interface {
 		FindSessions(ctx context.Context, id string) (*[]model.UserSession, error)
@@ -53,6 +94,7 @@ type (
 	UserFields struct {
 		UserName         bool `json:"username,omitempty"`
 		Email            bool `json:"email,omitempty"`
+		EmailConfirmed   bool `json:"-"`
 		CreatedAt        bool `json:"created_at,omitempty"`
 		UserInfo         bool `json:"user_info,omitempty"`
 		UserSessions     bool `json:"user_sessions,omitempty"`
@@ -64,9 +106,151 @@ type (
 	ClientRepository interface {
 		//CRUD methods
 		FindById(ctx context.Context, id string) (*model.Client, error)
+		Create(ctx context.Context, client *model.Client) (string, error)
+		FindAll(ctx context.Context) ([]model.Client, error)
+		FindServiceAccounts(ctx context.Context) ([]model.Client, error)
+		//Update replaces registration data and secret hash of client
+		Update(ctx context.Context, client *model.Client) error
+		UpdateSecret(ctx context.Context, clientID, secretHash string) error
+		Delete(ctx context.Context, clientID string) error
 		CreateRefToken(ctx context.Context, clientID string, refToken *model.ClientRefToken) error
+		//FindRefToken returns the current refresh token, sessionID may be empty if it's unknown
 		FindRefToken(ctx context.Context, clientID, sessionID, refToken string) (*model.ClientRefToken, error)
+		FindRotatedRefToken(ctx context.Context, clientID, refToken string) (*model.ClientRefToken, error)
 		CheckRefToken(ctx context.Context, clientID, sessionID, refToken string) (bool, error)
+		RotateRefToken(ctx context.Context, clientID, oldRefToken string, newRefToken *model.ClientRefToken) error
 		DeleteRefToken(ctx context.Context, clientID, sessionID, refToken string) error
+		DeleteSessionRefTokens(ctx context.Context, clientID, sessionID string) error
+	}
+
+	//GroupRepository interface
+	GroupRepository interface {
+		Create(ctx context.Context, group *model.Group) (string, error)
+		FindByID(ctx context.Context, groupID string) (*model.Group, error)
+		FindByIDs(ctx context.Context, groupIDs []string) ([]model.Group, error)
+		FindAll(ctx context.Context) ([]model.Group, error)
+		//FindUserGroups returns the groups which user is member of, parent groups are not returned
+		FindUserGroups(ctx context.Context, userID string) ([]model.Group, error)
+		CountChildren(ctx context.Context, groupID string) (int64, error)
+		//FindChildren returns the direct subgroups of groups
+		FindChildren(ctx context.Context, parentIDs []string) ([]model.Group, error)
+		//Update replaces name, description and parent of group
+		Update(ctx context.Context, group *model.Group) error
+		Delete(ctx context.Context, groupID string) error
+		AddMember(ctx context.Context, groupID, userID string) error
+		RemoveMember(ctx context.Context, groupID, userID string) error
+		GrantRole(ctx context.Context, groupID, clientID, role string) error
+		RevokeRole(ctx context.Context, groupID, clientID, role string) error
+	}
+
+	//AuthCodeRepository interface
+	AuthCodeRepository interface {
+		Create(ctx context.Context, code *model.AuthCode) error
+		//Consume returns the code and removes it, so each code may be used once. Pending codes aren't consumed.
+		Consume(ctx context.Context, code string) (*model.AuthCode, error)
+		//Approve makes pending code exchangeable till expIn
+		Approve(ctx context.Context, code string, expIn time.Time) (*model.AuthCode, error)
+		DeletePending(ctx context.Context, code string) (*model.AuthCode, error)
+	}
+
+	//ScopeRepository is a registry of scopes
+	ScopeRepository interface {
+		Create(ctx context.Context, scope *model.Scope) error
+		FindAll(ctx context.Context) ([]model.Scope, error)
+		//FindByNames returns the registered scopes, unknown names are skipped
+		FindByNames(ctx context.Context, names []string) ([]model.Scope, error)
+		Update(ctx context.Context, scope *model.Scope) error
+		Delete(ctx context.Context, name string) error
+	}
+
+	//ConsentRepository keeps scopes which users granted to clients
+	ConsentRepository interface {
+		Find(ctx context.Context, userID, clientID string) (*model.Consent, error)
+		FindByUser(ctx context.Context, userID string) ([]model.Consent, error)
+		//Grant adds scopes to consent of user, consent is created if user has no one
+		Grant(ctx context.Context, userID, clientID string, scopes []string) error
+		Delete(ctx context.Context, userID, clientID string) error
+	}
+
+	//MFAChallengeRepository keeps logins which wait for second factor
+	MFAChallengeRepository interface {
+		Create(ctx context.Context, challenge *model.MFAChallenge) error
+		//Find returns unexpired challenge without counting an answer
+		Find(ctx context.Context, token string) (*model.MFAChallenge, error)
+		//Attempt counts an answer to unexpired challenge, challenge with maxAttempts answers isn't returned
+		Attempt(ctx context.Context, token string, maxAttempts int) (*model.MFAChallenge, error)
+		//Consume removes answered challenge, so each challenge creates one session
+		Consume(ctx context.Context, token string) error
+	}
+
+	//WebAuthnChallengeRepository keeps challenges of WebAuthn ceremonies
+	WebAuthnChallengeRepository interface {
+		Create(ctx context.Context, challenge *model.WebAuthnChallenge) error
+		//Consume returns unexpired challenge and removes it, so each challenge is answered once
+		Consume(ctx context.Context, challenge string) (*model.WebAuthnChallenge, error)
+	}
+
+	//PasswordResetRepository keeps tokens which were sent to users who forgot password
+	PasswordResetRepository interface {
+		Create(ctx context.Context, reset *model.PasswordReset) error
+		//Find returns unexpired token without using it
+		Find(ctx context.Context, token string) (*model.PasswordReset, error)
+		//Consume returns unexpired token and removes it, so each token is used once
+		Consume(ctx context.Context, token string) (*model.PasswordReset, error)
+		//FindLastByUser returns the latest unexpired token of user without its value
+		FindLastByUser(ctx context.Context, userID string) (*model.PasswordReset, error)
+		DeleteByUser(ctx context.Context, userID string) error
+	}
+
+	//LoginAttemptRepository keeps counters of failed logins per account and per IP address
+	LoginAttemptRepository interface {
+		//Find returns counter without failures if key has no unexpired one
+		Find(ctx context.Context, key string) (*model.LoginAttempts, error)
+		//Fail counts failed login and blocks key by the throttle in one update, it is model.LoginAttempts.Fail.
+		//Counter expires after lockout duration since the last failure.
+		Fail(ctx context.Context, key string, throttle *model.LoginThrottle) (*model.LoginAttempts, error)
+		Reset(ctx context.Context, key string) error
+	}
+
+	//RateLimitRepository keeps token buckets of rate limiter
+	RateLimitRepository interface {
+		//Take takes one token from bucket of the key, new bucket is full.
+		//It returns bucket after the request and false if bucket had no token.
+		Take(ctx context.Context, key string, rate float64, burst int) (*model.TokenBucket, bool, error)
+	}
+
+	//DeviceCodeRepository interface
+	DeviceCodeRepository interface {
+		Create(ctx context.Context, auth *model.DeviceAuthorization) error
+		FindByUserCode(ctx context.Context, userCode string) (*model.DeviceAuthorization, error)
+		//Decide sets status of pending authorization found by user code
+		Decide(ctx context.Context, userCode, status, userID string) error
+		//Poll records the poll time and returns the authorization as it was before the poll
+		Poll(ctx context.Context, deviceCode string) (*model.DeviceAuthorization, error)
+		SlowDown(ctx context.Context, deviceCode string, interval time.Duration) error
+		//Consume removes decided authorization, so tokens are issued once
+		Consume(ctx context.Context, deviceCode string) (*model.DeviceAuthorization, error)
+	}
+
+	//EventRepository interface
+	EventRepository interface {
+		Create(ctx context.Context, event *model.SecurityEvent) error
+	}
+
+	//RevokedTokenRepository is a denylist of access tokens revoked before expiration
+	RevokedTokenRepository interface {
+		//Create returns ErrDuplicateEntry if token is already revoked
+		Create(ctx context.Context, tokenID string, expIn time.Time) error
+		//FindActive returns IDs of revoked tokens which aren't expired yet with their expiration
+		FindActive(ctx context.Context) (map[string]time.Time, error)
+	}
+
+	//KeyRepository interface
+	KeyRepository interface {
+		Create(ctx context.Context, key *model.SigningKey) error
+		//FindValid returns the active and not expired retired keys
+		FindValid(ctx context.Context) ([]model.SigningKey, error)
+		//Retire deactivates all keys except one, the keys verify tokens till expIn
+		Retire(ctx context.Context, exceptID string, expIn time.Time) error
 	}
 )
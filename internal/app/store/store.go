@@ -6,4 +6,17 @@ package store
 type Store interface {
 	User() UserRepository
 	Client() ClientRepository
+	Event() EventRepository
+	AuthCode() AuthCodeRepository
+	Key() KeyRepository
+	RevokedToken() RevokedTokenRepository
+	DeviceCode() DeviceCodeRepository
+	Group() GroupRepository
+	Scope() ScopeRepository
+	Consent() ConsentRepository
+	MFAChallenge() MFAChallengeRepository
+	WebAuthnChallenge() WebAuthnChallengeRepository
+	PasswordReset() PasswordResetRepository
+	LoginAttempt() LoginAttemptRepository
+	RateLimit() RateLimitRepository
 }
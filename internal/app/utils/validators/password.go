@@ -0,0 +1,214 @@
+package validators
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	"auth-server/pkg/breached"
+	errors "auth-server/pkg/errors/types"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Rules of password policy, they are keys of error params
+const (
+	RuleMinLength    = "min_length"
+	RuleMaxLength    = "max_length"
+	RuleUpper        = "upper"
+	RuleLower        = "lower"
+	RuleDigit        = "digit"
+	RuleSymbol       = "symbol"
+	RuleAllowedChars = "allowed_chars"
+	RuleStrength     = "strength"
+	RulePersonalInfo = "personal_info"
+	RuleHistory      = "history"
+	RuleBreached     = "breached"
+)
+
+//minPersonalInfoLength is a length of username or email part which is too short to be banned in password
+const minPersonalInfoLength = 3
+
+type (
+	//PasswordPolicy is a set of rules which new passwords must satisfy
+	PasswordPolicy struct {
+		MinLength      int
+		MaxLength      int
+		RequireUpper   bool
+		RequireLower   bool
+		RequireDigits  bool
+		RequireSymbols bool
+		//AllowedChars matches passwords which consist of allowed characters, it is nil if any character is allowed
+		AllowedChars *regexp.Regexp
+		//MinStrength is a min score of strength estimation, from 0 to 4
+		MinStrength int
+		//BanPersonalInfo bans passwords which contain username or email
+		BanPersonalInfo bool
+		//HistorySize is a number of last passwords which can't be reused
+		HistorySize int
+		//Breached is a set of known breached passwords, it is nil if check is disabled
+		Breached BreachedPasswords
+	}
+	//BreachedPasswords reports whether password is known from data breaches
+	BreachedPasswords interface {
+		Contains(password string) bool
+	}
+	//PasswordHistory reports whether password matches one of the last passwords of user
+	PasswordHistory func(password string) bool
+
+	//violation is a failed rule of policy
+	violation struct {
+		rule    string
+		message string
+	}
+)
+
+//NewPasswordPolicy creates policy from config
+func NewPasswordPolicy(config *cfg.Config) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{
+		MinLength:       config.PasswordMinLength,
+		MaxLength:       config.PasswordMaxLength,
+		RequireUpper:    config.PasswordRequireUpper,
+		RequireLower:    config.PasswordRequireLower,
+		RequireDigits:   config.PasswordRequireDigits,
+		RequireSymbols:  config.PasswordRequireSymbols,
+		MinStrength:     config.PasswordMinStrength,
+		BanPersonalInfo: config.PasswordBanPersonalInfo,
+		HistorySize:     config.PasswordHistorySize,
+	}
+	if policy.MinLength < 1 || policy.MaxLength < policy.MinLength {
+		return nil, errors.ErrInvalidArgument.New("Invalid password length limits.")
+	}
+	if policy.MinStrength < 0 || policy.MinStrength > maxStrengthScore {
+		return nil, errors.ErrInvalidArgument.Newf("Password strength must be from 0 to %d.", maxStrengthScore)
+	}
+	if policy.HistorySize < 0 {
+		return nil, errors.ErrInvalidArgument.New("Invalid password history size.")
+	}
+	if len(config.PasswordAllowedChars) > 0 {
+		allowed, err := regexp.Compile(`^(?:` + config.PasswordAllowedChars + `)*$`)
+		if err != nil {
+			return nil, errors.ErrInvalidArgument.Newf("Invalid allowed password characters %s.", config.PasswordAllowedChars)
+		}
+		policy.AllowedChars = allowed
+	}
+	if len(config.PasswordBreachedFile) > 0 {
+		filter, err := breached.Open(config.PasswordBreachedFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("Breached passwords filter of %d hashes is loaded.", filter.Len())
+		policy.Breached = filter
+	}
+	return policy, nil
+}
+
+//Check returns error which reports every failed rule in params, user provides personal info and may be nil
+func (p *PasswordPolicy) Check(password string, user *model.User, history PasswordHistory) error {
+	var violations []violation
+	fail := func(rule, message string, args ...interface{}) {
+		violations = append(violations, violation{rule: rule, message: fmt.Sprintf(message, args...)})
+	}
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		fail(RuleMinLength, "Password too short, min length is %d.", p.MinLength)
+	}
+	if length > p.MaxLength {
+		fail(RuleMaxLength, "Password too long, max length is %d.", p.MaxLength)
+	}
+	classes := characterClasses(password)
+	if p.RequireUpper && !classes.upper {
+		fail(RuleUpper, "Password must contain an uppercase letter.")
+	}
+	if p.RequireLower && !classes.lower {
+		fail(RuleLower, "Password must contain a lowercase letter.")
+	}
+	if p.RequireDigits && !classes.digit {
+		fail(RuleDigit, "Password must contain a digit.")
+	}
+	if p.RequireSymbols && !classes.symbol {
+		fail(RuleSymbol, "Password must contain a symbol.")
+	}
+	if p.AllowedChars != nil && !p.AllowedChars.MatchString(password) {
+		fail(RuleAllowedChars, "Password contains characters which are not allowed.")
+	}
+	personal := personalInfo(user)
+	if p.BanPersonalInfo && containsAny(strings.ToLower(password), personal) {
+		fail(RulePersonalInfo, "Password must not contain username or email.")
+	}
+	//Strength estimation takes time which grows with length, too long password is rejected anyway
+	if length <= p.MaxLength {
+		if score := StrengthScore(password, personal); score < p.MinStrength {
+			fail(RuleStrength, "Password too weak, strength is %d of %d required.", score, p.MinStrength)
+		}
+	}
+	if p.Breached != nil && p.Breached.Contains(password) {
+		fail(RuleBreached, "Password is known from data breaches.")
+	}
+	if p.HistorySize > 0 && history != nil && history(password) {
+		fail(RuleHistory, "Password must differ from the last %d passwords.", p.HistorySize)
+	}
+	if len(violations) == 0 {
+		return nil
+	}
+	messages := make([]string, 0, len(violations))
+	params := make(map[string]string, len(violations))
+	for _, v := range violations {
+		messages = append(messages, v.message)
+		params[v.rule] = v.message
+	}
+	err := errors.ErrInvalidArgument.Newf("Error in validation. %s", strings.Join(messages, " "))
+	return errors.AddErrorParams(err, params)
+}
+
+type classes struct {
+	upper, lower, digit, symbol bool
+}
+
+func characterClasses(password string) classes {
+	var c classes
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			c.upper = true
+		case unicode.IsLower(r):
+			c.lower = true
+		case unicode.IsDigit(r):
+			c.digit = true
+		default:
+			c.symbol = true
+		}
+	}
+	return c
+}
+
+//personalInfo returns lowercase username, email and its local part
+func personalInfo(user *model.User) []string {
+	if user == nil {
+		return nil
+	}
+	var info []string
+	add := func(value string) {
+		value = strings.ToLower(value)
+		if utf8.RuneCountInString(value) >= minPersonalInfoLength {
+			info = append(info, value)
+		}
+	}
+	add(user.UserName)
+	add(user.Email)
+	if at := strings.Index(user.Email, "@"); at > 0 {
+		add(user.Email[:at])
+	}
+	return info
+}
+
+func containsAny(value string, parts []string) bool {
+	for _, part := range parts {
+		if strings.Contains(value, part) {
+			return true
+		}
+	}
+	return false
+}
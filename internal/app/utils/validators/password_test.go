@@ -0,0 +1,148 @@
+package validators
+
+import (
+	cfg "auth-server/internal/app/config"
+	"auth-server/internal/app/model"
+	errors "auth-server/pkg/errors/types"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+)
+
+type testBreached map[string]bool
+
+func (b testBreached) Contains(password string) bool {
+	return b[password]
+}
+
+func strictPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:       12,
+		MaxLength:       20,
+		RequireUpper:    true,
+		RequireLower:    true,
+		RequireDigits:   true,
+		RequireSymbols:  true,
+		AllowedChars:    regexp.MustCompile(`^(?:[\x20-\x7e])*$`),
+		MinStrength:     3,
+		BanPersonalInfo: true,
+		HistorySize:     3,
+		Breached:        testBreached{"johnny": true},
+	}
+}
+
+func violatedRules(t *testing.T, err error) []string {
+	if err == nil {
+		return nil
+	}
+	if errors.GetType(err) != errors.ErrInvalidArgument {
+		t.Fatalf("unexpected error %v", err)
+	}
+	var rules []string
+	for rule := range errors.GetErrorParams(err) {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	return rules
+}
+
+func TestCheckReportsAllViolations(t *testing.T) {
+	user := &model.User{UserName: "johnny", Email: "john.smith@example.com"}
+	history := func(password string) bool { return password == "johnny" }
+	err := strictPolicy().Check("johnny", user, history)
+	want := []string{RuleBreached, RuleDigit, RuleHistory, RuleMinLength, RulePersonalInfo, RuleStrength, RuleSymbol, RuleUpper}
+	if rules := violatedRules(t, err); strings.Join(rules, ",") != strings.Join(want, ",") {
+		t.Fatalf("got rules %v, want %v", rules, want)
+	}
+	//every failed rule is in the message too
+	if n := strings.Count(err.Error(), "Password"); n != len(want) {
+		t.Fatalf("%d messages in %q, want %d", n, err.Error(), len(want))
+	}
+}
+
+func TestCheckRules(t *testing.T) {
+	cases := []struct {
+		password string
+		want     []string
+	}{
+		{"Tr0ub4dor&3xylo", nil},
+		{"tr0ub4dor&3xylo", []string{RuleUpper}},
+		{"TR0UB4DOR&3XYLO", []string{RuleLower}},
+		{"Troubador&xylo!", []string{RuleDigit}},
+		{"Tr0ub4dor3xylo9", []string{RuleSymbol}},
+		{"Tr0ub4dor&3xylö", []string{RuleAllowedChars}},
+		{"Tr0ub4dor&3xylo-Tr0ub4dor&3xylo", []string{RuleMaxLength}},
+		{"John.Smith&2Tr0ub4", []string{RulePersonalInfo}},
+	}
+	user := &model.User{UserName: "johnny", Email: "john.smith@example.com"}
+	for _, c := range cases {
+		rules := violatedRules(t, strictPolicy().Check(c.password, user, nil))
+		if strings.Join(rules, ",") != strings.Join(c.want, ",") {
+			t.Errorf("%s: got rules %v, want %v", c.password, rules, c.want)
+		}
+	}
+}
+
+func TestCheckSkipsStrengthOfTooLongPassword(t *testing.T) {
+	p := strictPolicy()
+	password := "Aa1!" + strings.Repeat("a", 1000)
+	rules := violatedRules(t, p.Check(password, nil, nil))
+	if strings.Join(rules, ",") != RuleMaxLength {
+		t.Fatalf("got rules %v, want only %s", rules, RuleMaxLength)
+	}
+}
+
+func TestStrengthScore(t *testing.T) {
+	cases := []struct {
+		password string
+		personal []string
+		want     int
+	}{
+		{"password", nil, 0},
+		{"PassWord", nil, 0},
+		{"aaaaaa", nil, 0},
+		{"abcdef", nil, 0},
+		{"qwerty", nil, 0},
+		{"johnny", []string{"johnny"}, 0},
+		//repeats and sequences are cheap, random characters aren't
+		{"aaaaaaaaaaaa", nil, 1},
+		{"x7", nil, 1},
+		{"x7Kp", nil, 2},
+		{"x7Kp2m", nil, 4},
+		{"Tr0ub4dor&3xylo", nil, 4},
+		{"correcthorsebatterystaple", nil, 4},
+	}
+	for _, c := range cases {
+		if got := StrengthScore(c.password, c.personal); got != c.want {
+			t.Errorf("%s: score %d, want %d", c.password, got, c.want)
+		}
+	}
+	//personal info is as guessable as common passwords
+	if StrengthScore("johnny2021", []string{"johnny"}) >= StrengthScore("jqhxny2021", nil) {
+		t.Error("password with personal info isn't weaker")
+	}
+}
+
+func TestNewPasswordPolicy(t *testing.T) {
+	config := &cfg.Config{PasswordMinLength: 8, PasswordMaxLength: 64, PasswordMinStrength: 2, PasswordAllowedChars: `[\x20-\x7e]`}
+	p, err := NewPasswordPolicy(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.AllowedChars.MatchString("Tr0ub4dor&3") || p.AllowedChars.MatchString("Tr0ub4dor\n3") {
+		t.Fatal("allowed characters rule doesn't match whole password")
+	}
+	for _, invalid := range []cfg.Config{
+		{PasswordMinLength: 0, PasswordMaxLength: 64},
+		{PasswordMinLength: 8, PasswordMaxLength: 7},
+		{PasswordMinLength: 8, PasswordMaxLength: 64, PasswordMinStrength: 5},
+		{PasswordMinLength: 8, PasswordMaxLength: 64, PasswordHistorySize: -1},
+		{PasswordMinLength: 8, PasswordMaxLength: 64, PasswordAllowedChars: `[a-z`},
+	} {
+		config := invalid
+		if _, err = NewPasswordPolicy(&config); err == nil {
+			t.Errorf("invalid config %+v accepted", invalid)
+		}
+	}
+}
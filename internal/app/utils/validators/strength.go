@@ -0,0 +1,109 @@
+package validators
+
+import (
+	"math"
+	"strings"
+	"unicode"
+)
+
+const maxStrengthScore = 4
+
+//commonPasswords are the most used passwords and their parts, they are matched case-insensitively
+var commonPasswords = []string{
+	"password", "passw0rd", "123456", "qwerty", "letmein", "welcome", "admin", "iloveyou", "monkey",
+	"dragon", "football", "baseball", "master", "sunshine", "shadow", "princess", "login", "abc123",
+	"trustno1", "superman", "hello", "freedom", "whatever", "starwars", "secret", "summer", "winter",
+	"spring", "autumn", "love", "test", "user", "root", "pass",
+}
+
+//keyboardRows are used to find sequences of adjacent keys
+var keyboardRows = []string{"1234567890", "qwertyuiop", "asdfghjkl", "zxcvbnm"}
+
+//strengthThresholds are log10 of guesses which separate scores, they are the same as zxcvbn ones
+var strengthThresholds = []float64{3, 6, 8, 10}
+
+//StrengthScore estimates number of guesses like zxcvbn and returns score from 0 (too guessable) to 4.
+//Common passwords and personal info count as one dictionary guess, repeats and sequences are cheap.
+func StrengthScore(password string, personal []string) int {
+	lower := strings.ToLower(password)
+	runes := []rune(lower)
+	covered := make([]bool, len(runes))
+	dictionary := append(append([]string{}, commonPasswords...), personal...)
+	dictionaryGuesses := math.Log10(float64(len(dictionary)))
+	guesses := 0.0
+	for _, word := range dictionary {
+		if word == lower {
+			return 0
+		}
+		w := []rune(word)
+		for i := 0; i+len(w) <= len(runes); i++ {
+			if string(runes[i:i+len(w)]) != word || covered[i] {
+				continue
+			}
+			for j := i; j < i+len(w); j++ {
+				covered[j] = true
+			}
+			guesses += dictionaryGuesses
+		}
+	}
+	charGuesses := math.Log10(float64(cardinality(password)))
+	for i, r := range runes {
+		if covered[i] {
+			continue
+		}
+		if i > 0 && predictable(runes[i-1], r) {
+			guesses += math.Log10(2)
+			continue
+		}
+		guesses += charGuesses
+	}
+	score := 0
+	for _, threshold := range strengthThresholds {
+		if guesses >= threshold {
+			score++
+		}
+	}
+	return score
+}
+
+//cardinality returns size of alphabet of password character classes
+func cardinality(password string) int {
+	c := characterClasses(password)
+	size := 0
+	if c.lower {
+		size += 26
+	}
+	if c.upper {
+		size += 26
+	}
+	if c.digit {
+		size += 10
+	}
+	if c.symbol {
+		size += 33
+	}
+	if size == 0 {
+		size = 1
+	}
+	return size
+}
+
+//predictable reports whether r repeats prev, continues alphabet or digit sequence or is adjacent key
+func predictable(prev, r rune) bool {
+	if prev == r {
+		return true
+	}
+	if (unicode.IsLetter(prev) && unicode.IsLetter(r)) || (unicode.IsDigit(prev) && unicode.IsDigit(r)) {
+		if d := prev - r; d == 1 || d == -1 {
+			return true
+		}
+	}
+	for _, row := range keyboardRows {
+		i := strings.IndexRune(row, prev)
+		j := strings.IndexRune(row, r)
+		if i >= 0 && j >= 0 && (i-j == 1 || j-i == 1) {
+			return true
+		}
+	}
+	return false
+}
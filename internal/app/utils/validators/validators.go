@@ -1,6 +1,7 @@
 package validators
 
 import (
+	cfg "auth-server/internal/app/config"
 	"auth-server/internal/app/model"
 	"auth-server/internal/app/service"
 	"auth-server/internal/app/store"
@@ -16,8 +17,6 @@ const (
 	UsernameMaxLength      = 15
 	UniqueEmail            = true
 	UniqueUsername         = true
-	PassMinLength          = 8
-	PassRequiredDigits     = true
 )
 
 type (
@@ -25,15 +24,16 @@ type (
 		UserNameAllowedSymbols *regexp.Regexp
 		UsernameMinLength      int
 		UsernameMaxLength      int
-		PassAllowedSymbols     *regexp.Regexp
-		PassRequiredSymbols    *regexp.Regexp
 		UniqueEmail            bool
 		UniqueUsername         bool
-		PassMinLength          int
-		PassRequiredDigits     bool
+		Password               *PasswordPolicy
 	}
 	IUserValidator interface {
 		Validate(ctx context.Context, service service.UserFinder, user *model.User) error
+		//ValidatePassword checks new password of user, history may be nil if user has no passwords yet
+		ValidatePassword(password string, user *model.User, history PasswordHistory) error
+		//PasswordHistorySize returns number of last passwords which can't be reused
+		PasswordHistorySize() int
 	}
 )
 
@@ -43,8 +43,8 @@ type (
 	}
 )
 
-//New is constructor for UserValidator
-func NewUserValidator() (*UserValidator, error) {
+//New is constructor for UserValidator, password policy is loaded from config
+func NewUserValidator(config *cfg.Config) (*UserValidator, error) {
 	usernameAllowedSymbols, err := regexp.Compile(fmt.Sprintf(UsernameAllowedSymbols, UsernameMinLength, UsernameMaxLength))
 	if err != nil {
 		return nil, err
@@ -53,18 +53,18 @@ func NewUserValidator() (*UserValidator, error) {
 	//if err != nil {
 	//	return nil, err
 	//}
-	//passRequiredSymbols, err := regexp.Compile()
+	passwordPolicy, err := NewPasswordPolicy(config)
+	if err != nil {
+		return nil, err
+	}
 
 	params := userValidatorConfiguration{
 		UserNameAllowedSymbols: usernameAllowedSymbols,
 		UsernameMinLength:      UsernameMinLength,
 		UsernameMaxLength:      UsernameMaxLength,
-		PassAllowedSymbols:     nil,
-		PassRequiredSymbols:    nil,
 		UniqueEmail:            UniqueEmail,
 		UniqueUsername:         UniqueUsername,
-		PassMinLength:          PassMinLength,
-		PassRequiredDigits:     PassRequiredDigits,
+		Password:               passwordPolicy,
 	}
 
 	return &UserValidator{
@@ -98,8 +98,8 @@ func (u UserValidator) Validate(ctx context.Context, service service.UserFinder,
 			}
 		}
 	}
-	if len(user.Password) < u.params.PassMinLength {
-		return errors.ErrInvalidArgument.Newf("Error in validation. Password too short, min length is %d.", u.params.PassMinLength)
+	if err := u.ValidatePassword(user.Password, user, nil); err != nil {
+		return err
 	}
 	if len(user.UserName) < u.params.UsernameMinLength {
 		return errors.ErrInvalidArgument.Newf("Error in validation. Username too short, min length is %d", u.params.UsernameMinLength)
@@ -122,3 +122,12 @@ func (u UserValidator) Validate(ctx context.Context, service service.UserFinder,
 	}
 	return nil
 }
+
+//ValidatePassword checks only the password, it is used when user sets new password
+func (u UserValidator) ValidatePassword(password string, user *model.User, history PasswordHistory) error {
+	return u.params.Password.Check(password, user, history)
+}
+
+func (u UserValidator) PasswordHistorySize() int {
+	return u.params.Password.HistorySize
+}
@@ -0,0 +1,173 @@
+//Package breached checks passwords against a local dataset of breached SHA-1 hashes like Have I Been Pwned one.
+//Hashes are kept in a bloom filter, so check is offline and false negatives are impossible.
+package breached
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"io"
+	"math"
+	"os"
+)
+
+//filterMagic starts filter file, files without it are read as raw dataset
+var filterMagic = []byte("GBLOOM1\n")
+
+//DefaultFalsePositiveRate is a share of safe passwords which filter reports as breached
+const DefaultFalsePositiveRate = 0.001
+
+const maxHashes = 30
+
+//Filter is a bloom filter of SHA-1 hashes of passwords, hash is split to two 64-bit halves for double hashing
+type Filter struct {
+	bits   []byte
+	m      uint64
+	k      uint32
+	hashes uint64
+}
+
+//NewFilter creates filter of n hashes with false positive rate p
+func NewFilter(n uint64, p float64) *Filter {
+	if n == 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = DefaultFalsePositiveRate
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	//Size is rounded to bytes of the bit array
+	m = (m + 7) / 8 * 8
+	k := uint32(math.Round(float64(m) / float64(n) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	if k > maxHashes {
+		k = maxHashes
+	}
+	return &Filter{
+		bits: make([]byte, m/8),
+		m:    m,
+		k:    k,
+	}
+}
+
+func (f *Filter) positions(sum [sha1.Size]byte, visit func(bit uint64) bool) bool {
+	h1 := binary.BigEndian.Uint64(sum[0:8])
+	h2 := binary.BigEndian.Uint64(sum[8:16]) | 1
+	for i := uint64(0); i < uint64(f.k); i++ {
+		if !visit((h1 + i*h2) % f.m) {
+			return false
+		}
+	}
+	return true
+}
+
+//Add adds SHA-1 hash of password
+func (f *Filter) Add(sum [sha1.Size]byte) {
+	f.positions(sum, func(bit uint64) bool {
+		f.bits[bit/8] |= 1 << (bit % 8)
+		return true
+	})
+	f.hashes++
+}
+
+//ContainsHash reports whether hash may be in dataset
+func (f *Filter) ContainsHash(sum [sha1.Size]byte) bool {
+	return f.positions(sum, func(bit uint64) bool {
+		return f.bits[bit/8]&(1<<(bit%8)) != 0
+	})
+}
+
+//Contains reports whether password may be breached
+func (f *Filter) Contains(password string) bool {
+	return f.ContainsHash(sha1.Sum([]byte(password)))
+}
+
+//Len returns number of added hashes
+func (f *Filter) Len() uint64 {
+	return f.hashes
+}
+
+//WriteTo writes filter file: magic, k, m, number of hashes and bit array
+func (f *Filter) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, filterHeaderSize)
+	copy(header, filterMagic)
+	binary.LittleEndian.PutUint32(header[len(filterMagic):], f.k)
+	binary.LittleEndian.PutUint64(header[len(filterMagic)+4:], f.m)
+	binary.LittleEndian.PutUint64(header[len(filterMagic)+12:], f.hashes)
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), errors.NoType.Wrap(err, "Err write filter.")
+	}
+	written, err := w.Write(f.bits)
+	if err != nil {
+		return int64(n + written), errors.NoType.Wrap(err, "Err write filter.")
+	}
+	return int64(n + written), nil
+}
+
+//filterHeaderSize is a size of magic, k, m and number of hashes
+var filterHeaderSize = len(filterMagic) + 4 + 8 + 8
+
+//ReadFilter reads filter file written by WriteTo. Bit array is read as it comes,
+//so header of truncated stream can't make reader allocate more than the stream has.
+func ReadFilter(r io.Reader) (*Filter, error) {
+	return readFilter(r, -1)
+}
+
+//readFilter reads filter, size is a size of filter file or -1 if it isn't known
+func readFilter(r io.Reader, size int64) (*Filter, error) {
+	header := make([]byte, filterHeaderSize)
+	if _, err := io.ReadFull(r, header); err != nil || !bytes.Equal(header[:len(filterMagic)], filterMagic) {
+		return nil, errors.ErrInvalidArgument.New("Invalid breached passwords filter.")
+	}
+	f := &Filter{
+		k:      binary.LittleEndian.Uint32(header[len(filterMagic):]),
+		m:      binary.LittleEndian.Uint64(header[len(filterMagic)+4:]),
+		hashes: binary.LittleEndian.Uint64(header[len(filterMagic)+12:]),
+	}
+	if f.k < 1 || f.k > maxHashes || f.m == 0 || f.m%8 != 0 {
+		return nil, errors.ErrInvalidArgument.New("Invalid breached passwords filter.")
+	}
+	if size >= 0 {
+		//size is checked before allocation of bit array which header declares
+		if f.m/8 != uint64(size)-uint64(filterHeaderSize) {
+			return nil, errors.ErrInvalidArgument.New("Size of breached passwords filter doesn't match its header.")
+		}
+		f.bits = make([]byte, f.m/8)
+		if _, err := io.ReadFull(r, f.bits); err != nil {
+			return nil, errors.ErrInvalidArgument.Wrap(err, "Breached passwords filter is truncated.")
+		}
+		return f, nil
+	}
+	bits := &bytes.Buffer{}
+	if n, err := io.CopyN(bits, r, int64(f.m/8)); err != nil || uint64(n) != f.m/8 {
+		return nil, errors.ErrInvalidArgument.New("Breached passwords filter is truncated.")
+	}
+	f.bits = bits.Bytes()
+	return f, nil
+}
+
+//Open loads filter file or builds filter from raw dataset if path isn't a filter file
+func Open(path string) (*Filter, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.Wrapf(err, "Err open breached passwords %s.", path)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "")
+	}
+	if !info.IsDir() {
+		reader := bufio.NewReader(file)
+		magic, err := reader.Peek(len(filterMagic))
+		if err == nil && bytes.Equal(magic, filterMagic) {
+			return readFilter(reader, info.Size())
+		}
+	}
+	return Build(path, DefaultFalsePositiveRate, 1)
+}
@@ -0,0 +1,128 @@
+package breached
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestFilterAddContains(t *testing.T) {
+	f := NewFilter(1000, 0.01)
+	for i := 0; i < 1000; i++ {
+		f.Add(sha1.Sum([]byte(fmt.Sprintf("breached-%d", i))))
+	}
+	if f.Len() != 1000 {
+		t.Fatalf("filter has %d hashes, want 1000", f.Len())
+	}
+	for i := 0; i < 1000; i++ {
+		if !f.Contains(fmt.Sprintf("breached-%d", i)) {
+			t.Fatalf("added password %d isn't found", i)
+		}
+	}
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if f.ContainsHash(sha1.Sum([]byte(fmt.Sprintf("safe-%d", i)))) {
+			falsePositives++
+		}
+	}
+	//expected 100 of 10000 at 1% rate
+	if falsePositives > 300 {
+		t.Fatalf("%d false positives of 10000", falsePositives)
+	}
+}
+
+func TestFilterRoundTrip(t *testing.T) {
+	f := NewFilter(100, 0.001)
+	f.Add(sha1.Sum([]byte("password")))
+	buf := &bytes.Buffer{}
+	n, err := f.WriteTo(buf)
+	if err != nil || n != int64(buf.Len()) {
+		t.Fatalf("written %d of %d bytes, err %v", n, buf.Len(), err)
+	}
+	read, err := ReadFilter(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if read.m != f.m || read.k != f.k || read.Len() != 1 || !bytes.Equal(read.bits, f.bits) {
+		t.Fatal("read filter differs from written one")
+	}
+	if !read.Contains("password") {
+		t.Fatal("password isn't found in read filter")
+	}
+
+	path := filepath.Join(t.TempDir(), "filter.bin")
+	if err = ioutil.WriteFile(path, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	opened, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !opened.Contains("password") || opened.Len() != 1 {
+		t.Fatal("opened filter differs from written one")
+	}
+}
+
+func TestReadInvalidFilter(t *testing.T) {
+	f := NewFilter(100, 0.001)
+	buf := &bytes.Buffer{}
+	f.WriteTo(buf)
+	valid := buf.Bytes()
+	withHeader := func(k uint32, m uint64) []byte {
+		data := append([]byte{}, valid...)
+		binary.LittleEndian.PutUint32(data[len(filterMagic):], k)
+		binary.LittleEndian.PutUint64(data[len(filterMagic)+4:], m)
+		return data
+	}
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"other magic", append([]byte("GBLOOM2\n"), valid[len(filterMagic):]...)},
+		{"truncated header", valid[:filterHeaderSize-1]},
+		{"truncated bits", valid[:len(valid)-1]},
+		{"zero hashes", withHeader(0, f.m)},
+		{"too many hashes", withHeader(maxHashes+1, f.m)},
+		{"zero size", withHeader(f.k, 0)},
+		{"size not in bytes", withHeader(f.k, f.m+1)},
+		{"huge size", withHeader(f.k, 1<<62)},
+	}
+	for _, c := range cases {
+		if _, err := ReadFilter(bytes.NewReader(c.data)); err == nil {
+			t.Errorf("%s: filter read", c.name)
+		}
+	}
+}
+
+func TestOpenChecksSize(t *testing.T) {
+	f := NewFilter(100, 0.001)
+	buf := &bytes.Buffer{}
+	f.WriteTo(buf)
+	dir := t.TempDir()
+	for name, data := range map[string][]byte{
+		//header declares 1 TiB bit array
+		"huge": func() []byte {
+			data := append([]byte{}, buf.Bytes()...)
+			binary.LittleEndian.PutUint64(data[len(filterMagic)+4:], 8<<40)
+			return data
+		}(),
+		"truncated": buf.Bytes()[:buf.Len()-1],
+		"trailing":  append(append([]byte{}, buf.Bytes()...), 0),
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Open(path); err == nil {
+			t.Errorf("%s filter opened", name)
+		}
+	}
+	if _, err := Open(filepath.Join(dir, "missing")); err == nil {
+		t.Error("missing file opened")
+	}
+}
@@ -0,0 +1,100 @@
+package breached
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+//prefixLength is a length of hash prefix of k-anonymity range files
+const prefixLength = 5
+
+//Build creates filter from dataset. Dataset is a file of "SHA1:COUNT" lines or a directory
+//of k-anonymity range files which are named by 5 hex prefix and have "SUFFIX:COUNT" lines.
+//Hashes seen less than minCount times are skipped, it makes filter smaller.
+func Build(path string, falsePositiveRate float64, minCount int) (*Filter, error) {
+	var n uint64
+	err := readDataset(path, minCount, func(sum [sha1.Size]byte) {
+		n++
+	})
+	if err != nil {
+		return nil, err
+	}
+	filter := NewFilter(n, falsePositiveRate)
+	err = readDataset(path, minCount, filter.Add)
+	if err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func readDataset(path string, minCount int, visit func(sum [sha1.Size]byte)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.ErrInvalidArgument.Wrapf(err, "Err open breached passwords %s.", path)
+	}
+	if !info.IsDir() {
+		return readHashes(path, "", minCount, visit)
+	}
+	files, err := ioutil.ReadDir(path)
+	if err != nil {
+		return errors.NoType.Wrapf(err, "Err read directory %s.", path)
+	}
+	for _, file := range files {
+		prefix := strings.ToUpper(strings.TrimSuffix(file.Name(), filepath.Ext(file.Name())))
+		if file.IsDir() || len(prefix) != prefixLength {
+			continue
+		}
+		if _, err := hex.DecodeString(prefix + "0"); err != nil {
+			continue
+		}
+		err = readHashes(filepath.Join(path, file.Name()), prefix, minCount, visit)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//readHashes reads "HASH:COUNT" lines, prefix is prepended to hash of range file
+func readHashes(path, prefix string, minCount int, visit func(sum [sha1.Size]byte)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return errors.ErrInvalidArgument.Wrapf(err, "Err open breached passwords %s.", path)
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 {
+			continue
+		}
+		hash, count := text, 1
+		if colon := strings.Index(text, ":"); colon >= 0 {
+			hash = text[:colon]
+			if count, err = strconv.Atoi(text[colon+1:]); err != nil {
+				return errors.ErrInvalidArgument.Newf("Invalid count in %s line %d.", path, line)
+			}
+		}
+		if count < minCount {
+			continue
+		}
+		var sum [sha1.Size]byte
+		decoded, err := hex.DecodeString(prefix + hash)
+		if err != nil || len(decoded) != sha1.Size {
+			return errors.ErrInvalidArgument.Newf("Invalid SHA-1 hash in %s line %d.", path, line)
+		}
+		copy(sum[:], decoded)
+		visit(sum)
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.NoType.Wrapf(err, "Err read %s.", path)
+	}
+	return nil
+}
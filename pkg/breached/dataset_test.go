@@ -0,0 +1,95 @@
+package breached
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func hashHex(password string) string {
+	sum := sha1.Sum([]byte(password))
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func TestBuildFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	data := fmt.Sprintf("%s:120\n\n%s:3\n%s\n", hashHex("password"), hashHex("rare"), strings.ToLower(hashHex("nocount")))
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 3 {
+		t.Fatalf("filter has %d hashes, want 3", f.Len())
+	}
+	for _, password := range []string{"password", "rare", "nocount"} {
+		if !f.Contains(password) {
+			t.Errorf("%s isn't found", password)
+		}
+	}
+}
+
+func TestBuildFromRangeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, password := range []string{"password", "letmein"} {
+		hash := hashHex(password)
+		write(hash[:prefixLength]+".txt", hash[prefixLength:]+":10\n")
+	}
+	//files which aren't range files are skipped
+	write("README.md", "not a range file")
+	write("XYZ12.txt", "not a hex prefix")
+	f, err := Build(dir, 0.001, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Len() != 2 || !f.Contains("password") || !f.Contains("letmein") {
+		t.Fatalf("filter of %d hashes doesn't contain dataset", f.Len())
+	}
+}
+
+func TestBuildMinCount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pwned.txt")
+	data := fmt.Sprintf("%s:120\n%s:3\n%s\n", hashHex("password"), hashHex("rare"), hashHex("nocount"))
+	if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f, err := Build(path, 0.001, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//line without count is seen once
+	if f.Len() != 1 || !f.Contains("password") {
+		t.Fatalf("filter has %d hashes, want only frequent one", f.Len())
+	}
+}
+
+func TestBuildInvalidDataset(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{
+		"count":  hashHex("password") + ":many\n",
+		"hash":   "XYZ:10\n",
+		"length": hashHex("password")[:38] + ":10\n",
+	} {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := Build(path, 0.001, 1); err == nil {
+			t.Errorf("dataset with invalid %s accepted", name)
+		}
+	}
+	if _, err := Build(filepath.Join(dir, "missing"), 0.001, 1); err == nil {
+		t.Error("missing dataset accepted")
+	}
+}
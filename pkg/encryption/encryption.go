@@ -0,0 +1,73 @@
+//Package encryption encrypts secrets which are kept at rest by AES-256-GCM.
+//Ciphertext is stored as "enc:v1:" followed by base64 of nonce and sealed data.
+package encryption
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+)
+
+const (
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+type Cipher struct {
+	aead cipher.AEAD
+}
+
+//New creates cipher by base64 encoded 32 bytes key
+func New(key string) (*Cipher, error) {
+	if len(key) == 0 {
+		return nil, errors.ErrInvalidArgument.New("Encryption key not be null.")
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != keySize {
+		return nil, errors.ErrInvalidArgument.Newf("Encryption key must be base64 of %d bytes.", keySize)
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "Err create cipher.")
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.NoType.Wrap(err, "Err create cipher.")
+	}
+	return &Cipher{aead: aead}, nil
+}
+
+//Encrypt seals plaintext, associated data binds ciphertext to its owner (e.g. record ID)
+func (c *Cipher) Encrypt(plaintext, associated []byte) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate nonce.")
+	}
+	sealed := c.aead.Seal(nonce, nonce, plaintext, associated)
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+//Decrypt opens ciphertext produced by Encrypt with the same associated data
+func (c *Cipher) Decrypt(encoded string, associated []byte) ([]byte, error) {
+	if !IsEncrypted(encoded) {
+		return nil, errors.ErrInvalidArgument.New("Value is not encrypted.")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded[len(prefix):])
+	if err != nil || len(sealed) < c.aead.NonceSize() {
+		return nil, errors.ErrInvalidArgument.New("Invalid encrypted value.")
+	}
+	nonce, sealed := sealed[:c.aead.NonceSize()], sealed[c.aead.NonceSize():]
+	plaintext, err := c.aead.Open(nil, nonce, sealed, associated)
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid encrypted value.")
+	}
+	return plaintext, nil
+}
+
+//IsEncrypted reports whether value was produced by Encrypt, so values stored before encryption can be read
+func IsEncrypted(encoded string) bool {
+	return strings.HasPrefix(encoded, prefix)
+}
@@ -19,6 +19,9 @@ var (
 		types.ErrDuplicateEntry:            5,
 		types.ErrInvalidPassword:           http.StatusUnauthorized,
 		types.ErrInvalidPasswordOrUsername: http.StatusUnauthorized,
+		types.ErrUnauthorized:              http.StatusUnauthorized,
+		types.ErrForbidden:                 http.StatusForbidden,
+		types.ErrTooManyRequests:           http.StatusTooManyRequests,
 	}
 )
 
@@ -42,6 +45,15 @@ func New(err error) (*HTTPError, int) {
 	case types.ErrDuplicateEntry:
 		msg = err.Error()
 		httpCode = http.StatusConflict
+	case types.ErrUnauthorized:
+		msg = err.Error()
+		httpCode = http.StatusUnauthorized
+	case types.ErrForbidden:
+		msg = err.Error()
+		httpCode = http.StatusForbidden
+	case types.ErrTooManyRequests:
+		msg = err.Error()
+		httpCode = http.StatusTooManyRequests
 	default:
 		msg = err.Error()
 	}
@@ -49,6 +61,6 @@ func New(err error) (*HTTPError, int) {
 	return &HTTPError{
 		Code:    codes[errtype],
 		Message: msg,
-		Params:  nil,
+		Params:  types.GetErrorParams(err),
 	}, httpCode
 }
@@ -12,6 +12,9 @@ const (
 	ErrInvalidPassword
 	ErrDatabaseDown
 	ErrDuplicateEntry
+	ErrUnauthorized
+	ErrForbidden
+	ErrTooManyRequests
 )
 
 type ErrorType uint
@@ -22,12 +25,16 @@ var messages = map[ErrorType]string{
 	ErrInvalidPasswordOrUsername: "Invalid password or username. Check and try again. ",
 	ErrDatabaseDown:              "Database down. ",
 	ErrDuplicateEntry:            "Duplicate entry. ",
+	ErrUnauthorized:              "Unauthorized. ",
+	ErrForbidden:                 "Forbidden. ",
+	ErrTooManyRequests:           "Too many requests. ",
 }
 
 type customError struct {
 	errorType     ErrorType
 	standartError error
 	contextInfo   errorContext
+	params        *errorParams
 }
 
 type errorContext struct {
@@ -35,6 +42,11 @@ type errorContext struct {
 	Message string
 }
 
+//errorParams is kept by pointer, so customError stays comparable
+type errorParams struct {
+	values map[string]string
+}
+
 func (err customError) Error() string {
 	return err.standartError.Error()
 }
@@ -73,6 +85,7 @@ func Wrapf(err error, msg string, args ...interface{}) error {
 			errorType:     customErr.errorType,
 			standartError: wrappedError,
 			contextInfo:   customErr.contextInfo,
+			params:        customErr.params,
 		}
 	}
 	return customError{
@@ -92,6 +105,7 @@ func AddErrorContext(err error, field, message string) error {
 			errorType:     customErr.errorType,
 			standartError: customErr.standartError,
 			contextInfo:   context,
+			params:        customErr.params,
 		}
 	}
 	return customError{
@@ -113,6 +127,27 @@ func GetErrorContext(err error) map[string]string {
 	return nil
 }
 
+//AddErrorParams adds params which are reported to client, e.g. all failed validation rules
+func AddErrorParams(err error, params map[string]string) error {
+	if customErr, ok := err.(customError); ok {
+		customErr.params = &errorParams{values: params}
+		return customErr
+	}
+	return customError{
+		errorType:     NoType,
+		standartError: err,
+		params:        &errorParams{values: params},
+	}
+}
+
+//GetErrorParams returns the error params
+func GetErrorParams(err error) map[string]string {
+	if customErr, ok := err.(customError); ok && customErr.params != nil {
+		return customErr.params.values
+	}
+	return nil
+}
+
 //GetType returns the error type
 func GetType(err error) ErrorType {
 	if customErr, ok := err.(customError); ok {
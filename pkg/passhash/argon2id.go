@@ -0,0 +1,126 @@
+package passhash
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+//Default parameters of Argon2id, they follow OWASP recommendations
+const (
+	DefaultArgon2Memory      = 64 * 1024
+	DefaultArgon2Iterations  = 3
+	DefaultArgon2Parallelism = 2
+	argon2SaltLength         = 16
+	argon2KeyLength          = 32
+)
+
+//Upper bounds of Argon2id parameters. Stored hashes are decoded before key derivation,
+//so hash with huge parameters can't make login take all memory and CPU.
+const (
+	MaxArgon2Memory      = 1024 * 1024
+	MaxArgon2Iterations  = 10
+	MaxArgon2Parallelism = 16
+	argon2MaxSaltLength  = 64
+	argon2MaxKeyLength   = 64
+)
+
+//Argon2id is a hasher of RFC 9106 Argon2id, memory is set in KiB
+type Argon2id struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+}
+
+//argon2Hash is a decoded PHC string "$argon2id$v=19$m=65536,t=3,p=2$salt$hash"
+type argon2Hash struct {
+	params Argon2id
+	salt   []byte
+	key    []byte
+}
+
+func NewArgon2id(memory, iterations uint32, parallelism uint8) *Argon2id {
+	if memory == 0 {
+		memory = DefaultArgon2Memory
+	}
+	if iterations == 0 {
+		iterations = DefaultArgon2Iterations
+	}
+	if parallelism == 0 {
+		parallelism = DefaultArgon2Parallelism
+	}
+	return &Argon2id{
+		Memory:      memory,
+		Iterations:  iterations,
+		Parallelism: parallelism,
+	}
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate salt.")
+	}
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, argon2KeyLength)
+	return fmt.Sprintf("$%s$v=%d$m=%d,t=%d,p=%d$%s$%s", AlgArgon2id, argon2.Version,
+		a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (a *Argon2id) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$"+AlgArgon2id+"$")
+}
+
+func (a *Argon2id) Verify(encoded, password string) (bool, error) {
+	hash, err := decodeArgon2(encoded)
+	if err != nil {
+		return false, err
+	}
+	key := argon2.IDKey([]byte(password), hash.salt, hash.params.Iterations, hash.params.Memory,
+		hash.params.Parallelism, uint32(len(hash.key)))
+	return subtle.ConstantTimeCompare(key, hash.key) == 1, nil
+}
+
+func (a *Argon2id) NeedsRehash(encoded string) bool {
+	hash, err := decodeArgon2(encoded)
+	if err != nil {
+		return true
+	}
+	return hash.params.Memory < a.Memory || hash.params.Iterations < a.Iterations ||
+		hash.params.Parallelism != a.Parallelism || len(hash.key) < argon2KeyLength
+}
+
+func decodeArgon2(encoded string) (*argon2Hash, error) {
+	invalid := errors.ErrInvalidArgument.New("Invalid argon2id hash.")
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgArgon2id {
+		return nil, invalid
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, invalid
+	}
+	hash := &argon2Hash{}
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &hash.params.Memory, &hash.params.Iterations, &hash.params.Parallelism)
+	if err != nil || hash.params.Memory == 0 || hash.params.Iterations == 0 || hash.params.Parallelism == 0 {
+		return nil, invalid
+	}
+	if hash.params.Memory > MaxArgon2Memory || hash.params.Iterations > MaxArgon2Iterations ||
+		hash.params.Parallelism > MaxArgon2Parallelism {
+		return nil, invalid
+	}
+	hash.salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil || len(hash.salt) == 0 || len(hash.salt) > argon2MaxSaltLength {
+		return nil, invalid
+	}
+	hash.key, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(hash.key) == 0 || len(hash.key) > argon2MaxKeyLength {
+		return nil, invalid
+	}
+	return hash, nil
+}
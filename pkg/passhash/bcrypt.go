@@ -0,0 +1,50 @@
+package passhash
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//Bcrypt is a hasher of legacy bcrypt hashes
+type Bcrypt struct {
+	Cost int
+}
+
+func NewBcrypt(cost int) *Bcrypt {
+	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
+		cost = bcrypt.DefaultCost
+	}
+	return &Bcrypt{Cost: cost}
+}
+
+func (b *Bcrypt) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), b.Cost)
+	if err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate hash password.")
+	}
+	return string(hash), nil
+}
+
+func (b *Bcrypt) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") || strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (b *Bcrypt) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	switch err {
+	case nil:
+		return true, nil
+	case bcrypt.ErrMismatchedHashAndPassword:
+		return false, nil
+	default:
+		return false, errors.ErrInvalidArgument.Wrap(err, "Invalid bcrypt hash.")
+	}
+}
+
+func (b *Bcrypt) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < b.Cost
+}
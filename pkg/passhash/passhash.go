@@ -0,0 +1,73 @@
+//Package passhash hashes passwords by preferred algorithm and verifies hashes of legacy ones.
+//Hashes are stored as PHC strings, bcrypt hashes keep their own modular crypt format.
+package passhash
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+)
+
+//Names of algorithms
+const (
+	AlgArgon2id = "argon2id"
+	AlgBcrypt   = "bcrypt"
+)
+
+//Hasher hashes passwords by one algorithm
+type Hasher interface {
+	Hash(password string) (string, error)
+	//Identify reports whether encoded hash was produced by the algorithm
+	Identify(encoded string) bool
+	//Verify returns false if password doesn't match the hash
+	Verify(encoded, password string) (bool, error)
+	//NeedsRehash reports whether encoded hash uses weaker parameters than current ones
+	NeedsRehash(encoded string) bool
+}
+
+//Passwords hashes new passwords by preferred hasher, other hashers only verify old hashes
+type Passwords struct {
+	preferred Hasher
+	hashers   []Hasher
+	//dummyHash is a hash of random password which is verified instead of missing hash
+	dummyHash string
+	dummyOnce sync.Once
+}
+
+func New(preferred Hasher, legacy ...Hasher) *Passwords {
+	return &Passwords{
+		preferred: preferred,
+		hashers:   append([]Hasher{preferred}, legacy...),
+	}
+}
+
+func (p *Passwords) Hash(password string) (string, error) {
+	return p.preferred.Hash(password)
+}
+
+//Verify checks password, rehash is true if hash is valid but must be replaced by hash of preferred hasher
+func (p *Passwords) Verify(encoded, password string) (ok, rehash bool, err error) {
+	for _, hasher := range p.hashers {
+		if !hasher.Identify(encoded) {
+			continue
+		}
+		ok, err = hasher.Verify(encoded, password)
+		if err != nil || !ok {
+			return false, false, err
+		}
+		return true, hasher != p.preferred || hasher.NeedsRehash(encoded), nil
+	}
+	return false, false, errors.ErrInvalidArgument.New("Unknown password hash format.")
+}
+
+//VerifyDummy takes as long as Verify of password by preferred hasher and always fails,
+//so rejection of unknown or throttled user isn't faster than rejection of wrong password
+func (p *Passwords) VerifyDummy(password string) {
+	p.dummyOnce.Do(func() {
+		random := make([]byte, 16)
+		rand.Read(random)
+		p.dummyHash, _ = p.preferred.Hash(hex.EncodeToString(random))
+	})
+	p.Verify(p.dummyHash, password)
+}
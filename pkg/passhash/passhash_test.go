@@ -0,0 +1,133 @@
+package passhash
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+//Tests use cheap parameters, hashes of defaults take too long
+func testArgon2id() *Argon2id {
+	return NewArgon2id(1024, 1, 1)
+}
+
+func TestArgon2idRoundTrip(t *testing.T) {
+	a := testArgon2id()
+	encoded, err := a.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=1024,t=1,p=1$") {
+		t.Fatalf("unexpected PHC string %s", encoded)
+	}
+	hash, err := decodeArgon2(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hash.salt) != argon2SaltLength || len(hash.key) != argon2KeyLength || hash.params != *a {
+		t.Fatalf("unexpected decoded hash %+v", hash)
+	}
+	if ok, err := a.Verify(encoded, "correct horse"); !ok || err != nil {
+		t.Fatalf("valid password rejected, err %v", err)
+	}
+	if ok, err := a.Verify(encoded, "wrong horse"); ok || err != nil {
+		t.Fatalf("wrong password: ok %v, err %v", ok, err)
+	}
+	other, _ := a.Hash("correct horse")
+	if other == encoded {
+		t.Fatal("hashes of the same password are equal, salt isn't random")
+	}
+}
+
+func TestBcryptRehashedToArgon2id(t *testing.T) {
+	legacy := NewBcrypt(bcrypt.MinCost)
+	passwords := New(testArgon2id(), legacy)
+	encoded, err := legacy.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, rehash, err := passwords.Verify(encoded, "correct horse")
+	if !ok || !rehash || err != nil {
+		t.Fatalf("bcrypt hash: ok %v, rehash %v, err %v", ok, rehash, err)
+	}
+	if ok, rehash, _ = passwords.Verify(encoded, "wrong horse"); ok || rehash {
+		t.Fatalf("wrong password: ok %v, rehash %v", ok, rehash)
+	}
+	encoded, err = passwords.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !testArgon2id().Identify(encoded) {
+		t.Fatalf("new hash %s isn't argon2id", encoded)
+	}
+	ok, rehash, err = passwords.Verify(encoded, "correct horse")
+	if !ok || rehash || err != nil {
+		t.Fatalf("argon2id hash: ok %v, rehash %v, err %v", ok, rehash, err)
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	encoded, _ := testArgon2id().Hash("correct horse")
+	cases := []struct {
+		name   string
+		hasher *Argon2id
+		want   bool
+	}{
+		{"same parameters", NewArgon2id(1024, 1, 1), false},
+		{"weaker parameters", NewArgon2id(512, 1, 1), false},
+		{"more memory", NewArgon2id(2048, 1, 1), true},
+		{"more iterations", NewArgon2id(1024, 2, 1), true},
+		{"other parallelism", NewArgon2id(1024, 1, 2), true},
+	}
+	for _, c := range cases {
+		if got := c.hasher.NeedsRehash(encoded); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.name, got, c.want)
+		}
+	}
+	if !testArgon2id().NeedsRehash("$argon2id$invalid") {
+		t.Error("invalid hash doesn't need rehash")
+	}
+
+	encoded, _ = NewBcrypt(bcrypt.MinCost).Hash("correct horse")
+	if NewBcrypt(bcrypt.MinCost).NeedsRehash(encoded) {
+		t.Error("bcrypt hash of the same cost needs rehash")
+	}
+	if !NewBcrypt(bcrypt.MinCost + 1).NeedsRehash(encoded) {
+		t.Error("bcrypt hash of lower cost doesn't need rehash")
+	}
+}
+
+func TestMalformedHashes(t *testing.T) {
+	const salt, key = "c2FsdHNhbHRzYWx0c2FsdA", "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5"
+	cases := []struct {
+		name    string
+		encoded string
+	}{
+		{"missing parts", "$argon2id$v=19$m=1024,t=1,p=1$" + salt},
+		{"other variant", "$argon2i$v=19$m=1024,t=1,p=1$" + salt + "$" + key},
+		{"old version", "$argon2id$v=16$m=1024,t=1,p=1$" + salt + "$" + key},
+		{"invalid parameters", "$argon2id$v=19$m=x,t=1,p=1$" + salt + "$" + key},
+		{"zero memory", "$argon2id$v=19$m=0,t=1,p=1$" + salt + "$" + key},
+		{"too much memory", "$argon2id$v=19$m=1048577,t=1,p=1$" + salt + "$" + key},
+		{"too many iterations", "$argon2id$v=19$m=1024,t=11,p=1$" + salt + "$" + key},
+		{"too much parallelism", "$argon2id$v=19$m=1024,t=1,p=17$" + salt + "$" + key},
+		{"invalid salt", "$argon2id$v=19$m=1024,t=1,p=1$!!!$" + key},
+		{"empty salt", "$argon2id$v=19$m=1024,t=1,p=1$$" + key},
+		{"empty key", "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$"},
+		{"too long key", "$argon2id$v=19$m=1024,t=1,p=1$" + salt + "$" + strings.Repeat(key, 3)},
+	}
+	a := testArgon2id()
+	for _, c := range cases {
+		if ok, err := a.Verify(c.encoded, "correct horse"); ok || err == nil {
+			t.Errorf("%s: ok %v, err %v", c.name, ok, err)
+		}
+	}
+	if ok, err := NewBcrypt(bcrypt.MinCost).Verify("$2a$04$short", "correct horse"); ok || err == nil {
+		t.Errorf("truncated bcrypt hash: ok %v, err %v", ok, err)
+	}
+	passwords := New(a, NewBcrypt(bcrypt.MinCost))
+	if ok, _, err := passwords.Verify("plaintext", "plaintext"); ok || err == nil {
+		t.Errorf("unknown format: ok %v, err %v", ok, err)
+	}
+}
@@ -0,0 +1,158 @@
+package webauthn
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"encoding/binary"
+	"math"
+)
+
+//cborMaxDepth limits nesting of decoded items, WebAuthn structures are shallow
+const cborMaxDepth = 16
+
+//cborDecoder decodes the CBOR subset used by WebAuthn, RFC 8949.
+//Indefinite lengths and floats aren't supported, tags are skipped.
+type cborDecoder struct {
+	data []byte
+	pos  int
+	//info is additional information of the last item head
+	info byte
+}
+
+//decodeCBOR decodes the first item of data and returns the number of bytes it takes
+func decodeCBOR(data []byte) (interface{}, int, error) {
+	d := &cborDecoder{data: data}
+	v, err := d.decode(0)
+	if err != nil {
+		return nil, 0, err
+	}
+	return v, d.pos, nil
+}
+
+func (d *cborDecoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, errors.ErrInvalidArgument.New("Unexpected end of CBOR data.")
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+//head returns major type and argument of the item
+func (d *cborDecoder) head() (byte, uint64, error) {
+	b, err := d.readBytes(1)
+	if err != nil {
+		return 0, 0, err
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	d.info = info
+	switch {
+	case info < 24:
+		return major, uint64(info), nil
+	case info == 24:
+		b, err = d.readBytes(1)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(b[0]), nil
+	case info == 25:
+		b, err = d.readBytes(2)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err = d.readBytes(4)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err = d.readBytes(8)
+		if err != nil {
+			return 0, 0, err
+		}
+		return major, binary.BigEndian.Uint64(b), nil
+	}
+	return 0, 0, errors.ErrInvalidArgument.New("Unsupported CBOR item.")
+}
+
+func (d *cborDecoder) decode(depth int) (interface{}, error) {
+	if depth > cborMaxDepth {
+		return nil, errors.ErrInvalidArgument.New("CBOR data is nested too deep.")
+	}
+	major, arg, err := d.head()
+	if err != nil {
+		return nil, err
+	}
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, errors.ErrInvalidArgument.New("CBOR integer overflow.")
+		}
+		return int64(arg), nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, errors.ErrInvalidArgument.New("CBOR integer overflow.")
+		}
+		return -1 - int64(arg), nil
+	case 2:
+		return d.readBytes(arg)
+	case 3:
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case 4:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.ErrInvalidArgument.New("Unexpected end of CBOR data.")
+		}
+		items := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, v)
+		}
+		return items, nil
+	case 5:
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, errors.ErrInvalidArgument.New("Unexpected end of CBOR data.")
+		}
+		items := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, errors.ErrInvalidArgument.New("Unsupported CBOR map key.")
+			}
+			v, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			items[k] = v
+		}
+		return items, nil
+	case 6:
+		return d.decode(depth + 1)
+	default:
+		//Floats have the same major type as simple values
+		if d.info > 24 {
+			return nil, errors.ErrInvalidArgument.New("Unsupported CBOR item.")
+		}
+		switch arg {
+		case 20:
+			return false, nil
+		case 21:
+			return true, nil
+		case 22, 23:
+			return nil, nil
+		}
+		return nil, errors.ErrInvalidArgument.New("Unsupported CBOR item.")
+	}
+}
@@ -0,0 +1,121 @@
+package webauthn
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+)
+
+//COSE algorithms supported for credentials, RFC 8152 and RFC 8812
+const (
+	AlgES256 = -7
+	AlgEdDSA = -8
+	AlgRS256 = -257
+)
+
+//COSE key parameters, RFC 8152 section 7
+const (
+	coseKeyType    = 1
+	coseKeyAlg     = 3
+	coseKeyCrv     = -1
+	coseKeyX       = -2
+	coseKeyY       = -3
+	coseKeyRSAN    = -1
+	coseKeyRSAE    = -2
+	coseKeyTypeOKP = 1
+	coseKeyTypeEC2 = 2
+	coseKeyTypeRSA = 3
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+//coseKey is a credential public key
+type coseKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+//parseCOSEKey decodes COSE_Key and checks that key and algorithm are supported
+func parseCOSEKey(data []byte) (*coseKey, int, error) {
+	v, n, err := decodeCBOR(data)
+	if err != nil {
+		return nil, 0, err
+	}
+	params, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.ErrInvalidArgument.New("Invalid credential public key.")
+	}
+	kty, _ := params[int64(coseKeyType)].(int64)
+	alg, _ := params[int64(coseKeyAlg)].(int64)
+	key := &coseKey{alg: alg}
+	switch {
+	case kty == coseKeyTypeEC2 && alg == AlgES256:
+		crv, _ := params[int64(coseKeyCrv)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		y, _ := params[int64(coseKeyY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.ErrInvalidArgument.New("Invalid EC2 credential public key.")
+		}
+		pub := &ecdsa.PublicKey{
+			Curve: elliptic.P256(),
+			X:     new(big.Int).SetBytes(x),
+			Y:     new(big.Int).SetBytes(y),
+		}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, 0, errors.ErrInvalidArgument.New("Invalid EC2 credential public key.")
+		}
+		key.key = pub
+	case kty == coseKeyTypeRSA && alg == AlgRS256:
+		n, _ := params[int64(coseKeyRSAN)].([]byte)
+		e, _ := params[int64(coseKeyRSAE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.ErrInvalidArgument.New("Invalid RSA credential public key.")
+		}
+		key.key = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	case kty == coseKeyTypeOKP && alg == AlgEdDSA:
+		crv, _ := params[int64(coseKeyCrv)].(int64)
+		x, _ := params[int64(coseKeyX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.ErrInvalidArgument.New("Invalid OKP credential public key.")
+		}
+		key.key = ed25519.PublicKey(x)
+	default:
+		return nil, 0, errors.ErrInvalidArgument.New("Unsupported credential public key algorithm.")
+	}
+	return key, n, nil
+}
+
+//verifySignature checks signature of data made by key with COSE algorithm alg
+func verifySignature(key crypto.PublicKey, alg int64, data, sig []byte) bool {
+	switch alg {
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return false
+		}
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return false
+		}
+		return ed25519.Verify(pub, data, sig)
+	}
+	return false
+}
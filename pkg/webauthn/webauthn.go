@@ -0,0 +1,394 @@
+//Package webauthn verifies passkey registrations and assertions, W3C Web Authentication Level 2.
+//Supported attestation formats are "none" and "packed", certificate chains aren't validated.
+package webauthn
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+)
+
+//Types of client data
+const (
+	TypeCreate = "webauthn.create"
+	TypeGet    = "webauthn.get"
+)
+
+//Attestation statement formats
+const (
+	AttestationNone   = "none"
+	AttestationPacked = "packed"
+)
+
+//User verification requirements
+const (
+	UserVerificationRequired  = "required"
+	UserVerificationPreferred = "preferred"
+)
+
+//Authenticator data flags
+const (
+	flagUserPresent      = 0x01
+	flagUserVerified     = 0x04
+	flagBackupEligible   = 0x08
+	flagAttestedCredData = 0x40
+)
+
+//challengeSize is a size of random challenge, at least 16 bytes are required
+const challengeSize = 32
+
+//RelyingParty is the server which users authenticate to
+type RelyingParty struct {
+	//ID is a domain of the server, credentials are scoped to it
+	ID   string
+	Name string
+	//Origins are allowed origins of pages which call WebAuthn API
+	Origins []string
+}
+
+//CredentialDescriptor identifies a credential of user
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+//CredentialParameter is a credential type and algorithm which relying party accepts
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+//CreationOptions are passed to navigator.credentials.create, binary values are base64url encoded
+type CreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout,omitempty"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials,omitempty"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+//RequestOptions are passed to navigator.credentials.get, binary values are base64url encoded
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout,omitempty"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials,omitempty"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+//AttestationResponse is a credential returned by navigator.credentials.create
+type AttestationResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports,omitempty"`
+	} `json:"response"`
+}
+
+//AssertionResponse is a credential returned by navigator.credentials.get
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle,omitempty"`
+	} `json:"response"`
+}
+
+//ClientData is collected by browser and signed by authenticator
+type ClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin,omitempty"`
+}
+
+//Credential is a registered public key credential
+type Credential struct {
+	//ID is base64url encoded credential ID
+	ID                string
+	PublicKey         []byte
+	SignCount         uint32
+	AttestationFormat string
+	AAGUID            string
+	Transports        []string
+	BackupEligible    bool
+}
+
+//authenticatorData is parsed authenticator data, W3C WebAuthn section 6.1
+type authenticatorData struct {
+	rpIDHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+//NewChallenge returns random base64url encoded challenge
+func NewChallenge() (string, error) {
+	b := make([]byte, challengeSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.NoType.Wrap(err, "Err generate challenge.")
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+//DecodeBase64 decodes base64url with or without padding
+func DecodeBase64(s string) ([]byte, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, errors.ErrInvalidArgument.New("Invalid base64url value.")
+	}
+	return b, nil
+}
+
+//PublicKeyCredentialParameters returns the supported algorithms in order of preference
+func PublicKeyCredentialParameters() []CredentialParameter {
+	return []CredentialParameter{
+		{Type: "public-key", Alg: AlgES256},
+		{Type: "public-key", Alg: AlgEdDSA},
+		{Type: "public-key", Alg: AlgRS256},
+	}
+}
+
+//ParseClientData decodes clientDataJSON, the challenge in it identifies the ceremony
+func ParseClientData(clientDataJSON string) (*ClientData, []byte, error) {
+	raw, err := DecodeBase64(clientDataJSON)
+	if err != nil {
+		return nil, nil, err
+	}
+	data := &ClientData{}
+	if err = json.Unmarshal(raw, data); err != nil {
+		return nil, nil, errors.ErrInvalidArgument.New("Invalid client data.")
+	}
+	return data, raw, nil
+}
+
+//verifyClientData checks type, challenge and origin of client data
+func (rp *RelyingParty) verifyClientData(data *ClientData, typ, challenge string) error {
+	if data.Type != typ {
+		return errors.ErrUnauthorized.New("Invalid client data type.")
+	}
+	if subtle.ConstantTimeCompare([]byte(data.Challenge), []byte(challenge)) != 1 {
+		return errors.ErrUnauthorized.New("Invalid challenge.")
+	}
+	for _, v := range rp.Origins {
+		if data.Origin == v {
+			return nil
+		}
+	}
+	return errors.ErrUnauthorized.New("Invalid origin.")
+}
+
+//verifyAuthenticatorData checks relying party, user presence and user verification if it is required
+func (rp *RelyingParty) verifyAuthenticatorData(authData *authenticatorData, requireUV bool) error {
+	rpIDHash := sha256.Sum256([]byte(rp.ID))
+	if !bytes.Equal(authData.rpIDHash, rpIDHash[:]) {
+		return errors.ErrUnauthorized.New("Invalid relying party ID.")
+	}
+	if authData.flags&flagUserPresent == 0 {
+		return errors.ErrUnauthorized.New("User is not present.")
+	}
+	if requireUV && authData.flags&flagUserVerified == 0 {
+		return errors.ErrUnauthorized.New("User is not verified.")
+	}
+	return nil
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.ErrInvalidArgument.New("Invalid authenticator data.")
+	}
+	authData := &authenticatorData{
+		rpIDHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+	if authData.flags&flagAttestedCredData == 0 {
+		return authData, nil
+	}
+	rest := data[37:]
+	if len(rest) < 18 {
+		return nil, errors.ErrInvalidArgument.New("Invalid attested credential data.")
+	}
+	authData.aaguid = rest[:16]
+	idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+		return nil, errors.ErrInvalidArgument.New("Invalid credential ID.")
+	}
+	authData.credentialID = rest[:idLen]
+	rest = rest[idLen:]
+	_, n, err := parseCOSEKey(rest)
+	if err != nil {
+		return nil, err
+	}
+	authData.publicKey = rest[:n]
+	return authData, nil
+}
+
+//VerifyRegistration checks response of navigator.credentials.create to the challenge and returns the new credential
+func (rp *RelyingParty) VerifyRegistration(challenge string, resp *AttestationResponse, requireUV bool) (*Credential, error) {
+	if resp == nil || resp.Type != "public-key" {
+		return nil, errors.ErrInvalidArgument.New("Invalid credential type.")
+	}
+	clientData, rawClientData, err := ParseClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyClientData(clientData, TypeCreate, challenge); err != nil {
+		return nil, err
+	}
+	rawAttestation, err := DecodeBase64(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, err
+	}
+	v, _, err := decodeCBOR(rawAttestation)
+	if err != nil {
+		return nil, err
+	}
+	attestation, ok := v.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.ErrInvalidArgument.New("Invalid attestation object.")
+	}
+	format, _ := attestation["fmt"].(string)
+	attStmt, _ := attestation["attStmt"].(map[interface{}]interface{})
+	rawAuthData, _ := attestation["authData"].([]byte)
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err = rp.verifyAuthenticatorData(authData, requireUV); err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, errors.ErrInvalidArgument.New("Attested credential data is missing.")
+	}
+	err = verifyAttestation(format, attStmt, authData, signedData(rawAuthData, rawClientData))
+	if err != nil {
+		return nil, err
+	}
+	return &Credential{
+		ID:                base64.RawURLEncoding.EncodeToString(authData.credentialID),
+		PublicKey:         authData.publicKey,
+		SignCount:         authData.signCount,
+		AttestationFormat: format,
+		AAGUID:            hex.EncodeToString(authData.aaguid),
+		Transports:        resp.Response.Transports,
+		BackupEligible:    authData.flags&flagBackupEligible != 0,
+	}, nil
+}
+
+//signedData returns authenticator data concatenated with hash of client data.
+//Authenticator data may be a part of attestation object, so it is copied.
+func signedData(rawAuthData, rawClientData []byte) []byte {
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := make([]byte, 0, len(rawAuthData)+len(clientDataHash))
+	signed = append(signed, rawAuthData...)
+	return append(signed, clientDataHash[:]...)
+}
+
+//verifyAttestation checks attestation statement over signed data, W3C WebAuthn section 8
+func verifyAttestation(format string, attStmt map[interface{}]interface{}, authData *authenticatorData, signed []byte) error {
+	switch format {
+	case AttestationNone:
+		if len(attStmt) != 0 {
+			return errors.ErrInvalidArgument.New("Invalid none attestation statement.")
+		}
+		return nil
+	case AttestationPacked:
+		alg, _ := attStmt["alg"].(int64)
+		sig, _ := attStmt["sig"].([]byte)
+		x5c, _ := attStmt["x5c"].([]interface{})
+		if len(x5c) == 0 {
+			//Self attestation is signed by the credential key
+			key, _, err := parseCOSEKey(authData.publicKey)
+			if err != nil {
+				return err
+			}
+			if key.alg != alg || !verifySignature(key.key, alg, signed, sig) {
+				return errors.ErrUnauthorized.New("Invalid attestation signature.")
+			}
+			return nil
+		}
+		der, _ := x5c[0].([]byte)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return errors.ErrInvalidArgument.New("Invalid attestation certificate.")
+		}
+		if !verifySignature(cert.PublicKey, alg, signed, sig) {
+			return errors.ErrUnauthorized.New("Invalid attestation signature.")
+		}
+		return nil
+	}
+	return errors.ErrInvalidArgument.Newf("Unsupported attestation format %s.", format)
+}
+
+//VerifyAssertion checks response of navigator.credentials.get to the challenge made by the credential.
+//It returns the new sign counter of credential.
+func (rp *RelyingParty) VerifyAssertion(challenge string, resp *AssertionResponse, cred *Credential, requireUV bool) (uint32, error) {
+	if resp == nil || resp.Type != "public-key" || cred == nil {
+		return 0, errors.ErrInvalidArgument.New("Invalid credential.")
+	}
+	clientData, rawClientData, err := ParseClientData(resp.Response.ClientDataJSON)
+	if err != nil {
+		return 0, err
+	}
+	if err = rp.verifyClientData(clientData, TypeGet, challenge); err != nil {
+		return 0, err
+	}
+	rawAuthData, err := DecodeBase64(resp.Response.AuthenticatorData)
+	if err != nil {
+		return 0, err
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err = rp.verifyAuthenticatorData(authData, requireUV); err != nil {
+		return 0, err
+	}
+	sig, err := DecodeBase64(resp.Response.Signature)
+	if err != nil {
+		return 0, err
+	}
+	key, _, err := parseCOSEKey(cred.PublicKey)
+	if err != nil {
+		return 0, err
+	}
+	if !verifySignature(key.key, key.alg, signedData(rawAuthData, rawClientData), sig) {
+		return 0, errors.ErrUnauthorized.New("Invalid assertion signature.")
+	}
+	//Authenticators without counter always send zero, otherwise counter must grow or credential is cloned
+	if (authData.signCount != 0 || cred.SignCount != 0) && authData.signCount <= cred.SignCount {
+		return 0, errors.ErrUnauthorized.New("Sign counter didn't increase, credential may be cloned.")
+	}
+	return authData.signCount, nil
+}
@@ -0,0 +1,433 @@
+package webauthn
+
+import (
+	errors "auth-server/pkg/errors/types"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+func testRP() *RelyingParty {
+	return &RelyingParty{ID: testRPID, Name: "Example", Origins: []string{testOrigin}}
+}
+
+//cborPair is an entry of cborMap, entries are encoded in the given order
+type cborPair struct {
+	key   interface{}
+	value interface{}
+}
+
+type cborMap []cborPair
+
+//encodeCBOR encodes the values which tests build, RFC 8949
+func encodeCBOR(v interface{}) []byte {
+	switch v := v.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v < 0 {
+			return cborHead(1, uint64(-1-v))
+		}
+		return cborHead(0, uint64(v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []interface{}:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case cborMap:
+		out := cborHead(5, uint64(len(v)))
+		for _, pair := range v {
+			out = append(out, encodeCBOR(pair.key)...)
+			out = append(out, encodeCBOR(pair.value)...)
+		}
+		return out
+	}
+	panic("unsupported CBOR value")
+}
+
+func cborHead(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg <= 0xff:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg <= 0xffff:
+		out := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(out[1:], uint16(arg))
+		return out
+	}
+	out := []byte{major<<5 | 26, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(out[1:], uint32(arg))
+	return out
+}
+
+//testAuthenticator is a software authenticator with ES256 credential
+type testAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	signCount    uint32
+	flags        byte
+	rpID         string
+	//attestationKey signs packed attestation instead of credential key if it is set
+	attestationKey *ecdsa.PrivateKey
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &testAuthenticator{
+		key:          key,
+		credentialID: id,
+		flags:        flagUserPresent | flagUserVerified,
+		rpID:         testRPID,
+	}
+}
+
+func (a *testAuthenticator) publicKey() []byte {
+	x := make([]byte, 32)
+	y := make([]byte, 32)
+	a.key.X.FillBytes(x)
+	a.key.Y.FillBytes(y)
+	return encodeCBOR(cborMap{
+		{coseKeyType, coseKeyTypeEC2},
+		{coseKeyAlg, AlgES256},
+		{coseKeyCrv, coseCrvP256},
+		{coseKeyX, x},
+		{coseKeyY, y},
+	})
+}
+
+func (a *testAuthenticator) authData(attested bool) []byte {
+	rpIDHash := sha256.Sum256([]byte(a.rpID))
+	data := append([]byte{}, rpIDHash[:]...)
+	flags := a.flags
+	if attested {
+		flags |= flagAttestedCredData
+	}
+	data = append(data, flags, 0, 0, 0, 0)
+	binary.BigEndian.PutUint32(data[33:37], a.signCount)
+	if !attested {
+		return data
+	}
+	data = append(data, make([]byte, 16)...)
+	data = append(data, byte(len(a.credentialID)>>8), byte(len(a.credentialID)))
+	data = append(data, a.credentialID...)
+	return append(data, a.publicKey()...)
+}
+
+func (a *testAuthenticator) sign(data []byte) []byte {
+	return signES256(a.key, data)
+}
+
+func signES256(key *ecdsa.PrivateKey, data []byte) []byte {
+	digest := sha256.Sum256(data)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return sig
+}
+
+func newTestChallenge(t *testing.T) string {
+	challenge, err := NewChallenge()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return challenge
+}
+
+func clientDataJSON(typ, challenge, origin string) []byte {
+	raw, _ := json.Marshal(ClientData{Type: typ, Challenge: challenge, Origin: origin})
+	return raw
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//register creates credential with attestation of the format
+func (a *testAuthenticator) register(format, challenge, origin string) *AttestationResponse {
+	rawClientData := clientDataJSON(TypeCreate, challenge, origin)
+	authData := a.authData(true)
+	attStmt := cborMap{}
+	if format == AttestationPacked {
+		key := a.key
+		if a.attestationKey != nil {
+			key = a.attestationKey
+		}
+		attStmt = cborMap{
+			{"alg", AlgES256},
+			{"sig", signES256(key, signedData(authData, rawClientData))},
+		}
+	}
+	resp := &AttestationResponse{ID: encode(a.credentialID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encode(rawClientData)
+	resp.Response.AttestationObject = encode(encodeCBOR(cborMap{
+		{"fmt", format},
+		{"attStmt", attStmt},
+		{"authData", authData},
+	}))
+	return resp
+}
+
+//assert signs the challenge by credential
+func (a *testAuthenticator) assert(challenge, origin string) *AssertionResponse {
+	rawClientData := clientDataJSON(TypeGet, challenge, origin)
+	authData := a.authData(false)
+	resp := &AssertionResponse{ID: encode(a.credentialID), Type: "public-key"}
+	resp.Response.ClientDataJSON = encode(rawClientData)
+	resp.Response.AuthenticatorData = encode(authData)
+	resp.Response.Signature = encode(a.sign(signedData(authData, rawClientData)))
+	return resp
+}
+
+func TestRegistration(t *testing.T) {
+	for _, format := range []string{AttestationNone, AttestationPacked} {
+		a := newTestAuthenticator(t)
+		a.signCount = 1
+		challenge := newTestChallenge(t)
+		cred, err := testRP().VerifyRegistration(challenge, a.register(format, challenge, testOrigin), true)
+		if err != nil {
+			t.Fatalf("%s attestation: %v", format, err)
+		}
+		if cred.ID != encode(a.credentialID) || cred.SignCount != 1 || cred.AttestationFormat != format {
+			t.Errorf("%s attestation: unexpected credential %+v", format, cred)
+		}
+		if _, _, err = parseCOSEKey(cred.PublicKey); err != nil {
+			t.Errorf("%s attestation: invalid public key: %v", format, err)
+		}
+	}
+}
+
+func TestRegistrationPackedInvalidSignature(t *testing.T) {
+	a := newTestAuthenticator(t)
+	challenge := newTestChallenge(t)
+	//self attestation signed by another key
+	a.attestationKey = newTestAuthenticator(t).key
+	_, err := testRP().VerifyRegistration(challenge, a.register(AttestationPacked, challenge, testOrigin), false)
+	if errors.GetType(err) != errors.ErrUnauthorized {
+		t.Fatalf("got %v, want unauthorized", err)
+	}
+}
+
+func TestAssertion(t *testing.T) {
+	a := newTestAuthenticator(t)
+	challenge := newTestChallenge(t)
+	cred, err := testRP().VerifyRegistration(challenge, a.register(AttestationNone, challenge, testOrigin), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.signCount = 7
+	challenge = newTestChallenge(t)
+	count, err := testRP().VerifyAssertion(challenge, a.assert(challenge, testOrigin), cred, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 7 {
+		t.Fatalf("sign counter %d, want 7", count)
+	}
+}
+
+func TestAssertionInvalidSignature(t *testing.T) {
+	a := newTestAuthenticator(t)
+	cred := &Credential{ID: encode(a.credentialID), PublicKey: a.publicKey()}
+	challenge := newTestChallenge(t)
+	resp := a.assert(challenge, testOrigin)
+	//signature of another key
+	resp.Response.Signature = encode(newTestAuthenticator(t).sign([]byte("data")))
+	_, err := testRP().VerifyAssertion(challenge, resp, cred, false)
+	if errors.GetType(err) != errors.ErrUnauthorized {
+		t.Fatalf("got %v, want unauthorized", err)
+	}
+}
+
+func TestCeremonyMismatch(t *testing.T) {
+	challenge := newTestChallenge(t)
+	cases := []struct {
+		name      string
+		challenge string
+		origin    string
+		rpID      string
+	}{
+		{"wrong origin", challenge, "https://evil.example.com", testRPID},
+		{"wrong challenge", newTestChallenge(t), testOrigin, testRPID},
+		{"wrong rpId", challenge, testOrigin, "evil.example.com"},
+	}
+	for _, c := range cases {
+		a := newTestAuthenticator(t)
+		a.rpID = c.rpID
+		_, err := testRP().VerifyRegistration(challenge, a.register(AttestationNone, c.challenge, c.origin), false)
+		if errors.GetType(err) != errors.ErrUnauthorized {
+			t.Errorf("registration with %s: got %v, want unauthorized", c.name, err)
+		}
+		cred := &Credential{ID: encode(a.credentialID), PublicKey: a.publicKey()}
+		_, err = testRP().VerifyAssertion(challenge, a.assert(c.challenge, c.origin), cred, false)
+		if errors.GetType(err) != errors.ErrUnauthorized {
+			t.Errorf("assertion with %s: got %v, want unauthorized", c.name, err)
+		}
+	}
+}
+
+func TestCeremonyTypeMismatch(t *testing.T) {
+	a := newTestAuthenticator(t)
+	challenge := newTestChallenge(t)
+	//assertion client data replayed as registration
+	resp := a.register(AttestationNone, challenge, testOrigin)
+	resp.Response.ClientDataJSON = encode(clientDataJSON(TypeGet, challenge, testOrigin))
+	if _, err := testRP().VerifyRegistration(challenge, resp, false); err == nil {
+		t.Fatal("registration with webauthn.get client data accepted")
+	}
+}
+
+func TestUserFlags(t *testing.T) {
+	cases := []struct {
+		name      string
+		flags     byte
+		requireUV bool
+		ok        bool
+	}{
+		{"present and verified", flagUserPresent | flagUserVerified, true, true},
+		{"present, verification preferred", flagUserPresent, false, true},
+		{"not verified", flagUserPresent, true, false},
+		{"not present", flagUserVerified, false, false},
+		{"no flags", 0, false, false},
+	}
+	for _, c := range cases {
+		a := newTestAuthenticator(t)
+		a.flags = c.flags
+		challenge := newTestChallenge(t)
+		_, err := testRP().VerifyRegistration(challenge, a.register(AttestationPacked, challenge, testOrigin), c.requireUV)
+		if (err == nil) != c.ok {
+			t.Errorf("registration %s: got %v", c.name, err)
+		}
+		cred := &Credential{ID: encode(a.credentialID), PublicKey: a.publicKey()}
+		_, err = testRP().VerifyAssertion(challenge, a.assert(challenge, testOrigin), cred, c.requireUV)
+		if (err == nil) != c.ok {
+			t.Errorf("assertion %s: got %v", c.name, err)
+		}
+	}
+}
+
+func TestSignCounter(t *testing.T) {
+	cases := []struct {
+		name   string
+		stored uint32
+		sent   uint32
+		ok     bool
+	}{
+		{"increased", 5, 6, true},
+		{"not supported", 0, 0, true},
+		{"repeated", 5, 5, false},
+		{"regressed", 5, 3, false},
+		{"reset to zero", 5, 0, false},
+	}
+	for _, c := range cases {
+		a := newTestAuthenticator(t)
+		a.signCount = c.sent
+		cred := &Credential{ID: encode(a.credentialID), PublicKey: a.publicKey(), SignCount: c.stored}
+		challenge := newTestChallenge(t)
+		count, err := testRP().VerifyAssertion(challenge, a.assert(challenge, testOrigin), cred, false)
+		if (err == nil) != c.ok {
+			t.Errorf("%s: got %v", c.name, err)
+		}
+		if c.ok && count != c.sent {
+			t.Errorf("%s: sign counter %d, want %d", c.name, count, c.sent)
+		}
+	}
+}
+
+func TestTruncatedAttestationObject(t *testing.T) {
+	a := newTestAuthenticator(t)
+	challenge := newTestChallenge(t)
+	for _, format := range []string{AttestationNone, AttestationPacked} {
+		resp := a.register(format, challenge, testOrigin)
+		raw, _ := DecodeBase64(resp.Response.AttestationObject)
+		for n := 0; n < len(raw); n++ {
+			resp.Response.AttestationObject = encode(raw[:n])
+			if _, err := testRP().VerifyRegistration(challenge, resp, false); err == nil {
+				t.Fatalf("%s attestation truncated to %d bytes accepted", format, n)
+			}
+		}
+	}
+}
+
+func TestTruncatedAuthenticatorData(t *testing.T) {
+	a := newTestAuthenticator(t)
+	cred := &Credential{ID: encode(a.credentialID), PublicKey: a.publicKey()}
+	challenge := newTestChallenge(t)
+	resp := a.assert(challenge, testOrigin)
+	raw, _ := DecodeBase64(resp.Response.AuthenticatorData)
+	for n := 0; n < len(raw); n++ {
+		resp.Response.AuthenticatorData = encode(raw[:n])
+		if _, err := testRP().VerifyAssertion(challenge, resp, cred, false); err == nil {
+			t.Fatalf("authenticator data truncated to %d bytes accepted", n)
+		}
+	}
+}
+
+func TestMalformedCBOR(t *testing.T) {
+	nested := make([]byte, cborMaxDepth+2)
+	for i := range nested {
+		nested[i] = 0x81
+	}
+	cases := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", []byte{}},
+		{"truncated head", []byte{0x19, 0x01}},
+		{"truncated bytes", []byte{0x44, 0x01, 0x02}},
+		{"huge byte string", []byte{0x5b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge array", []byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"huge map", []byte{0xba, 0xff, 0xff, 0xff, 0xff}},
+		{"map without value", []byte{0xa1, 0x01}},
+		{"indefinite array", []byte{0x9f, 0x01, 0xff}},
+		{"float", []byte{0xfa, 0x3f, 0x80, 0x00, 0x00}},
+		{"byte string map key", []byte{0xa1, 0x41, 0x00, 0x01}},
+		{"integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"nested too deep", nested},
+	}
+	for _, c := range cases {
+		if _, _, err := decodeCBOR(c.data); err == nil {
+			t.Errorf("%s: decoded", c.name)
+		}
+	}
+}
+
+func TestDecodeCBOR(t *testing.T) {
+	data := encodeCBOR(cborMap{
+		{"fmt", "none"},
+		{1, []interface{}{int64(-7), []byte{1, 2}}},
+	})
+	v, n, err := decodeCBOR(append(data, 0xff))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != len(data) {
+		t.Fatalf("decoded %d bytes, want %d", n, len(data))
+	}
+	m := v.(map[interface{}]interface{})
+	items := m[int64(1)].([]interface{})
+	if m["fmt"] != "none" || items[0] != int64(-7) || string(items[1].([]byte)) != "\x01\x02" {
+		t.Fatalf("unexpected value %v", v)
+	}
+}